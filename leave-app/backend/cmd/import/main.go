@@ -0,0 +1,101 @@
+// cmd/import/main.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/importer"
+	"leave-app/internal/workdays"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: import [-dry-run] [-users users.csv] [-leaves leaves.csv]
+
+Imports users and historical leave from CSV in a single transaction. Any
+row error stops the whole import. The changes are audited as made by
+the "system:import" actor. Working days are counted with WEEKEND_DAYS and
+the holidays in the database, or in HOLIDAYS_ICS_FILE when it is set.
+
+users.csv columns:  email, role, manager, annual, sick, casual
+leaves.csv columns: email, type, start_date, end_date, portion, status, reason
+
+Flags:
+`
+
+func main() {
+	usersPath := flag.String("users", "", "CSV file of users to create or update")
+	leavesPath := flag.String("leaves", "", "CSV file of leave already decided")
+	dryRun := flag.Bool("dry-run", false, "check the files and report errors without importing")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *usersPath == "" && *leavesPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var users, leaves io.Reader
+	for _, f := range []struct {
+		path string
+		dest *io.Reader
+	}{
+		{*usersPath, &users},
+		{*leavesPath, &leaves},
+	} {
+		if f.path == "" {
+			continue
+		}
+		file, err := os.Open(f.path)
+		if err != nil {
+			log.Fatalf("Could not open %s: %v", f.path, err)
+		}
+		defer file.Close()
+		*f.dest = file
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	database, err := db.NewDatabase()
+	if err != nil {
+		log.Fatalf("Could not connect to the database: %v", err)
+	}
+
+	// Leave is costed on the same calendar the server uses
+	if database.Calendar, err = workdays.FromEnv(database); err != nil {
+		log.Fatalf("Could not set up the calendar: %v", err)
+	}
+
+	result, err := importer.Run(database, constants.ImportActorID, users, leaves, *dryRun)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	for _, e := range result.Errors {
+		column := ""
+		if e.Column != "" {
+			column = " " + e.Column
+		}
+		fmt.Fprintf(os.Stderr, "%s row %d%s: %s\n", e.File, e.Row, column, e.Message)
+	}
+
+	summary := fmt.Sprintf("%d user(s) created, %d updated, %d leave(s)", result.UsersCreated, result.UsersUpdated, result.Leaves)
+	switch {
+	case len(result.Errors) > 0:
+		fmt.Printf("Nothing imported: %d error(s)\n", len(result.Errors))
+		os.Exit(1)
+	case result.DryRun:
+		fmt.Printf("Dry run, nothing imported: %s\n", summary)
+	default:
+		fmt.Printf("Imported %s\n", summary)
+	}
+}
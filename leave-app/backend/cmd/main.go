@@ -2,10 +2,20 @@
 package main
 
 import (
+	"context"
+	"leave-app/internal/constants"
 	"leave-app/internal/db"
 	"leave-app/internal/handlers"
+	"leave-app/internal/notify"
+	"leave-app/internal/scheduler"
+	"leave-app/internal/storage"
+	"leave-app/internal/webhook"
+	"leave-app/internal/workdays"
 	"leave-app/pkg/auth"
 	"log"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -29,14 +39,56 @@ func main() {
 		log.Fatalf("Could not run database migrations: %v", err)
 	}
 
+	// Configure the working-day calendar used to cost leave
+	if database.Calendar, err = workdays.FromEnv(database); err != nil {
+		log.Fatalf("Could not set up the calendar: %v", err)
+	}
+
+	// Reread holidays on a schedule to pick up edits made through other
+	// replicas and changes to the ICS file
+	go func() {
+		ticker := time.NewTicker(constants.HolidayReloadMinutes * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := database.Calendar.Reload(); err != nil {
+				log.Printf("Holiday reload failed: %v", err)
+			}
+		}
+	}()
+
+	// Credit accruals on boot and then on a schedule. Runs are idempotent,
+	// so replicas running them at the same time is harmless.
+	go func() {
+		ticker := time.NewTicker(constants.AccrualIntervalHours * time.Hour)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			run, err := database.RunAccruals("", time.Now())
+			if err != nil {
+				log.Printf("Accrual run failed: %v", err)
+				continue
+			}
+			log.Printf("Accrual run for %s: %.2f accrued, %.2f carried forward, %.2f expired", run.AsOf, run.Accrued, run.CarriedForward, run.Expired)
+		}
+	}()
+
+	// Send queued webhook deliveries. Claims in the outbox keep replicas
+	// from sending the same delivery at once.
+	dispatcher := webhook.Dispatcher{
+		Outbox: database,
+		Client: &http.Client{Timeout: constants.WebhookTimeoutSeconds * time.Second},
+	}
+	go dispatcher.Run(context.Background())
+
 	// Initialize authenticator
 	authenticator, err := auth.New(database)
 	if err != nil {
 		log.Fatalf("Failed to initialize authenticator: %v", err)
 	}
 
-	// Create Gin router
-	r := gin.Default()
+	// Create Gin router. The logger masks calendar feed tokens, which
+	// arrive in the query string.
+	r := gin.New()
+	r.Use(handlers.Logger(), gin.Recovery())
 
 	// CORS Middleware
 	r.Use(func(c *gin.Context) {
@@ -55,22 +107,49 @@ func main() {
 
 	// Initialize handlers
 	h := handlers.New(database)
+	attachmentsDir := os.Getenv("ATTACHMENTS_DIR")
+	if attachmentsDir == "" {
+		attachmentsDir = "uploads"
+	}
+	h.Blobs = storage.Local{Dir: attachmentsDir}
+
+	// Email goes through SMTP when SMTP_ADDR is set and is otherwise
+	// written to MAIL_DIR, or the log, for development
+	var mailer notify.Mailer = &notify.LogMailer{Dir: os.Getenv("MAIL_DIR"), From: os.Getenv("MAIL_FROM")}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		mailer = &notify.SMTPMailer{
+			Addr:     addr,
+			From:     os.Getenv("MAIL_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	}
+	h.Notify = &notify.Notifier{Mailer: mailer, Store: database, AppURL: os.Getenv("APP_URL")}
+
+	// Remind approvers of stale leave and escalate it. The job lease keeps
+	// replicas from chasing the same leave at once.
+	var chase scheduler.Config
+	for _, setting := range []struct {
+		env      string
+		fallback int
+		into     *time.Duration
+	}{
+		{"REMINDER_AFTER_HOURS", constants.ReminderAfterHours, &chase.ReminderAfter},
+		{"ESCALATE_AFTER_HOURS", constants.EscalateAfterHours, &chase.EscalateAfter},
+		{"SICK_AUTO_APPROVE_AFTER_HOURS", constants.SickAutoApproveAfterHours, &chase.SickAutoApproveAfter},
+	} {
+		if *setting.into, err = scheduler.ParseHours(os.Getenv(setting.env), setting.fallback); err != nil {
+			log.Fatalf("Invalid %s: %v", setting.env, err)
+		}
+	}
+	reminders := scheduler.Scheduler{Store: database, Notify: h.Notify, Config: chase, Holder: scheduler.DefaultHolder()}
+	go reminders.Run(context.Background())
 
 	// Setup routes
 	api := r.Group("/api")
 	api.Use(authenticator.AuthMiddleware())
-	{
-		api.GET("/me", h.GetCurrentUser)
-		api.GET("/users", h.GetUsers)
-		api.PUT("/admin/allowances", h.UpdateAllowances)
-		api.PUT("/users/:id/role", h.UpdateUserRole)
-		api.GET("/leaves", h.GetLeaves)
-		api.POST("/leaves", h.CreateLeave)
-		api.PUT("/leaves/:id", h.UpdateLeave)
-		api.DELETE("/leaves/:id", h.DeleteLeave)
-		api.POST("/leaves/:id/approve", h.ApproveLeave)
-		api.POST("/leaves/:id/reject", h.RejectLeave)
-	}
+	h.Register(api)
+	h.RegisterFeeds(r.Group("/api"))
 
 	// A simple health check route
 	r.GET("/ping", func(c *gin.Context) {
@@ -0,0 +1,60 @@
+// internal/accrual/accrual.go
+package accrual
+
+import (
+	"leave-app/internal/constants"
+	"math"
+	"time"
+)
+
+// Credit is the days earned for one period of a leave year. Yearly accrual
+// has a single period 0; monthly accrual uses periods 1 to 12.
+type Credit struct {
+	Period int
+	Days   float64
+}
+
+// Due returns the credits earned in year by asOf for an annual entitlement,
+// for someone who joined on joined. Monthly credits fall on the first of
+// each month. In the joining year only the months from the joining month
+// count, so a yearly entitlement is pro-rated.
+func Due(frequency constants.AccrualFrequency, annual float64, joined time.Time, year int, asOf time.Time) []Credit {
+	if year > asOf.Year() || year < joined.Year() {
+		return nil
+	}
+	first := 1
+	if year == joined.Year() {
+		first = int(joined.Month())
+	}
+
+	if frequency == constants.AccrualYearly {
+		return []Credit{{Period: 0, Days: round(annual * float64(13-first) / 12)}}
+	}
+
+	last := 12
+	if year == asOf.Year() {
+		last = int(asOf.Month())
+	}
+	var credits []Credit
+	for month := first; month <= last; month++ {
+		// Differences of rounded running totals add up to exactly annual
+		days := round(annual*float64(month)/12) - round(annual*float64(month-1)/12)
+		credits = append(credits, Credit{Period: month, Days: days})
+	}
+	return credits
+}
+
+// CarryForward splits the days left at year end into those carried into
+// the next year, up to limit, and those that expire.
+func CarryForward(remaining, limit float64) (carried, expired float64) {
+	if remaining <= 0 {
+		return 0, 0
+	}
+	carried = math.Min(remaining, limit)
+	return carried, round(remaining - carried)
+}
+
+// round keeps day counts to two decimals
+func round(days float64) float64 {
+	return math.Round(days*100) / 100
+}
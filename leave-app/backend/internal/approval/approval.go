@@ -0,0 +1,88 @@
+// internal/approval/approval.go
+package approval
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"strings"
+)
+
+// DefaultSteps apply when no policy matches a leave
+var DefaultSteps = []string{string(constants.RoleManager)}
+
+// Resolve returns the ordered approval steps for a leave of leaveType that
+// costs days. Among the policies for the type whose threshold the leave
+// exceeds, the one with the highest threshold wins. A zero threshold
+// matches every leave of the type.
+func Resolve(policies []models.ApprovalPolicy, leaveType string, days float64) []string {
+	var best *models.ApprovalPolicy
+	for i := range policies {
+		p := &policies[i]
+		if p.LeaveType != leaveType {
+			continue
+		}
+		if p.OverDays > 0 && days <= p.OverDays {
+			continue
+		}
+		if best == nil || p.OverDays > best.OverDays {
+			best = p
+		}
+	}
+	if best == nil || len(best.Steps) == 0 {
+		return DefaultSteps
+	}
+	return best.Steps
+}
+
+// CanAct reports whether user may decide the step of leave that needs role.
+// Admins can act on any step. Nobody else may decide their own leave; the
+// manager step belongs to the manager the leave was routed to, and the hr
+// step to anyone with the hr role.
+func CanAct(user *models.User, leave *models.Leave, role string) bool {
+	if user.Role == string(constants.RoleAdmin) {
+		return true
+	}
+	if leave.UserID == user.ID {
+		return false
+	}
+	switch constants.Role(role) {
+	case constants.RoleManager:
+		return leave.ApproverID != nil && *leave.ApproverID == user.ID
+	case constants.RoleHR:
+		return user.Role == string(constants.RoleHR)
+	}
+	return false
+}
+
+// Decider returns who user decides the step of leave that needs role as:
+// themselves, or the first of delegators whose authority they hold. ok is
+// false when neither may act. Delegates never decide their own leave.
+func Decider(user *models.User, delegators []models.User, leave *models.Leave, role string) (decider *models.User, ok bool) {
+	if CanAct(user, leave, role) {
+		return user, true
+	}
+	if leave.UserID == user.ID {
+		return nil, false
+	}
+	for i := range delegators {
+		if CanAct(&delegators[i], leave, role) {
+			return &delegators[i], true
+		}
+	}
+	return nil, false
+}
+
+// JoinSteps and SplitSteps convert steps to and from their column format
+func JoinSteps(steps []string) string {
+	return strings.Join(steps, ",")
+}
+
+func SplitSteps(s string) []string {
+	var steps []string
+	for _, step := range strings.Split(s, ",") {
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
@@ -4,24 +4,201 @@ package constants
 type Role string
 type LeaveStatus string
 type LeaveType string
+type LeavePortion string
+type AuditAction string
+type AccrualFrequency string
+type AllowanceSource string
+type CalendarScope string
+type WebhookEvent string
+type WebhookStatus string
 
 const (
-	RoleAdmin Role = "admin"
-	RoleUser  Role = "user"
+	RoleAdmin   Role = "admin"
+	RoleHR      Role = "hr"
+	RoleManager Role = "manager"
+	RoleUser    Role = "user"
 )
 
 const (
-	LeaveStatusPending  LeaveStatus = "pending"
-	LeaveStatusApproved LeaveStatus = "approved"
-	LeaveStatusRejected LeaveStatus = "rejected"
+	LeaveStatusPending               LeaveStatus = "pending"
+	LeaveStatusApproved              LeaveStatus = "approved"
+	LeaveStatusRejected              LeaveStatus = "rejected"
+	LeaveStatusWithdrawn             LeaveStatus = "withdrawn"
+	LeaveStatusCancellationRequested LeaveStatus = "cancellation_requested"
+	LeaveStatusCancelled             LeaveStatus = "cancelled"
 )
 
+// Built-in leave types, each backed by an allowance column. Others are
+// defined in the leave_types table.
 const (
 	LeaveTypeSick   LeaveType = "sick"
 	LeaveTypeAnnual LeaveType = "annual"
 	LeaveTypeCasual LeaveType = "casual"
 )
 
+// LeaveTypeCompOff is spent from comp-off credits, oldest first, rather
+// than a yearly allowance
+const LeaveTypeCompOff LeaveType = "compoff"
+
+const (
+	LeavePortionFull       LeavePortion = "full"
+	LeavePortionFirstHalf  LeavePortion = "first_half"
+	LeavePortionSecondHalf LeavePortion = "second_half"
+	LeavePortionHourly     LeavePortion = "hourly"
+)
+
+// Actions recorded in audit_events; the prefix is the entity type
+const (
+	AuditLeaveCreate      AuditAction = "leave.create"
+	AuditLeaveStatus      AuditAction = "leave.status"
+	AuditLeaveStep        AuditAction = "leave.step" // A chain step approved, status unchanged
+	AuditLeaveEscalate    AuditAction = "leave.escalate"
+	AuditLeaveDelegated   AuditAction = "leave.delegated_decision" // A delegate decided for a delegator
+	AuditLeaveDelete      AuditAction = "leave.delete"
+	AuditLeaveAttach      AuditAction = "leave.attach"
+	AuditLeaveImport      AuditAction = "leave.import"
+	AuditUserCreate       AuditAction = "user.create"
+	AuditUserImport       AuditAction = "user.import"
+	AuditUserRole         AuditAction = "user.role"
+	AuditUserManager      AuditAction = "user.manager"
+	AuditUserAllowances   AuditAction = "user.allowances"
+	AuditUserGroups       AuditAction = "user.groups"
+	AuditUserCalendar     AuditAction = "user.calendar_token"
+	AuditUserNotify       AuditAction = "user.notifications"
+	AuditUserStaffing     AuditAction = "user.min_staffing"
+	AuditApprovalPolicies AuditAction = "approval_policy.replace"
+	AuditAccrualPolicies  AuditAction = "accrual_policy.replace"
+	AuditAccrualRun       AuditAction = "accrual.run"
+
+	AuditAllowancePolicyCreate AuditAction = "allowance_policy.create"
+	AuditAllowancePolicyAssign AuditAction = "allowance_policy.assign"
+	AuditLeaveTypeCreate       AuditAction = "leave_type.create"
+	AuditLeaveTypeUpdate       AuditAction = "leave_type.update"
+	AuditLeaveTypeDelete       AuditAction = "leave_type.delete"
+	AuditWebhookCreate         AuditAction = "webhook.create"
+	AuditWebhookUpdate         AuditAction = "webhook.update"
+	AuditWebhookDelete         AuditAction = "webhook.delete"
+	AuditDelegationCreate      AuditAction = "delegation.create"
+	AuditDelegationDelete      AuditAction = "delegation.delete"
+	AuditCompOffRequest        AuditAction = "comp_off.request"
+	AuditCompOffDecide         AuditAction = "comp_off.decide"
+	AuditHolidaySet            AuditAction = "holiday.set"
+	AuditHolidayDelete         AuditAction = "holiday.delete"
+)
+
+// ImportActorID is the audit actor of changes made by the import tool. It
+// is not a user id; audit_events.actor_id has no foreign key.
+const ImportActorID = "system:import"
+
+const (
+	AccrualMonthly AccrualFrequency = "monthly"
+	AccrualYearly  AccrualFrequency = "yearly"
+)
+
+// Where a user's effective allowances come from, in priority order
+const (
+	AllowanceSourceUser    AllowanceSource = "user"
+	AllowanceSourceGroup   AllowanceSource = "group"
+	AllowanceSourceDefault AllowanceSource = "default"
+)
+
+// Scopes of GET /api/calendar/{scope}.ics. A team is the feed owner's
+// direct reports plus everyone who shares the owner's manager. Only admins
+// may subscribe to the company feed.
+const (
+	CalendarScopeMe      CalendarScope = "me"
+	CalendarScopeTeam    CalendarScope = "team"
+	CalendarScopeCompany CalendarScope = "company"
+)
+
+// CalendarFeedDays is how far back calendar feeds reach; older leave drops
+// out of subscribed calendars
+const CalendarFeedDays = 365
+
+// Leave lifecycle events sent to webhook subscriptions
+const (
+	WebhookLeaveCreated   WebhookEvent = "leave.created"
+	WebhookLeaveApproved  WebhookEvent = "leave.approved"
+	WebhookLeaveRejected  WebhookEvent = "leave.rejected"
+	WebhookLeaveCancelled WebhookEvent = "leave.cancelled"
+	WebhookLeaveDeleted   WebhookEvent = "leave.deleted"
+)
+
+const (
+	WebhookStatusPending   WebhookStatus = "pending"
+	WebhookStatusSucceeded WebhookStatus = "succeeded"
+	WebhookStatusFailed    WebhookStatus = "failed"
+)
+
+// Webhook delivery tuning. Failed attempts are retried after
+// WebhookRetryBaseSeconds, doubling each time up to WebhookRetryMaxSeconds,
+// until WebhookMaxAttempts have been made.
+const (
+	WebhookIntervalSeconds  = 10    // how often the dispatcher polls the outbox
+	WebhookBatchSize        = 50    // deliveries claimed per poll
+	WebhookTimeoutSeconds   = 10    // per request
+	WebhookClaimSeconds     = 60    // how long a claimed delivery is hidden from other replicas
+	WebhookRetryBaseSeconds = 30    // delay after the first failure
+	WebhookRetryMaxSeconds  = 21600 // six hours
+	WebhookMaxAttempts      = 10
+)
+
+// Reminder scheduler defaults. Approvers are reminded of a step waiting
+// ReminderAfterHours; after EscalateAfterHours it moves to the approver's
+// manager, or to admins. Pending sick leave can be approved automatically
+// after SickAutoApproveAfterHours. Zero turns a step off.
+const (
+	ReminderAfterHours        = 48
+	EscalateAfterHours        = 120
+	SickAutoApproveAfterHours = 0
+)
+
+// The scheduler runs on one replica at a time, whichever holds the lease.
+// The lease outlives the interval so its holder keeps it between runs.
+const (
+	SchedulerLeaseName       = "leave_reminders"
+	SchedulerIntervalMinutes = 15
+	SchedulerLeaseMinutes    = 20
+)
+
+// CompOffExpiryDays is how long after the day worked a comp-off credit
+// can be spent
+const CompOffExpiryDays = 90
+
+// MaxAvailabilityDays caps the range of GET /api/team/availability
+const MaxAvailabilityDays = 92
+
+// MaxAnalyticsDays caps the range of GET /api/admin/analytics
+const MaxAnalyticsDays = 731
+
+// AnalyticsCacheMinutes bounds how long analytics stay cached. Changes to
+// leave, teams or holidays on any instance drop them at once; the bound
+// keeps the rolling Bradford window from going stale.
+const AnalyticsCacheMinutes = 15
+
+// BradfordWeeks is the rolling window Bradford factors are scored over
+const BradfordWeeks = 52
+
+// HolidayReloadMinutes is how often the working-day calendar rereads its
+// holidays, picking up edits made on other instances and to the ICS file
+const HolidayReloadMinutes = 15
+
+// AccrualIntervalHours is how often the accrual job runs; runs are idempotent
+const AccrualIntervalHours = 24
+
+// MaxAttachmentBytes caps the size of an uploaded leave attachment
+const MaxAttachmentBytes = 5 << 20
+
+// AttachmentContentTypes are the accepted attachment types, as sniffed
+// from the file contents
+var AttachmentContentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// MaxImportBytes caps the size of an import upload, all files together
+const MaxImportBytes = 10 << 20
+
+// WorkingHoursPerDay converts hourly leave into fractions of a day
+const WorkingHoursPerDay = 8.0
+
 const (
 	ContextUserKey = "user"
 )
@@ -0,0 +1,232 @@
+// internal/db/accrual.go
+package db
+
+import (
+	"leave-app/internal/accrual"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of leave_accruals rows
+const (
+	accrualCredit       = "accrual"
+	accrualCarryForward = "carry_forward"
+	accrualExpiry       = "expiry"
+)
+
+// getAccrualPolicies returns the accrual policies keyed by leave type
+func getAccrualPolicies(q querier) (map[string]models.AccrualPolicy, error) {
+	rows, err := q.Query("SELECT leave_type, frequency, carry_forward_cap FROM accrual_policies")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make(map[string]models.AccrualPolicy)
+	for rows.Next() {
+		var p models.AccrualPolicy
+		if err := rows.Scan(&p.LeaveType, &p.Frequency, &p.CarryForwardCap); err != nil {
+			return nil, err
+		}
+		policies[p.LeaveType] = p
+	}
+	return policies, rows.Err()
+}
+
+func (db *Database) GetAccrualPolicies() ([]models.AccrualPolicy, error) {
+	policies, err := getAccrualPolicies(db.Conn)
+	if err != nil {
+		return nil, err
+	}
+	return sortedAccrualPolicies(policies), nil
+}
+
+func sortedAccrualPolicies(policies map[string]models.AccrualPolicy) []models.AccrualPolicy {
+	sorted := make([]models.AccrualPolicy, 0, len(policies))
+	for _, p := range policies {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LeaveType < sorted[j].LeaveType })
+	return sorted
+}
+
+// ReplaceAccrualPolicies swaps the whole policy set. Credits already made
+// stay; the next run accrues under the new policies.
+func (db *Database) ReplaceAccrualPolicies(actorID string, policies []models.AccrualPolicy) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getAccrualPolicies(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM accrual_policies"); err != nil {
+		return err
+	}
+	for _, p := range policies {
+		query := "INSERT INTO accrual_policies (leave_type, frequency, carry_forward_cap) VALUES (?, ?, ?)"
+		if _, err := tx.Exec(query, p.LeaveType, p.Frequency, p.CarryForwardCap); err != nil {
+			return err
+		}
+	}
+	if err := recordAudit(tx, actorID, constants.AuditAccrualPolicies, "", sortedAccrualPolicies(before), policies); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// accruedDays sums what has been credited to a user for a type and year,
+// and how much of that was carried forward
+func accruedDays(q querier, userID, leaveType string, year int) (float64, float64, error) {
+	var credited, carried float64
+	query := `
+		SELECT COALESCE(SUM(days), 0), COALESCE(SUM(CASE WHEN kind = ? THEN days ELSE 0 END), 0)
+		FROM leave_accruals
+		WHERE user_id = ? AND leave_type = ? AND year = ?
+	`
+	err := q.QueryRow(query, accrualCarryForward, userID, leaveType, year).Scan(&credited, &carried)
+	return credited, carried, err
+}
+
+// insertAccrual writes a credit unless one already exists for the same
+// user, type, year, kind and period, reporting whether it was written
+func insertAccrual(q querier, userID, leaveType string, year int, kind string, period int, days float64) (bool, error) {
+	query := "INSERT IGNORE INTO leave_accruals (id, user_id, leave_type, year, kind, period, days) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := q.Exec(query, uuid.New().String(), userID, leaveType, year, kind, period, days)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RunAccruals credits everything due by asOf under the accrual policies
+// and, once the year has turned, carries forward what was left of the
+// previous year up to each policy's cap. Running it again for the same
+// date changes nothing.
+func (db *Database) RunAccruals(actorID string, asOf time.Time) (*models.AccrualRun, error) {
+	run := &models.AccrualRun{AsOf: asOf.Format(workdays.DateLayout)}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	policies, err := getAccrualPolicies(tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return run, nil
+	}
+	leaveTypes, err := leaveTypesByCode(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT " + userColumns + " FROM users FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	year := asOf.Year()
+	for _, user := range users {
+		effective, err := effectiveAllowance(tx, &user)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range sortedAccrualPolicies(policies) {
+			leaveType, ok := leaveTypes[p.LeaveType]
+			if !ok || !leaveType.CountsAgainstBalance {
+				continue
+			}
+			annual := leaveType.AllowanceFrom(effective.Allowances)
+			if err := db.rollover(tx, &user, p, year, run); err != nil {
+				return nil, err
+			}
+			for _, credit := range accrual.Due(constants.AccrualFrequency(p.Frequency), float64(annual), user.CreatedAt, year, asOf) {
+				written, err := insertAccrual(tx, user.ID, p.LeaveType, year, accrualCredit, credit.Period, credit.Days)
+				if err != nil {
+					return nil, err
+				}
+				if written {
+					run.Accrued += credit.Days
+				}
+			}
+		}
+	}
+
+	if run.Accrued != 0 || run.CarriedForward != 0 || run.Expired != 0 {
+		if err := recordAudit(tx, actorID, constants.AuditAccrualRun, "", nil, run); err != nil {
+			return nil, err
+		}
+	}
+	return run, tx.Commit()
+}
+
+// rollover closes the year before year for a user and type: the days left
+// over are carried into year up to the cap and the rest expire. The
+// expiry row marks the year as closed.
+func (db *Database) rollover(q querier, user *models.User, p models.AccrualPolicy, year int, run *models.AccrualRun) error {
+	prev := year - 1
+	if prev < user.CreatedAt.Year() {
+		return nil
+	}
+
+	var closed int
+	query := "SELECT COUNT(*) FROM leave_accruals WHERE user_id = ? AND leave_type = ? AND year = ? AND kind = ?"
+	if err := q.QueryRow(query, user.ID, p.LeaveType, prev, accrualExpiry).Scan(&closed); err != nil {
+		return err
+	}
+	if closed > 0 {
+		return nil
+	}
+
+	credited, _, err := accruedDays(q, user.ID, p.LeaveType, prev)
+	if err != nil {
+		return err
+	}
+	used, err := usedDays(q, user.ID, p.LeaveType, prev)
+	if err != nil {
+		return err
+	}
+	pending, err := db.pendingDays(q, user.ID, p.LeaveType, prev, "")
+	if err != nil {
+		return err
+	}
+	carried, expired := accrual.CarryForward(credited-used-pending, p.CarryForwardCap)
+
+	if _, err := insertAccrual(q, user.ID, p.LeaveType, prev, accrualExpiry, 0, -(carried + expired)); err != nil {
+		return err
+	}
+	if carried > 0 {
+		if _, err := insertAccrual(q, user.ID, p.LeaveType, year, accrualCarryForward, 0, carried); err != nil {
+			return err
+		}
+	}
+	run.CarriedForward += carried
+	run.Expired += expired
+	return nil
+}
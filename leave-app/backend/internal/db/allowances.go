@@ -0,0 +1,312 @@
+// internal/db/allowances.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicatePolicy = errors.New("an allowance policy with that name already exists")
+	ErrUnknownUser     = errors.New("unknown user")
+)
+
+// policyMatch is an allowance policy that applies to a user, either
+// directly or through Group
+type policyMatch struct {
+	Policy models.AllowancePolicy
+	Group  string
+}
+
+// outranks reports whether a should be used over b: direct assignments
+// beat group ones, then higher priority wins, then the name breaks ties
+func (a *policyMatch) outranks(b *policyMatch) bool {
+	if (a.Group == "") != (b.Group == "") {
+		return a.Group == ""
+	}
+	if a.Policy.Priority != b.Policy.Priority {
+		return a.Policy.Priority > b.Policy.Priority
+	}
+	return a.Policy.Name < b.Policy.Name
+}
+
+// resolvePolicy picks the winning match, falling back to the allowances
+// on the user's own row
+func resolvePolicy(user *models.User, matches []policyMatch) *models.EffectivePolicy {
+	var best *policyMatch
+	for i := range matches {
+		if best == nil || matches[i].outranks(best) {
+			best = &matches[i]
+		}
+	}
+	if best == nil {
+		return &models.EffectivePolicy{Source: string(constants.AllowanceSourceDefault), Allowances: user.Allowances}
+	}
+
+	source := constants.AllowanceSourceUser
+	if best.Group != "" {
+		source = constants.AllowanceSourceGroup
+	}
+	return &models.EffectivePolicy{
+		Source:     string(source),
+		PolicyID:   best.Policy.ID,
+		PolicyName: best.Policy.Name,
+		Group:      best.Group,
+		Allowances: best.Policy.Allowances,
+	}
+}
+
+// effectiveAllowance resolves the allowance policy that applies to user
+func effectiveAllowance(q querier, user *models.User) (*models.EffectivePolicy, error) {
+	query := `
+		SELECT p.id, p.name, p.priority, p.sick_allowance, p.annual_allowance, p.casual_allowance,
+			IF(a.subject_type = 'group', a.subject_id, '')
+		FROM allowance_policy_assignments a
+		JOIN allowance_policies p ON p.id = a.policy_id
+		WHERE (a.subject_type = 'user' AND a.subject_id = ?)
+			OR (a.subject_type = 'group' AND a.subject_id IN (SELECT group_name FROM user_groups WHERE user_id = ?))
+	`
+	rows, err := q.Query(query, user.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []policyMatch
+	for rows.Next() {
+		var m policyMatch
+		a := &m.Policy.Allowances
+		if err := rows.Scan(&m.Policy.ID, &m.Policy.Name, &m.Policy.Priority, &a.Sick, &a.Annual, &a.Casual, &m.Group); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return resolvePolicy(user, matches), nil
+}
+
+// GetEffectivePolicy returns the allowances that apply to a user
+func (db *Database) GetEffectivePolicy(userID string) (*models.EffectivePolicy, error) {
+	user, err := scanUser(db.Conn.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", userID))
+	if err != nil {
+		return nil, err
+	}
+	return effectiveAllowance(db.Conn, user)
+}
+
+const allowancePolicyColumns = "id, name, priority, sick_allowance, annual_allowance, casual_allowance"
+
+func scanAllowancePolicy(row rowScanner) (*models.AllowancePolicy, error) {
+	p := &models.AllowancePolicy{Users: []string{}, Groups: []string{}}
+	a := &p.Allowances
+	if err := row.Scan(&p.ID, &p.Name, &p.Priority, &a.Sick, &a.Annual, &a.Casual); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadAssignments fills in the users and groups assigned to each policy
+func loadAssignments(q querier, policies map[string]*models.AllowancePolicy) error {
+	rows, err := q.Query("SELECT subject_type, subject_id, policy_id FROM allowance_policy_assignments ORDER BY subject_id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subjectType, subjectID, policyID string
+		if err := rows.Scan(&subjectType, &subjectID, &policyID); err != nil {
+			return err
+		}
+		p, ok := policies[policyID]
+		if !ok {
+			continue
+		}
+		if subjectType == string(constants.AllowanceSourceUser) {
+			p.Users = append(p.Users, subjectID)
+		} else {
+			p.Groups = append(p.Groups, subjectID)
+		}
+	}
+	return rows.Err()
+}
+
+func (db *Database) GetAllowancePolicies() ([]models.AllowancePolicy, error) {
+	rows, err := db.Conn.Query("SELECT " + allowancePolicyColumns + " FROM allowance_policies ORDER BY priority DESC, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var order []string
+	byID := make(map[string]*models.AllowancePolicy)
+	for rows.Next() {
+		p, err := scanAllowancePolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, p.ID)
+		byID[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadAssignments(db.Conn, byID); err != nil {
+		return nil, err
+	}
+
+	policies := make([]models.AllowancePolicy, 0, len(order))
+	for _, id := range order {
+		policies = append(policies, *byID[id])
+	}
+	return policies, nil
+}
+
+// CreateAllowancePolicy inserts a policy with no assignments
+func (db *Database) CreateAllowancePolicy(actorID string, policy *models.AllowancePolicy) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM allowance_policies WHERE name = ?", policy.Name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrDuplicatePolicy
+	}
+
+	policy.ID = uuid.New().String()
+	policy.Users, policy.Groups = []string{}, []string{}
+	a := policy.Allowances
+	query := "INSERT INTO allowance_policies (id, name, priority, sick_allowance, annual_allowance, casual_allowance) VALUES (?, ?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, policy.ID, policy.Name, policy.Priority, a.Sick, a.Annual, a.Casual); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditAllowancePolicyCreate, policy.ID, nil, policy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AssignAllowancePolicy replaces the users and groups a policy is assigned
+// to. Users and groups assigned elsewhere move to this policy.
+func (db *Database) AssignAllowancePolicy(actorID string, policyID string, userIDs []string, groups []string) (*models.AllowancePolicy, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanAllowancePolicy(tx.QueryRow("SELECT "+allowancePolicyColumns+" FROM allowance_policies WHERE id = ? FOR UPDATE", policyID))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadAssignments(tx, map[string]*models.AllowancePolicy{policyID: before}); err != nil {
+		return nil, err
+	}
+
+	for _, userID := range userIDs {
+		var exists int
+		if err := tx.QueryRow("SELECT 1 FROM users WHERE id = ?", userID).Scan(&exists); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrUnknownUser
+			}
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM allowance_policy_assignments WHERE policy_id = ?", policyID); err != nil {
+		return nil, err
+	}
+	assign := "INSERT INTO allowance_policy_assignments (subject_type, subject_id, policy_id) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE policy_id = VALUES(policy_id)"
+	for _, userID := range userIDs {
+		if _, err := tx.Exec(assign, string(constants.AllowanceSourceUser), userID, policyID); err != nil {
+			return nil, err
+		}
+	}
+	for _, group := range groups {
+		if _, err := tx.Exec(assign, string(constants.AllowanceSourceGroup), group, policyID); err != nil {
+			return nil, err
+		}
+	}
+
+	after := *before
+	after.Users, after.Groups = sortedUnique(userIDs), sortedUnique(groups)
+	if err := recordAudit(tx, actorID, constants.AuditAllowancePolicyAssign, policyID, before, after); err != nil {
+		return nil, err
+	}
+	return &after, tx.Commit()
+}
+
+func getUserGroups(q querier, userID string) ([]string, error) {
+	rows, err := q.Query("SELECT group_name FROM user_groups WHERE user_id = ? ORDER BY group_name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]string, 0)
+	for rows.Next() {
+		var group string
+		if err := rows.Scan(&group); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// SetUserGroups replaces the groups a user belongs to
+func (db *Database) SetUserGroups(actorID string, userID string, groups []string) ([]string, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockUser(tx, userID); err != nil {
+		return nil, err
+	}
+	before, err := getUserGroups(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_groups WHERE user_id = ?", userID); err != nil {
+		return nil, err
+	}
+	after := sortedUnique(groups)
+	for _, group := range after {
+		if _, err := tx.Exec("INSERT INTO user_groups (user_id, group_name) VALUES (?, ?)", userID, group); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(tx, actorID, constants.AuditUserGroups, userID, map[string][]string{"groups": before}, map[string][]string{"groups": after}); err != nil {
+		return nil, err
+	}
+	return after, tx.Commit()
+}
+
+func sortedUnique(values []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
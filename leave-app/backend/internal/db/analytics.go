@@ -0,0 +1,595 @@
+// internal/db/analytics.go
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// decidedStatuses are the statuses of leave whose approval was decided.
+// Cancelled leave had been approved.
+var decidedStatuses = []string{
+	string(constants.LeaveStatusApproved),
+	string(constants.LeaveStatusRejected),
+	string(constants.LeaveStatusCancellationRequested),
+	string(constants.LeaveStatusCancelled),
+}
+
+// analyticsCacheName names the analytics row of cache_generations
+const analyticsCacheName = "analytics"
+
+// analyticsCache keeps computed analytics by range, stamped with the
+// generation of leave they were computed from. A result only counts while
+// that generation is current.
+type analyticsCache struct {
+	mu      sync.Mutex
+	entries map[string]analyticsEntry
+}
+
+type analyticsEntry struct {
+	gen       uint64
+	analytics *models.LeaveAnalytics
+}
+
+// lookup returns the analytics cached for key under generation gen, or nil
+func (c *analyticsCache) lookup(key string, gen uint64, now time.Time) *models.LeaveAnalytics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && e.gen == gen && now.Sub(e.analytics.GeneratedAt) < constants.AnalyticsCacheMinutes*time.Minute {
+		return e.analytics
+	}
+	return nil
+}
+
+// store keeps analytics computed under generation gen and drops what was
+// computed under earlier ones
+func (c *analyticsCache) store(key string, gen uint64, analytics *models.LeaveAnalytics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]analyticsEntry)
+	}
+	for k, e := range c.entries {
+		if e.gen < gen {
+			delete(c.entries, k)
+		}
+	}
+	if e, ok := c.entries[key]; ok && e.gen > gen {
+		return
+	}
+	c.entries[key] = analyticsEntry{gen: gen, analytics: analytics}
+}
+
+// analyticsRange parses an analytics range of at most
+// constants.MaxAnalyticsDays days
+func analyticsRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.Parse(workdays.DateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidDateRange
+	}
+	end, err := time.Parse(workdays.DateLayout, to)
+	if err != nil || end.Before(start) || end.After(start.AddDate(0, 0, constants.MaxAnalyticsDays-1)) {
+		return time.Time{}, time.Time{}, ErrInvalidDateRange
+	}
+	return start, end, nil
+}
+
+// bradfordRange is the rolling window Bradford factors cover: the
+// constants.BradfordWeeks weeks up to and including today
+func bradfordRange(now time.Time) (time.Time, time.Time) {
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, 0, 1-7*constants.BradfordWeeks), end
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// newTurnaround reports decided leaves of leaveType that waited seconds on
+// average and at most
+func newTurnaround(leaveType string, decided int, average, most float64) models.ApprovalTurnaround {
+	return models.ApprovalTurnaround{Type: leaveType, Decided: decided, AverageHours: round(average / 3600), MaxHours: round(most / 3600)}
+}
+
+func newRejectionRate(leaveType string, decided, rejected int) models.RejectionRate {
+	rate := models.RejectionRate{Type: leaveType, Decided: decided, Rejected: rejected}
+	if decided > 0 {
+		rate.Rate = round(float64(rejected) / float64(decided))
+	}
+	return rate
+}
+
+// newAnalytics starts the analytics of a range with every month in it
+func newAnalytics(start, end time.Time) *models.LeaveAnalytics {
+	analytics := &models.LeaveAnalytics{
+		From:     start.Format(workdays.DateLayout),
+		To:       end.Format(workdays.DateLayout),
+		Months:   make([]models.MonthlyLeaveDays, 0),
+		Teams:    make([]models.TeamLeaveDays, 0),
+		Bradford: make([]models.BradfordScore, 0),
+	}
+	for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(end); month = month.AddDate(0, 1, 0) {
+		analytics.Months = append(analytics.Months, models.MonthlyLeaveDays{Month: month.Format("2006-01"), Days: make(map[string]float64)})
+	}
+	return analytics
+}
+
+// roundLeaveDays rounds the day counts of analytics once they are summed
+func roundLeaveDays(analytics *models.LeaveAnalytics) {
+	for i := range analytics.Months {
+		m := &analytics.Months[i]
+		for leaveType, days := range m.Days {
+			m.Days[leaveType] = round(days)
+		}
+		m.Total = round(m.Total)
+	}
+	for i := range analytics.Teams {
+		t := &analytics.Teams[i]
+		for leaveType, days := range t.Days {
+			t.Days[leaveType] = round(days)
+		}
+		t.Total = round(t.Total)
+	}
+}
+
+// newBradfordScore scores spells of sick leave adding up to days
+func newBradfordScore(userID, email string, spells int, days float64) models.BradfordScore {
+	score := models.BradfordScore{UserID: userID, UserEmail: email, Spells: spells, Days: round(days)}
+	score.Score = round(float64(spells*spells) * score.Days)
+	return score
+}
+
+// sortBradford puts the highest scores first
+func sortBradford(scores []models.BradfordScore) {
+	slices.SortFunc(scores, func(a, b models.BradfordScore) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.UserEmail, b.UserEmail))
+	})
+}
+
+// spell is a run of sick leave with no working day between its leaves
+type spell struct {
+	end  time.Time
+	days float64
+}
+
+// buildAnalytics is what the Database sums in SQL, for the MemoryStore: it
+// lays the approved leaves out over the working days from start to end, by
+// month and by the owner's team. users are everyone, for their managers.
+func buildAnalytics(cal *workdays.Calendar, start, end time.Time, users []models.User, leaves []models.Leave) *models.LeaveAnalytics {
+	analytics := newAnalytics(start, end)
+	months := make(map[string]*models.MonthlyLeaveDays)
+	for i := range analytics.Months {
+		months[analytics.Months[i].Month] = &analytics.Months[i]
+	}
+
+	byID := make(map[string]*models.User, len(users))
+	teamOf := make(map[string]string, len(users)) // User ID to manager ID, "" for none
+	teams := make(map[string]*models.TeamLeaveDays)
+	for i := range users {
+		u := &users[i]
+		byID[u.ID] = u
+		managerID := ""
+		if u.ManagerID != nil {
+			managerID = *u.ManagerID
+		}
+		teamOf[u.ID] = managerID
+		if teams[managerID] == nil {
+			teams[managerID] = &models.TeamLeaveDays{Days: make(map[string]float64)}
+		}
+		teams[managerID].Members++
+	}
+
+	for i := range leaves {
+		l := &leaves[i]
+		from, _ := parseDate(l.StartDate)
+		to, _ := parseDate(l.EndDate)
+		for day := maxTime(from, start); !day.After(to) && !day.After(end); day = day.AddDate(0, 0, 1) {
+			if !cal.IsWorkingDay(day) {
+				continue
+			}
+			weight := dayWeight(l)
+			month := months[day.Format("2006-01")]
+			month.Days[l.Type] += weight
+			month.Total += weight
+			if team := teams[teamOf[l.UserID]]; team != nil {
+				team.Days[l.Type] += weight
+				team.Total += weight
+			}
+		}
+	}
+
+	for managerID, team := range teams {
+		if manager, ok := byID[managerID]; ok {
+			team.ManagerID = &manager.ID
+			team.ManagerEmail = manager.Email
+		}
+		analytics.Teams = append(analytics.Teams, *team)
+	}
+	slices.SortFunc(analytics.Teams, func(a, b models.TeamLeaveDays) int {
+		// The team without a manager goes last
+		if (a.ManagerID == nil) != (b.ManagerID == nil) {
+			if a.ManagerID == nil {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.ManagerEmail, b.ManagerEmail)
+	})
+	roundLeaveDays(analytics)
+	return analytics
+}
+
+// buildBradford is bradfordScores for the MemoryStore: it scores the
+// spells of approved sick leave in the working days from start to end
+func buildBradford(cal *workdays.Calendar, start, end time.Time, users []models.User, leaves []models.Leave) []models.BradfordScore {
+	emails := make(map[string]string, len(users))
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+
+	slices.SortFunc(leaves, func(a, b models.Leave) int {
+		return cmp.Compare(sqlDate(a.StartDate), sqlDate(b.StartDate))
+	})
+	spells := make(map[string][]spell)
+	for i := range leaves {
+		l := &leaves[i]
+		if l.Type != string(constants.LeaveTypeSick) {
+			continue
+		}
+		from, _ := parseDate(l.StartDate)
+		to, _ := parseDate(l.EndDate)
+		var taken float64
+		for day := maxTime(from, start); !day.After(to) && !day.After(end); day = day.AddDate(0, 0, 1) {
+			if cal.IsWorkingDay(day) {
+				taken += dayWeight(l)
+			}
+		}
+		if taken == 0 {
+			continue
+		}
+		runs := spells[l.UserID]
+		if n := len(runs); n > 0 && !workingDayBetween(cal, runs[n-1].end, from) {
+			runs[n-1].end = maxTime(runs[n-1].end, to)
+			runs[n-1].days += taken
+			continue
+		}
+		spells[l.UserID] = append(runs, spell{end: to, days: taken})
+	}
+
+	scores := make([]models.BradfordScore, 0, len(spells))
+	for userID, runs := range spells {
+		var days float64
+		for _, s := range runs {
+			days += s.days
+		}
+		scores = append(scores, newBradfordScore(userID, emails[userID], len(runs), days))
+	}
+	sortBradford(scores)
+	return scores
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// workingDayBetween reports whether there is a working day after a and
+// before b
+func workingDayBetween(cal *workdays.Calendar, a, b time.Time) bool {
+	for day := a.AddDate(0, 0, 1); day.Before(b); day = day.AddDate(0, 0, 1) {
+		if cal.IsWorkingDay(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// workingDaysJSON lists the working days from start to end as a JSON
+// array, for JSON_TABLE to turn into rows
+func workingDaysJSON(cal *workdays.Calendar, start, end time.Time) (string, error) {
+	days := make([]string, 0)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if cal.IsWorkingDay(day) {
+			days = append(days, day.Format(workdays.DateLayout))
+		}
+	}
+	raw, err := json.Marshal(days)
+	return string(raw), err
+}
+
+// leaveDayWeight is dayWeight in SQL, for leave aliased l
+var leaveDayWeight = fmt.Sprintf(`CASE l.portion
+		WHEN '%s' THEN 0.5
+		WHEN '%s' THEN 0.5
+		WHEN '%s' THEN TIME_TO_SEC(TIMEDIFF(l.end_time, l.start_time)) / 3600 / %g
+		ELSE 1 END`,
+	constants.LeavePortionFirstHalf, constants.LeavePortionSecondHalf, constants.LeavePortionHourly, constants.WorkingHoursPerDay)
+
+// leaveDaysFrom joins every approved leave, as l with its owner as u, to
+// each of the working days it covers, as d.work_date. Its arguments are
+// the working days as from workingDaysJSON, then calendarStatuses.
+var leaveDaysFrom = `
+	FROM JSON_TABLE(?, '$[*]' COLUMNS (work_date DATE PATH '$')) d
+	JOIN leaves l ON l.start_date <= d.work_date AND l.end_date >= d.work_date
+	JOIN users u ON u.id = l.user_id
+	WHERE l.status IN (?, ?)
+`
+
+// monthlyLeaveDays adds the approved days taken on the working days in
+// days to the months of analytics, by leave type
+func monthlyLeaveDays(q querier, days string, analytics *models.LeaveAnalytics) error {
+	query := `
+		SELECT DATE_FORMAT(d.work_date, '%Y-%m'), l.type, SUM(` + leaveDayWeight + `)
+		` + leaveDaysFrom + `
+		GROUP BY DATE_FORMAT(d.work_date, '%Y-%m'), l.type
+	`
+	rows, err := q.Query(query, days, calendarStatuses[0], calendarStatuses[1])
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	months := make(map[string]*models.MonthlyLeaveDays, len(analytics.Months))
+	for i := range analytics.Months {
+		months[analytics.Months[i].Month] = &analytics.Months[i]
+	}
+	for rows.Next() {
+		var month, leaveType string
+		var taken float64
+		if err := rows.Scan(&month, &leaveType, &taken); err != nil {
+			return err
+		}
+		if m := months[month]; m != nil {
+			m.Days[leaveType] += taken
+			m.Total += taken
+		}
+	}
+	return rows.Err()
+}
+
+// teamLeaveDays sums the approved days taken on the working days in days
+// by each manager's direct reports, by leave type. The team without a
+// manager comes last.
+func teamLeaveDays(q querier, days string) ([]models.TeamLeaveDays, error) {
+	query := `
+		SELECT m.id, COALESCE(m.email, ''), t.members, COALESCE(x.type, ''), COALESCE(x.days, 0)
+		FROM (SELECT manager_id, COUNT(*) AS members FROM users GROUP BY manager_id) t
+		LEFT JOIN users m ON m.id = t.manager_id
+		LEFT JOIN (
+			SELECT u.manager_id, l.type, SUM(` + leaveDayWeight + `) AS days
+			` + leaveDaysFrom + `
+			GROUP BY u.manager_id, l.type
+		) x ON x.manager_id <=> t.manager_id
+		ORDER BY m.id IS NULL, m.email, t.manager_id, x.type
+	`
+	rows, err := q.Query(query, days, calendarStatuses[0], calendarStatuses[1])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := make([]models.TeamLeaveDays, 0)
+	var team *models.TeamLeaveDays
+	for rows.Next() {
+		var managerID *string
+		var email, leaveType string
+		var members int
+		var taken float64
+		if err := rows.Scan(&managerID, &email, &members, &leaveType, &taken); err != nil {
+			return nil, err
+		}
+		// A team's rows are together, one per leave type taken
+		if team == nil || !equalStrings(team.ManagerID, managerID) {
+			teams = append(teams, models.TeamLeaveDays{ManagerID: managerID, ManagerEmail: email, Members: members, Days: make(map[string]float64)})
+			team = &teams[len(teams)-1]
+		}
+		if leaveType != "" {
+			team.Days[leaveType] += taken
+			team.Total += taken
+		}
+	}
+	return teams, rows.Err()
+}
+
+// equalStrings reports whether a and b are both nil or point to equal values
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// bradfordScores scores everyone's approved sick leave on the working days
+// in days. Leaves in the same spell have no working day between them:
+// each leave starts a new spell unless none lies between it and the
+// latest end of the owner's earlier leaves.
+func bradfordScores(q querier, days string) ([]models.BradfordScore, error) {
+	query := `
+		WITH days AS (
+			SELECT work_date FROM JSON_TABLE(?, '$[*]' COLUMNS (work_date DATE PATH '$')) d
+		),
+		taken AS (
+			SELECT l.id, l.user_id, l.start_date, l.end_date, SUM(` + leaveDayWeight + `) AS days
+			FROM days d
+			JOIN leaves l ON l.start_date <= d.work_date AND l.end_date >= d.work_date
+			WHERE l.type = ? AND l.status IN (?, ?)
+			GROUP BY l.id, l.user_id, l.start_date, l.end_date
+		),
+		runs AS (
+			SELECT user_id, start_date, days,
+				MAX(end_date) OVER (PARTITION BY user_id ORDER BY start_date, id ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING) AS prev_end
+			FROM taken
+		)
+		SELECT r.user_id, u.email,
+			SUM(r.prev_end IS NULL OR EXISTS (SELECT 1 FROM days g WHERE g.work_date > r.prev_end AND g.work_date < r.start_date)),
+			SUM(r.days)
+		FROM runs r
+		JOIN users u ON u.id = r.user_id
+		GROUP BY r.user_id, u.email
+	`
+	rows, err := q.Query(query, days, string(constants.LeaveTypeSick), calendarStatuses[0], calendarStatuses[1])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores := make([]models.BradfordScore, 0)
+	for rows.Next() {
+		var userID, email string
+		var spells int
+		var taken float64
+		if err := rows.Scan(&userID, &email, &spells, &taken); err != nil {
+			return nil, err
+		}
+		scores = append(scores, newBradfordScore(userID, email, spells, taken))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortBradford(scores)
+	return scores, nil
+}
+
+// approvalTurnaround sums up, by leave type, how long the leave filed from
+// start to end waited for its last decision. Leave decided without its
+// approval chain, such as imported leave, has no decision time and is left
+// out.
+func approvalTurnaround(q querier, start, end time.Time) ([]models.ApprovalTurnaround, error) {
+	query := `
+		SELECT l.type, COUNT(*), AVG(TIMESTAMPDIFF(SECOND, l.created_at, d.decided_at)), MAX(TIMESTAMPDIFF(SECOND, l.created_at, d.decided_at))
+		FROM leaves l
+		JOIN (SELECT leave_id, MAX(decided_at) AS decided_at FROM leave_approvals WHERE decided_at IS NOT NULL GROUP BY leave_id) d ON d.leave_id = l.id
+		WHERE l.status IN (?, ?, ?, ?) AND l.created_at >= ? AND l.created_at < ?
+		GROUP BY l.type
+		ORDER BY l.type
+	`
+	args := []any{decidedStatuses[0], decidedStatuses[1], decidedStatuses[2], decidedStatuses[3], start, end.AddDate(0, 0, 1)}
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	turnaround := make([]models.ApprovalTurnaround, 0)
+	for rows.Next() {
+		var leaveType string
+		var decided int
+		var average, most float64
+		if err := rows.Scan(&leaveType, &decided, &average, &most); err != nil {
+			return nil, err
+		}
+		turnaround = append(turnaround, newTurnaround(leaveType, decided, average, most))
+	}
+	return turnaround, rows.Err()
+}
+
+// rejectionRates counts, by leave type, how much of the leave filed from
+// start to end was decided and how much of that rejected
+func rejectionRates(q querier, start, end time.Time) ([]models.RejectionRate, error) {
+	query := `
+		SELECT l.type, COUNT(*), SUM(l.status = ?)
+		FROM leaves l
+		WHERE l.status IN (?, ?, ?, ?) AND l.created_at >= ? AND l.created_at < ?
+		GROUP BY l.type
+		ORDER BY l.type
+	`
+	args := []any{string(constants.LeaveStatusRejected), decidedStatuses[0], decidedStatuses[1], decidedStatuses[2], decidedStatuses[3], start, end.AddDate(0, 0, 1)}
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]models.RejectionRate, 0)
+	for rows.Next() {
+		var leaveType string
+		var decided, rejected int
+		if err := rows.Scan(&leaveType, &decided, &rejected); err != nil {
+			return nil, err
+		}
+		rates = append(rates, newRejectionRate(leaveType, decided, rejected))
+	}
+	return rates, rows.Err()
+}
+
+// analyticsGeneration reads the current generation of leave, which every
+// change to leave, teams or holidays bumps
+func analyticsGeneration(q querier) (uint64, error) {
+	var gen uint64
+	err := q.QueryRow("SELECT generation FROM cache_generations WHERE name = ?", analyticsCacheName).Scan(&gen)
+	return gen, err
+}
+
+// commitLeaves commits a transaction that changed leave, teams or holidays,
+// bumping the generation so every instance drops the analytics it
+// computed before. The bump comes last to hold its row lock briefly.
+func (db *Database) commitLeaves(tx *sql.Tx) error {
+	if _, err := tx.Exec("UPDATE cache_generations SET generation = generation + 1 WHERE name = ?", analyticsCacheName); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetLeaveAnalytics returns absence trends from one YYYY-MM-DD date to
+// another, from the cache when no leave has changed since they were
+// computed. Bradford factors cover the rolling window of bradfordRange
+// whatever the range.
+func (db *Database) GetLeaveAnalytics(from, to string) (*models.LeaveAnalytics, error) {
+	start, end, err := analyticsRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	gen, err := analyticsGeneration(db.Conn)
+	if err != nil {
+		return nil, err
+	}
+	key := from + "/" + to
+	if cached := db.analytics.lookup(key, gen, now); cached != nil {
+		return cached, nil
+	}
+
+	analytics := newAnalytics(start, end)
+	days, err := workingDaysJSON(db.Calendar, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err := monthlyLeaveDays(db.Conn, days, analytics); err != nil {
+		return nil, err
+	}
+	if analytics.Teams, err = teamLeaveDays(db.Conn, days); err != nil {
+		return nil, err
+	}
+	roundLeaveDays(analytics)
+
+	bradfordStart, bradfordEnd := bradfordRange(now)
+	analytics.BradfordFrom, analytics.BradfordTo = bradfordStart.Format(workdays.DateLayout), bradfordEnd.Format(workdays.DateLayout)
+	if days, err = workingDaysJSON(db.Calendar, bradfordStart, bradfordEnd); err != nil {
+		return nil, err
+	}
+	if analytics.Bradford, err = bradfordScores(db.Conn, days); err != nil {
+		return nil, err
+	}
+
+	if analytics.Turnaround, err = approvalTurnaround(db.Conn, start, end); err != nil {
+		return nil, err
+	}
+	if analytics.Rejections, err = rejectionRates(db.Conn, start, end); err != nil {
+		return nil, err
+	}
+	analytics.GeneratedAt = now
+	db.analytics.store(key, gen, analytics)
+	return analytics, nil
+}
@@ -0,0 +1,253 @@
+// internal/db/approvals.go
+package db
+
+import (
+	"errors"
+	"leave-app/internal/approval"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotApprover     = errors.New("not an approver for the leave's current step")
+	ErrLeaveNotPending = errors.New("leave is not awaiting approval")
+)
+
+func getApprovalPolicies(q querier) ([]models.ApprovalPolicy, error) {
+	rows, err := q.Query("SELECT id, leave_type, over_days, steps FROM approval_policies ORDER BY leave_type, over_days")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]models.ApprovalPolicy, 0)
+	for rows.Next() {
+		var p models.ApprovalPolicy
+		var steps string
+		if err := rows.Scan(&p.ID, &p.LeaveType, &p.OverDays, &steps); err != nil {
+			return nil, err
+		}
+		p.Steps = approval.SplitSteps(steps)
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+func (db *Database) GetApprovalPolicies() ([]models.ApprovalPolicy, error) {
+	return getApprovalPolicies(db.Conn)
+}
+
+// ReplaceApprovalPolicies swaps the whole policy set. Leaves already filed
+// keep the steps they were created with.
+func (db *Database) ReplaceApprovalPolicies(actorID string, policies []models.ApprovalPolicy) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getApprovalPolicies(tx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM approval_policies"); err != nil {
+		return err
+	}
+	for i := range policies {
+		policies[i].ID = uuid.New().String()
+		p := policies[i]
+		query := "INSERT INTO approval_policies (id, leave_type, over_days, steps) VALUES (?, ?, ?, ?)"
+		if _, err := tx.Exec(query, p.ID, p.LeaveType, p.OverDays, approval.JoinSteps(p.Steps)); err != nil {
+			return err
+		}
+	}
+	if err := recordAudit(tx, actorID, constants.AuditApprovalPolicies, "", before, policies); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func getLeaveApprovals(q querier, leaveID string) ([]models.LeaveApproval, error) {
+	query := "SELECT step, role, decision, approver_id, on_behalf_of, comment, decided_at FROM leave_approvals WHERE leave_id = ? ORDER BY step"
+	rows, err := q.Query(query, leaveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := make([]models.LeaveApproval, 0)
+	for rows.Next() {
+		var a models.LeaveApproval
+		if err := rows.Scan(&a.Step, &a.Role, &a.Decision, &a.ApproverID, &a.OnBehalfOfID, &a.Comment, &a.DecidedAt); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+// DecideLeave records actor's decision on the leave's current approval
+// step, or on every open step when decision.AllSteps. Rejecting any step
+// rejects the leave; approving the last step approves it, which charges
+// the balance and must not take the owner's team below its minimum
+// staffing unless decision.Confirm. The warnings confirmed are returned.
+// For a requested cancellation, approving cancels the leave and rejecting
+// keeps it approved. Actors may decide with the authority of anyone who
+// delegated it to them today.
+func (db *Database) DecideLeave(leaveID string, actor *models.User, decision models.LeaveDecision) ([]models.StaffingWarning, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	leave, err := db.lockLeave(tx, leaveID)
+	if err != nil {
+		return nil, err
+	}
+	delegators, err := activeDelegators(tx, actor.ID, today())
+	if err != nil {
+		return nil, err
+	}
+	if leave.Status == string(constants.LeaveStatusCancellationRequested) && !decision.AllSteps {
+		status, decider, err := decideCancellation(leave, actor, delegators, decision.Approve)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.setLeaveStatus(tx, actor.ID, leave, status, decision.Comment); err != nil {
+			return nil, err
+		}
+		if err := recordDelegatedDecision(tx, actor, decider, leaveID, status); err != nil {
+			return nil, err
+		}
+		return nil, db.commitLeaves(tx)
+	}
+	if leave.Status != string(constants.LeaveStatusPending) {
+		return nil, ErrLeaveNotPending
+	}
+
+	approvals, err := getLeaveApprovals(tx, leaveID)
+	if err != nil {
+		return nil, err
+	}
+	steps, decider, err := stepsToDecide(leave, approvals, actor, delegators, decision.AllSteps)
+	if err != nil {
+		return nil, err
+	}
+	last := steps[len(steps)-1]
+
+	status := constants.LeaveStatusRejected
+	var warnings []models.StaffingWarning
+	if decision.Approve {
+		status = constants.LeaveStatusApproved
+		if last == len(approvals)-1 {
+			if warnings, err = db.checkStaffing(tx, leave, decision.Confirm); err != nil {
+				return nil, err
+			}
+		}
+	}
+	now := time.Now()
+	query := "UPDATE leave_approvals SET decision = ?, approver_id = ?, on_behalf_of = ?, comment = ?, decided_at = ? WHERE leave_id = ? AND step = ?"
+	for _, i := range steps {
+		if _, err := tx.Exec(query, string(status), actor.ID, onBehalfOf(actor, decider), decision.Comment, now, leaveID, approvals[i].Step); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordDelegatedDecision(tx, actor, decider, leaveID, string(status)); err != nil {
+		return nil, err
+	}
+
+	if decision.Approve && last < len(approvals)-1 {
+		after := *leave
+		after.CurrentStep = &approvals[last+1].Role
+		restartWait(&after, now)
+		query := "UPDATE leaves SET current_step = ?, awaiting_since = ?, reminded_at = NULL, escalated_at = NULL WHERE id = ?"
+		if _, err := tx.Exec(query, after.CurrentStep, after.AwaitingSince, leaveID); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx, actor.ID, constants.AuditLeaveStep, leaveID, leave, after); err != nil {
+			return nil, err
+		}
+		return nil, db.commitLeaves(tx)
+	}
+
+	if err := db.setLeaveStatus(tx, actor.ID, leave, string(status), decision.Comment); err != nil {
+		return nil, err
+	}
+	return warnings, db.commitLeaves(tx)
+}
+
+// stepsToDecide returns the indexes of the steps a decision closes and
+// whose authority actor decides them with: the current step, or every
+// open one when an admin decides them all at once
+func stepsToDecide(leave *models.Leave, approvals []models.LeaveApproval, actor *models.User, delegators []models.User, allSteps bool) ([]int, *models.User, error) {
+	var open []int
+	for i := range approvals {
+		if approvals[i].Decision == string(constants.LeaveStatusPending) {
+			open = append(open, i)
+		}
+	}
+	if len(open) == 0 {
+		return nil, nil, ErrLeaveNotPending
+	}
+	if allSteps {
+		if actor.Role != string(constants.RoleAdmin) {
+			return nil, nil, ErrNotApprover
+		}
+		return open, actor, nil
+	}
+	decider, ok := approval.Decider(actor, delegators, leave, approvals[open[0]].Role)
+	if !ok {
+		return nil, nil, ErrNotApprover
+	}
+	return open[:1], decider, nil
+}
+
+// decideCancellation returns the status a requested cancellation moves to
+// and whose authority actor decides it with
+func decideCancellation(leave *models.Leave, actor *models.User, delegators []models.User, approve bool) (string, *models.User, error) {
+	if leave.CurrentStep == nil {
+		return "", nil, ErrNotApprover
+	}
+	decider, ok := approval.Decider(actor, delegators, leave, *leave.CurrentStep)
+	if !ok {
+		return "", nil, ErrNotApprover
+	}
+	if approve {
+		return string(constants.LeaveStatusCancelled), decider, nil
+	}
+	return string(constants.LeaveStatusApproved), decider, nil
+}
+
+// GetPendingApprovals returns the pending leaves and cancellation requests
+// that user may decide, including those of the delegators whose authority
+// they hold today
+func (db *Database) GetPendingApprovals(user *models.User) ([]models.Leave, error) {
+	delegators, err := activeDelegators(db.Conn, user.ID, today())
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.status IN (?, ?) AND l.current_step IS NOT NULL
+		ORDER BY l.created_at
+	`
+	leaves, err := db.queryLeaves(db.Conn, query, string(constants.LeaveStatusPending), string(constants.LeaveStatusCancellationRequested))
+	if err != nil {
+		return nil, err
+	}
+
+	actionable := make([]models.Leave, 0)
+	for i := range leaves {
+		if _, ok := approval.Decider(user, delegators, &leaves[i], *leaves[i].CurrentStep); ok {
+			actionable = append(actionable, leaves[i])
+		}
+	}
+	return actionable, nil
+}
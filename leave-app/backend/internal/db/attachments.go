@@ -0,0 +1,91 @@
+// internal/db/attachments.go
+package db
+
+import (
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+)
+
+var ErrAttachmentRequired = errors.New("leave type requires an attachment before approval")
+
+const attachmentColumns = "id, leave_id, file_name, content_type, size_bytes, storage_key, uploaded_by, created_at"
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	err := row.Scan(&a.ID, &a.LeaveID, &a.FileName, &a.ContentType, &a.Size, &a.StorageKey, &a.UploadedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func getAttachments(q querier, leaveID string) ([]models.Attachment, error) {
+	rows, err := q.Query("SELECT "+attachmentColumns+" FROM leave_attachments WHERE leave_id = ? ORDER BY created_at, id", leaveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+	return attachments, rows.Err()
+}
+
+// checkAttachment returns ErrAttachmentRequired if the leave's type needs
+// an attachment for a leave this long and none has been uploaded
+func (db *Database) checkAttachment(q querier, leave *models.Leave) error {
+	leaveType, err := getLeaveType(q, leave.Type)
+	if err != nil {
+		return err
+	}
+	days, err := db.LeaveDuration(leave)
+	if err != nil {
+		return err
+	}
+	if !leaveType.NeedsAttachment(days) {
+		return nil
+	}
+	var count int
+	if err := q.QueryRow("SELECT COUNT(*) FROM leave_attachments WHERE leave_id = ?", leave.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAttachmentRequired
+	}
+	return nil
+}
+
+// AddAttachment records a file already written to blob storage
+func (db *Database) AddAttachment(actorID string, attachment *models.Attachment) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := db.lockLeave(tx, attachment.LeaveID); err != nil {
+		return err
+	}
+
+	a := attachment
+	query := "INSERT INTO leave_attachments (id, leave_id, file_name, content_type, size_bytes, storage_key, uploaded_by) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, a.ID, a.LeaveID, a.FileName, a.ContentType, a.Size, a.StorageKey, a.UploadedBy); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditLeaveAttach, a.LeaveID, nil, a); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *Database) GetAttachment(leaveID, attachmentID string) (*models.Attachment, error) {
+	query := "SELECT " + attachmentColumns + " FROM leave_attachments WHERE id = ? AND leave_id = ?"
+	return scanAttachment(db.Conn.QueryRow(query, attachmentID, leaveID))
+}
@@ -0,0 +1,143 @@
+// internal/db/audit.go
+package db
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"strings"
+	"time"
+)
+
+// DefaultAuditLimit caps GET /api/admin/audit when no limit is given
+const DefaultAuditLimit = 100
+
+// newAuditEvent builds an event for action on entityID. actorID is empty
+// for changes nobody in particular made; nil before or after is omitted.
+func newAuditEvent(actorID string, action constants.AuditAction, entityID string, before, after any) (*models.AuditEvent, error) {
+	event := &models.AuditEvent{
+		Action:     string(action),
+		EntityType: strings.SplitN(string(action), ".", 2)[0],
+		EntityID:   entityID,
+		CreatedAt:  time.Now(),
+	}
+	if actorID != "" {
+		event.ActorID = &actorID
+	}
+	var err error
+	if before != nil {
+		if event.Before, err = json.Marshal(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if event.After, err = json.Marshal(after); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// recordAudit appends an event in the caller's transaction, so the change
+// and its audit row commit or roll back together
+func recordAudit(q querier, actorID string, action constants.AuditAction, entityID string, before, after any) error {
+	event, err := newAuditEvent(actorID, action, entityID, before, after)
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO audit_events (actor_id, action, entity_type, entity_id, before_json, after_json) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err = q.Exec(query, event.ActorID, event.Action, event.EntityType, event.EntityID, nullJSON(event.Before), nullJSON(event.After))
+	return err
+}
+
+func nullJSON(raw json.RawMessage) any {
+	if raw == nil {
+		return nil
+	}
+	return string(raw)
+}
+
+// auditRange turns the filter's inclusive dates into a half-open range of
+// times; zero times are unbounded
+func auditRange(filter models.AuditFilter) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if filter.From != "" {
+		if from, err = time.ParseInLocation(workdays.DateLayout, filter.From, time.Local); err != nil {
+			return from, to, ErrInvalidDateRange
+		}
+	}
+	if filter.To != "" {
+		if to, err = time.ParseInLocation(workdays.DateLayout, filter.To, time.Local); err != nil {
+			return from, to, ErrInvalidDateRange
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, ErrInvalidDateRange
+	}
+	return from, to, nil
+}
+
+func auditLimit(filter models.AuditFilter) int {
+	if filter.Limit > 0 {
+		return filter.Limit
+	}
+	return DefaultAuditLimit
+}
+
+// GetAuditEvents returns the newest events matching filter
+func (db *Database) GetAuditEvents(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	from, to, err := auditRange(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	for _, f := range []struct{ column, value string }{
+		{"actor_id", filter.ActorID},
+		{"action", filter.Action},
+		{"entity_type", filter.EntityType},
+		{"entity_id", filter.EntityID},
+	} {
+		if f.value != "" {
+			where = append(where, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if !from.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, to)
+	}
+
+	query := "SELECT id, actor_id, action, entity_type, entity_id, before_json, after_json, created_at FROM audit_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, auditLimit(filter))
+
+	rows, err := db.Conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.AuditEvent, 0)
+	for rows.Next() {
+		var e models.AuditEvent
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID, &before, &after, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
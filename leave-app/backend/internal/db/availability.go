@@ -0,0 +1,279 @@
+// internal/db/availability.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"slices"
+	"time"
+)
+
+// availabilityStatuses are the statuses shown in team availability.
+// Pending leave is listed; only calendarStatuses count against staffing.
+var availabilityStatuses = []string{
+	string(constants.LeaveStatusPending),
+	string(constants.LeaveStatusApproved),
+	string(constants.LeaveStatusCancellationRequested),
+}
+
+// StaffingError is returned when approving a leave takes its owner's team
+// below the minimum staffing and the approver has not confirmed
+type StaffingError struct {
+	Warnings []models.StaffingWarning
+}
+
+func (e *StaffingError) Error() string {
+	return fmt.Sprintf("approving the leave takes the team below its minimum staffing on %d day(s)", len(e.Warnings))
+}
+
+// confirmStaffing fails with a StaffingError when there are warnings the
+// approver has not confirmed
+func confirmStaffing(warnings []models.StaffingWarning, confirm bool) error {
+	if len(warnings) > 0 && !confirm {
+		return &StaffingError{Warnings: warnings}
+	}
+	return nil
+}
+
+// staffingAudit is what the audit trail records about a team's minimum
+type staffingAudit struct {
+	MinStaffing int `json:"minStaffing"`
+}
+
+// availabilityRange parses an availability range of at most
+// constants.MaxAvailabilityDays days
+func availabilityRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.Parse(workdays.DateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidDateRange
+	}
+	end, err := time.Parse(workdays.DateLayout, to)
+	if err != nil || end.Before(start) || end.After(start.AddDate(0, 0, constants.MaxAvailabilityDays-1)) {
+		return time.Time{}, time.Time{}, ErrInvalidDateRange
+	}
+	return start, end, nil
+}
+
+// dayWeight is how much of a working day a leave takes off its owner
+func dayWeight(leave *models.Leave) float64 {
+	switch constants.LeavePortion(leave.Portion) {
+	case constants.LeavePortionFirstHalf, constants.LeavePortionSecondHalf:
+		return 0.5
+	case constants.LeavePortionHourly:
+		return leave.WorkingDays
+	}
+	return 1
+}
+
+// buildAvailability lays the members' leaves out over the working days
+// from start to end
+func buildAvailability(cal *workdays.Calendar, managerID string, start, end time.Time, members []models.User, leaves []models.Leave, minStaffing int) *models.TeamAvailability {
+	avail := &models.TeamAvailability{
+		ManagerID:   managerID,
+		From:        start.Format(workdays.DateLayout),
+		To:          end.Format(workdays.DateLayout),
+		MinStaffing: minStaffing,
+		Members:     make([]models.TeamMember, 0, len(members)),
+		Days:        make([]models.AvailabilityDay, 0),
+	}
+	for _, m := range members {
+		avail.Members = append(avail.Members, models.TeamMember{ID: m.ID, Email: m.Email})
+	}
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(workdays.DateLayout)
+		d := models.AvailabilityDay{Date: date, Working: cal.IsWorkingDay(day), Out: make([]models.AvailabilityEntry, 0)}
+		if d.Working {
+			d.Available = float64(len(members))
+			for i := range leaves {
+				l := &leaves[i]
+				if sqlDate(l.StartDate) > date || sqlDate(l.EndDate) < date {
+					continue
+				}
+				d.Out = append(d.Out, models.AvailabilityEntry{UserID: l.UserID, Email: l.UserEmail, LeaveID: l.ID, Type: l.Type, Status: l.Status, Portion: l.Portion})
+				if slices.Contains(calendarStatuses, l.Status) {
+					d.Available -= dayWeight(l)
+				}
+			}
+			d.BelowMinimum = minStaffing > 0 && d.Available < float64(minStaffing)
+		}
+		avail.Days = append(avail.Days, d)
+	}
+	return avail
+}
+
+// projectApproval returns leaves with leave in them as if it were approved
+func projectApproval(leaves []models.Leave, leave *models.Leave) []models.Leave {
+	projected := *leave
+	projected.Status = string(constants.LeaveStatusApproved)
+	for i := range leaves {
+		if leaves[i].ID == leave.ID {
+			leaves[i] = projected
+			return leaves
+		}
+	}
+	return append(leaves, projected)
+}
+
+// staffingWarnings lists the days of avail below the team's minimum
+func staffingWarnings(avail *models.TeamAvailability) []models.StaffingWarning {
+	warnings := make([]models.StaffingWarning, 0)
+	for _, d := range avail.Days {
+		if d.BelowMinimum {
+			warnings = append(warnings, models.StaffingWarning{Date: d.Date, Available: d.Available, MinStaffing: avail.MinStaffing})
+		}
+	}
+	return warnings
+}
+
+// GetTeamAvailability returns who in managerID's team is out from one
+// YYYY-MM-DD date to another
+func (db *Database) GetTeamAvailability(managerID string, from, to string) (*models.TeamAvailability, error) {
+	start, end, err := availabilityRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var id string
+	if err := db.Conn.QueryRow("SELECT id FROM users WHERE id = ?", managerID).Scan(&id); err != nil {
+		return nil, err
+	}
+	return db.teamAvailability(db.Conn, managerID, start, end, nil)
+}
+
+// GetStaffingWarnings returns the days on which the leave, once approved,
+// leaves its owner's team below the team's minimum
+func (db *Database) GetStaffingWarnings(leave *models.Leave) ([]models.StaffingWarning, error) {
+	return db.staffingWarnings(db.Conn, leave, false)
+}
+
+// checkStaffing returns the warnings for approving leave in a transaction,
+// failing unless confirm. It locks the team's manager row so approvals in
+// one team are checked one at a time.
+func (db *Database) checkStaffing(tx *sql.Tx, leave *models.Leave, confirm bool) ([]models.StaffingWarning, error) {
+	warnings, err := db.staffingWarnings(tx, leave, true)
+	if err != nil {
+		return nil, err
+	}
+	return warnings, confirmStaffing(warnings, confirm)
+}
+
+func (db *Database) staffingWarnings(q querier, leave *models.Leave, lock bool) ([]models.StaffingWarning, error) {
+	var managerID *string
+	if err := q.QueryRow("SELECT manager_id FROM users WHERE id = ?", leave.UserID).Scan(&managerID); err != nil {
+		return nil, err
+	}
+	if managerID == nil {
+		return []models.StaffingWarning{}, nil
+	}
+	if lock {
+		if _, err := lockUser(q, *managerID); err != nil {
+			return nil, err
+		}
+	}
+	start, err := parseDate(leave.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseDate(leave.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	avail, err := db.teamAvailability(q, *managerID, start, end, leave)
+	if err != nil {
+		return nil, err
+	}
+	return staffingWarnings(avail), nil
+}
+
+// teamAvailability builds a team's availability, counting project as
+// approved when it is given
+func (db *Database) teamAvailability(q querier, managerID string, start, end time.Time, project *models.Leave) (*models.TeamAvailability, error) {
+	minStaffing, err := getMinStaffing(q, managerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT "+userColumns+" FROM users WHERE manager_id = ? ORDER BY email", managerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE u.manager_id = ? AND l.status IN (?, ?, ?) AND l.start_date <= ? AND l.end_date >= ?
+		ORDER BY l.start_date, l.id
+	`
+	args := []any{managerID, availabilityStatuses[0], availabilityStatuses[1], availabilityStatuses[2], end.Format(workdays.DateLayout), start.Format(workdays.DateLayout)}
+	leaves, err := db.queryLeaves(q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		leaves = projectApproval(leaves, project)
+	}
+	return buildAvailability(db.Calendar, managerID, start, end, members, leaves, minStaffing), nil
+}
+
+func getMinStaffing(q querier, managerID string) (int, error) {
+	var minStaffing int
+	err := q.QueryRow("SELECT min_staffing FROM team_staffing WHERE manager_id = ?", managerID).Scan(&minStaffing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return minStaffing, err
+}
+
+// SetMinStaffing sets how many of managerID's direct reports must be
+// working on any working day; 0 removes the minimum. The user must hold
+// the manager or admin role.
+func (db *Database) SetMinStaffing(actorID string, managerID string, minStaffing int) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	manager, err := lockUser(tx, managerID)
+	if err != nil {
+		return err
+	}
+	if !canManage(manager.Role) {
+		return ErrInvalidManager
+	}
+	before, err := getMinStaffing(tx, managerID)
+	if err != nil {
+		return err
+	}
+
+	if minStaffing == 0 {
+		_, err = tx.Exec("DELETE FROM team_staffing WHERE manager_id = ?", managerID)
+	} else {
+		query := "INSERT INTO team_staffing (manager_id, min_staffing) VALUES (?, ?) ON DUPLICATE KEY UPDATE min_staffing = VALUES(min_staffing)"
+		_, err = tx.Exec(query, managerID, minStaffing)
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditUserStaffing, managerID, staffingAudit{before}, staffingAudit{minStaffing}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,372 @@
+// internal/db/balance.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidDateRange = errors.New("invalid leave date range")
+	ErrUnknownLeaveType = errors.New("unknown leave type")
+	ErrNoWorkingDays    = errors.New("leave does not cover any working days")
+	ErrInvalidPortion   = errors.New("invalid leave portion")
+	ErrLeaveSpansYears  = errors.New("leave must start and end in the same year; request each year's days separately")
+)
+
+// InsufficientBalanceError is returned when a leave needs more days than
+// the user has left for its type.
+type InsufficientBalanceError struct {
+	Type      string
+	Requested float64
+	Remaining float64
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("insufficient %s leave balance: requested %.1f day(s), %.1f remaining", e.Type, e.Requested, e.Remaining)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// parseDate accepts plain dates from requests as well as the RFC 3339
+// values the driver returns for DATE columns when parseTime is enabled.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(workdays.DateLayout, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// sqlDate normalises a leave date to YYYY-MM-DD for use as a query argument
+func sqlDate(s string) string {
+	if t, err := parseDate(s); err == nil {
+		return t.Format(workdays.DateLayout)
+	}
+	return s
+}
+
+// LeaveDays returns the number of working days between startDate and
+// endDate, counting both ends and skipping weekends and holidays.
+func (db *Database) LeaveDays(startDate, endDate string) (float64, error) {
+	return leaveDays(db.Calendar, startDate, endDate)
+}
+
+// LeaveDuration returns how many days a leave costs. Full-day leave costs
+// its working days; half-day and hourly leave must fall on a single day
+// and cost a fraction of it.
+func (db *Database) LeaveDuration(leave *models.Leave) (float64, error) {
+	return leaveDuration(db.Calendar, leave)
+}
+
+func leaveDays(cal *workdays.Calendar, startDate, endDate string) (float64, error) {
+	start, err := parseDate(startDate)
+	if err != nil {
+		return 0, ErrInvalidDateRange
+	}
+	end, err := parseDate(endDate)
+	if err != nil {
+		return 0, ErrInvalidDateRange
+	}
+	if end.Before(start) {
+		return 0, ErrInvalidDateRange
+	}
+	return float64(cal.WorkingDays(start, end)), nil
+}
+
+func leaveDuration(cal *workdays.Calendar, leave *models.Leave) (float64, error) {
+	days, err := leaveDays(cal, leave.StartDate, leave.EndDate)
+	if err != nil {
+		return 0, err
+	}
+
+	portion := constants.LeavePortion(leave.Portion)
+	if portion == "" || portion == constants.LeavePortionFull {
+		return days, nil
+	}
+
+	start, _ := parseDate(leave.StartDate)
+	end, _ := parseDate(leave.EndDate)
+	if !start.Equal(end) {
+		return 0, ErrInvalidPortion
+	}
+	if days == 0 {
+		return 0, nil
+	}
+
+	switch portion {
+	case constants.LeavePortionFirstHalf, constants.LeavePortionSecondHalf:
+		return 0.5, nil
+	case constants.LeavePortionHourly:
+		hours, err := leaveHours(leave.StartTime, leave.EndTime)
+		if err != nil {
+			return 0, err
+		}
+		return hours / constants.WorkingHoursPerDay, nil
+	}
+	return 0, ErrInvalidPortion
+}
+
+// leaveHours returns the length of an hourly leave
+func leaveHours(startTime, endTime *string) (float64, error) {
+	if startTime == nil || endTime == nil {
+		return 0, ErrInvalidPortion
+	}
+	start, err := parseClock(*startTime)
+	if err != nil {
+		return 0, ErrInvalidPortion
+	}
+	end, err := parseClock(*endTime)
+	if err != nil {
+		return 0, ErrInvalidPortion
+	}
+	hours := end.Sub(start).Hours()
+	if hours <= 0 || hours > constants.WorkingHoursPerDay {
+		return 0, ErrInvalidPortion
+	}
+	return hours, nil
+}
+
+// parseClock accepts HH:MM as well as the HH:MM:SS the driver returns for TIME columns
+func parseClock(s string) (time.Time, error) {
+	if t, err := time.Parse("15:04", s); err == nil {
+		return t, nil
+	}
+	return time.Parse("15:04:05", s)
+}
+
+// trimSeconds shortens a TIME column value to HH:MM
+func trimSeconds(t *string) *string {
+	if t == nil || len(*t) <= 5 {
+		return t
+	}
+	short := (*t)[:5]
+	return &short
+}
+
+// leaveYear is the balance year a leave is charged to.
+func leaveYear(startDate string) (int, error) {
+	start, err := parseDate(startDate)
+	if err != nil {
+		return 0, ErrInvalidDateRange
+	}
+	return start.Year(), nil
+}
+
+// chargeYear is the balance year a new leave is charged to. Balances are
+// kept per year and a leave is charged to one of them, so leave that spans
+// New Year is refused rather than split.
+func chargeYear(leave *models.Leave) (int, error) {
+	year, err := leaveYear(leave.StartDate)
+	if err != nil {
+		return 0, err
+	}
+	end, err := parseDate(leave.EndDate)
+	if err != nil {
+		return 0, ErrInvalidDateRange
+	}
+	if end.Year() != year {
+		return 0, ErrLeaveSpansYears
+	}
+	return year, nil
+}
+
+// lockAllowance reads a user's allowance for leaveType in year and locks
+// the user row so concurrent balance checks for the same user are
+// serialised.
+func lockAllowance(q querier, userID string, leaveType *models.LeaveType, year int) (float64, error) {
+	user, err := lockUser(q, userID)
+	if err != nil {
+		return 0, err
+	}
+	effective, err := effectiveAllowance(q, user)
+	if err != nil {
+		return 0, err
+	}
+
+	policies, err := getAccrualPolicies(q)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := policies[leaveType.Code]; !ok {
+		return float64(leaveType.AllowanceFrom(effective.Allowances)), nil
+	}
+	credited, _, err := accruedDays(q, userID, leaveType.Code, year)
+	return credited, err
+}
+
+// usedDays sums the ledger entries for a user, type and year.
+func usedDays(q querier, userID, leaveType string, year int) (float64, error) {
+	var used float64
+	query := "SELECT COALESCE(SUM(days), 0) FROM leave_balance_ledger WHERE user_id = ? AND leave_type = ? AND year = ?"
+	err := q.QueryRow(query, userID, leaveType, year).Scan(&used)
+	return used, err
+}
+
+// pendingDays sums the days of a user's pending leaves of a type starting
+// in year, ignoring excludeLeaveID.
+func (db *Database) pendingDays(q querier, userID, leaveType string, year int, excludeLeaveID string) (float64, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.user_id = ? AND l.type = ? AND l.status = ? AND YEAR(l.start_date) = ? AND l.id <> ?
+	`
+	leaves, err := db.queryLeaves(q, query, userID, leaveType, string(constants.LeaveStatusPending), year, excludeLeaveID)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, leave := range leaves {
+		total += leave.WorkingDays
+	}
+	return total, nil
+}
+
+// chargedDays is the net number of days the ledger holds against a leave.
+func chargedDays(q querier, leaveID string) (float64, error) {
+	var days float64
+	err := q.QueryRow("SELECT COALESCE(SUM(days), 0) FROM leave_balance_ledger WHERE leave_id = ?", leaveID).Scan(&days)
+	return days, err
+}
+
+func insertLedgerEntry(q querier, userID, leaveID, leaveType string, year int, days float64) error {
+	query := "INSERT INTO leave_balance_ledger (id, user_id, leave_id, leave_type, year, days) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := q.Exec(query, uuid.New().String(), userID, leaveID, leaveType, year, days)
+	return err
+}
+
+// deductLeave charges an approved leave against the user's balance. The
+// user row is locked even for types without a balance.
+func (db *Database) deductLeave(q querier, leave *models.Leave) error {
+	days, err := db.LeaveDuration(leave)
+	if err != nil {
+		return err
+	}
+	year, err := leaveYear(leave.StartDate)
+	if err != nil {
+		return err
+	}
+
+	leaveType, err := getLeaveType(q, leave.Type)
+	if err != nil {
+		return err
+	}
+	if !leaveType.CountsAgainstBalance {
+		_, err := lockUser(q, leave.UserID)
+		return err
+	}
+	if isCompOff(leave) {
+		return db.deductCompOff(q, leave, days, year)
+	}
+	allowance, err := lockAllowance(q, leave.UserID, leaveType, year)
+	if err != nil {
+		return err
+	}
+	used, err := usedDays(q, leave.UserID, leave.Type, year)
+	if err != nil {
+		return err
+	}
+	if remaining := allowance - used; days > remaining {
+		return &InsufficientBalanceError{Type: leave.Type, Requested: days, Remaining: remaining}
+	}
+
+	return insertLedgerEntry(q, leave.UserID, leave.ID, leave.Type, year, days)
+}
+
+// restoreLeave reverses whatever the ledger currently charges for a leave.
+func restoreLeave(q querier, leave *models.Leave) error {
+	charged, err := chargedDays(q, leave.ID)
+	if err != nil {
+		return err
+	}
+	if charged == 0 {
+		return nil
+	}
+	year, err := leaveYear(leave.StartDate)
+	if err != nil {
+		return err
+	}
+	// Comp-off days go back to the credits they were drawn from
+	if _, err := q.Exec("DELETE FROM comp_off_usage WHERE leave_id = ?", leave.ID); err != nil {
+		return err
+	}
+	return insertLedgerEntry(q, leave.UserID, leave.ID, leave.Type, year, -charged)
+}
+
+// GetLeaveBalances returns the allowance, used, pending and remaining days
+// per leave type for a user in the given year. Types that don't count
+// against a balance are left out. Comp-off credits don't belong to a
+// year, so its balance is the credits valid today.
+func (db *Database) GetLeaveBalances(userID string, year int) (map[string]models.LeaveBalance, error) {
+	effective, err := db.GetEffectivePolicy(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := getAccrualPolicies(db.Conn)
+	if err != nil {
+		return nil, err
+	}
+	leaveTypes, err := getLeaveTypes(db.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]models.LeaveBalance)
+	for _, leaveType := range leaveTypes {
+		if !leaveType.CountsAgainstBalance {
+			continue
+		}
+		code := leaveType.Code
+		if code == string(constants.LeaveTypeCompOff) {
+			if balances[code], err = db.compOffBalance(db.Conn, userID, today()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		allowance, carried := float64(leaveType.AllowanceFrom(effective.Allowances)), 0.0
+		if _, ok := policies[code]; ok {
+			if allowance, carried, err = accruedDays(db.Conn, userID, code, year); err != nil {
+				return nil, err
+			}
+		}
+		used, err := usedDays(db.Conn, userID, code, year)
+		if err != nil {
+			return nil, err
+		}
+		pending, err := db.pendingDays(db.Conn, userID, code, year, "")
+		if err != nil {
+			return nil, err
+		}
+		balances[code] = models.LeaveBalance{
+			Allowance:      allowance,
+			CarriedForward: carried,
+			Used:           used,
+			Pending:        pending,
+			Remaining:      allowance - used - pending,
+		}
+	}
+	return balances, nil
+}
+
+// withWorkingDays fills in WorkingDays on leaves read from the database
+func (db *Database) withWorkingDays(leaves ...*models.Leave) {
+	for _, leave := range leaves {
+		if days, err := db.LeaveDuration(leave); err == nil {
+			leave.WorkingDays = days
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// internal/db/calendar.go
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+)
+
+var (
+	ErrUnknownCalendarScope   = errors.New("unknown calendar scope")
+	ErrCalendarScopeForbidden = errors.New("the company calendar is only available to admins")
+)
+
+// checkCalendarScope reports whether owner may subscribe to scope. The
+// company feed names everyone on leave, so only admins get it; the owner's
+// current role is checked on every fetch.
+func checkCalendarScope(owner *models.User, scope string) error {
+	switch constants.CalendarScope(scope) {
+	case constants.CalendarScopeMe, constants.CalendarScopeTeam:
+		return nil
+	case constants.CalendarScopeCompany:
+		if owner.Role != string(constants.RoleAdmin) {
+			return ErrCalendarScopeForbidden
+		}
+		return nil
+	}
+	return ErrUnknownCalendarScope
+}
+
+// calendarTokenAudit is what the audit trail records about a feed token;
+// the token itself is never logged
+type calendarTokenAudit struct {
+	Active bool `json:"active"`
+}
+
+// newCalendarToken returns a random feed token and the hash to store
+func newCalendarToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashCalendarToken(token), nil
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// calendarStatuses are the statuses shown in calendar feeds: the leave is
+// taken unless a requested cancellation goes through
+var calendarStatuses = []string{string(constants.LeaveStatusApproved), string(constants.LeaveStatusCancellationRequested)}
+
+// RotateCalendarToken issues a new feed token for userID, invalidating
+// any previous one. Only its hash is stored, so it is returned once.
+func (db *Database) RotateCalendarToken(actorID string, userID string) (string, error) {
+	token, hash, err := newCalendarToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockUser(tx, userID); err != nil {
+		return "", err
+	}
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM calendar_tokens WHERE user_id = ?", userID).Scan(&existing); err != nil {
+		return "", err
+	}
+
+	query := "INSERT INTO calendar_tokens (user_id, token_hash) VALUES (?, ?) ON DUPLICATE KEY UPDATE token_hash = VALUES(token_hash), created_at = CURRENT_TIMESTAMP"
+	if _, err := tx.Exec(query, userID, hash); err != nil {
+		return "", err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditUserCalendar, userID, calendarTokenAudit{Active: existing > 0}, calendarTokenAudit{Active: true}); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// RevokeCalendarToken stops userID's feeds from working. Revoking when
+// there is no token is a no-op.
+func (db *Database) RevokeCalendarToken(actorID string, userID string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM calendar_tokens WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditUserCalendar, userID, calendarTokenAudit{Active: true}, calendarTokenAudit{Active: false}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetUserByCalendarToken returns the owner of a feed token, or
+// sql.ErrNoRows if the token is unknown or revoked
+func (db *Database) GetUserByCalendarToken(token string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = (SELECT user_id FROM calendar_tokens WHERE token_hash = ?)"
+	return scanUser(db.Conn.QueryRow(query, hashCalendarToken(token)))
+}
+
+// GetCalendarLeaves returns the approved leave visible in owner's feed for
+// scope, ending on or after since, with dates as YYYY-MM-DD
+func (db *Database) GetCalendarLeaves(owner *models.User, scope string, since string) ([]models.Leave, error) {
+	if err := checkCalendarScope(owner, scope); err != nil {
+		return nil, err
+	}
+
+	var where string
+	args := []any{calendarStatuses[0], calendarStatuses[1], since}
+	switch constants.CalendarScope(scope) {
+	case constants.CalendarScopeMe:
+		where = "l.user_id = ?"
+		args = append(args, owner.ID)
+	case constants.CalendarScopeTeam:
+		where = "(l.user_id = ? OR u.manager_id = ? OR u.manager_id = ?)" // NULL never matches
+		args = append(args, owner.ID, owner.ID, owner.ManagerID)
+	case constants.CalendarScopeCompany:
+		where = "TRUE"
+	default:
+		return nil, ErrUnknownCalendarScope
+	}
+
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.status IN (?, ?) AND l.end_date >= ? AND ` + where + `
+		ORDER BY l.start_date, l.id
+	`
+	leaves, err := db.queryLeaves(db.Conn, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range leaves {
+		leaves[i].StartDate = sqlDate(leaves[i].StartDate)
+		leaves[i].EndDate = sqlDate(leaves[i].EndDate)
+	}
+	return leaves, nil
+}
+
+// inCalendarScope mirrors the scope conditions of GetCalendarLeaves
+func inCalendarScope(owner, user *models.User, scope constants.CalendarScope) bool {
+	switch scope {
+	case constants.CalendarScopeMe:
+		return user.ID == owner.ID
+	case constants.CalendarScopeTeam:
+		return user.ID == owner.ID ||
+			(user.ManagerID != nil && *user.ManagerID == owner.ID) ||
+			(user.ManagerID != nil && owner.ManagerID != nil && *user.ManagerID == *owner.ManagerID)
+	}
+	return scope == constants.CalendarScopeCompany
+}
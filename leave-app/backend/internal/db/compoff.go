@@ -0,0 +1,363 @@
+// internal/db/compoff.go
+package db
+
+import (
+	"errors"
+	"leave-app/internal/approval"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCompOffWorkingDay = errors.New("comp-off is only earned on a weekend or holiday")
+	ErrCompOffNotWorked  = errors.New("comp-off can only be claimed for a day already worked")
+	ErrDuplicateCompOff  = errors.New("comp-off has already been claimed for that day")
+	ErrCompOffNotPending = errors.New("comp-off credit is not awaiting approval")
+)
+
+// compOffUsage is the part of a credit one compoff leave was drawn from
+type compOffUsage struct {
+	CreditID string
+	LeaveID  string
+	Days     float64
+}
+
+// isCompOff reports whether leave is spent from comp-off credits
+func isCompOff(leave *models.Leave) bool {
+	return leave.Type == string(constants.LeaveTypeCompOff)
+}
+
+// checkCompOffDate makes sure a claim is for a YYYY-MM-DD date already
+// worked that is not a working day
+func checkCompOffDate(cal *workdays.Calendar, workDate string) error {
+	day, err := time.Parse(workdays.DateLayout, workDate)
+	if err != nil {
+		return ErrInvalidDateRange
+	}
+	if day.After(time.Now()) {
+		return ErrCompOffNotWorked
+	}
+	if cal.IsWorkingDay(day) {
+		return ErrCompOffWorkingDay
+	}
+	return nil
+}
+
+// compOffExpiry is the last day a credit for workDate can be spent
+func compOffExpiry(workDate string) string {
+	day, _ := parseDate(workDate)
+	return day.AddDate(0, 0, constants.CompOffExpiryDays).Format(workdays.DateLayout)
+}
+
+// creditDecider returns who actor decides credit as, treating the claim
+// like the manager step of a leave
+func creditDecider(actor *models.User, delegators []models.User, credit *models.CompOffCredit) (*models.User, bool) {
+	claim := &models.Leave{UserID: credit.UserID, ApproverID: credit.ApproverID}
+	return approval.Decider(actor, delegators, claim, string(constants.RoleManager))
+}
+
+// drawCompOff takes days from credits in order, returning how much of each
+// the leave uses
+func drawCompOff(leave *models.Leave, credits []models.CompOffCredit, days float64) ([]compOffUsage, error) {
+	var usage []compOffUsage
+	left := days
+	for _, c := range credits {
+		if left <= 0 {
+			break
+		}
+		take := min(c.Remaining, left)
+		if take <= 0 {
+			continue
+		}
+		usage = append(usage, compOffUsage{CreditID: c.ID, LeaveID: leave.ID, Days: take})
+		left -= take
+	}
+	if left > 0 {
+		return nil, &InsufficientBalanceError{Type: leave.Type, Requested: days, Remaining: days - left}
+	}
+	return usage, nil
+}
+
+// compOffColumns is the column list read by scanCompOff; queries must
+// alias comp_off_credits as c and join users as u
+const compOffColumns = `c.id, c.user_id, u.email, c.work_date, c.days, c.reason, c.status, c.approver_id, c.decided_by, c.decided_at, c.comment, c.expires_on,
+	c.days - COALESCE((SELECT SUM(cu.days) FROM comp_off_usage cu WHERE cu.credit_id = c.id), 0), c.created_at`
+
+func scanCompOff(row rowScanner) (*models.CompOffCredit, error) {
+	c := &models.CompOffCredit{}
+	err := row.Scan(&c.ID, &c.UserID, &c.UserEmail, &c.WorkDate, &c.Days, &c.Reason, &c.Status, &c.ApproverID, &c.DecidedBy, &c.DecidedAt, &c.Comment, &c.ExpiresOn, &c.Remaining, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.WorkDate = sqlDate(c.WorkDate)
+	if c.ExpiresOn != nil {
+		expires := sqlDate(*c.ExpiresOn)
+		c.ExpiresOn = &expires
+	}
+	return c, nil
+}
+
+func queryCompOff(q querier, query string, args ...any) ([]models.CompOffCredit, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := make([]models.CompOffCredit, 0)
+	for rows.Next() {
+		c, err := scanCompOff(rows)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, *c)
+	}
+	return credits, rows.Err()
+}
+
+// spendableCredits returns userID's approved credits still valid on date
+// with days left, oldest first
+func spendableCredits(q querier, userID string, date string) ([]models.CompOffCredit, error) {
+	query := `
+		SELECT ` + compOffColumns + `
+		FROM comp_off_credits c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.user_id = ? AND c.status = ? AND c.expires_on >= ?
+		ORDER BY c.work_date, c.id
+	`
+	credits, err := queryCompOff(q, query, userID, string(constants.LeaveStatusApproved), date)
+	if err != nil {
+		return nil, err
+	}
+	spendable := credits[:0]
+	for _, c := range credits {
+		if c.Remaining > 0 {
+			spendable = append(spendable, c)
+		}
+	}
+	return spendable, nil
+}
+
+// pendingCompOffDays sums userID's pending compoff leaves, ignoring
+// excludeLeaveID. Credits do not belong to a year, so neither does this.
+func (db *Database) pendingCompOffDays(q querier, userID string, excludeLeaveID string) (float64, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.user_id = ? AND l.type = ? AND l.status = ? AND l.id <> ?
+	`
+	leaves, err := db.queryLeaves(q, query, userID, string(constants.LeaveTypeCompOff), string(constants.LeaveStatusPending), excludeLeaveID)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, leave := range leaves {
+		total += leave.WorkingDays
+	}
+	return total, nil
+}
+
+// checkCompOffBalance makes sure the credits valid on the leave's last day
+// cover it alongside the user's other pending compoff leaves
+func (db *Database) checkCompOffBalance(q querier, leave *models.Leave, days float64) error {
+	if _, err := lockUser(q, leave.UserID); err != nil {
+		return err
+	}
+	credits, err := spendableCredits(q, leave.UserID, sqlDate(leave.EndDate))
+	if err != nil {
+		return err
+	}
+	pending, err := db.pendingCompOffDays(q, leave.UserID, leave.ID)
+	if err != nil {
+		return err
+	}
+	var available float64
+	for _, c := range credits {
+		available += c.Remaining
+	}
+	if remaining := available - pending; days > remaining {
+		return &InsufficientBalanceError{Type: leave.Type, Requested: days, Remaining: remaining}
+	}
+	return nil
+}
+
+// deductCompOff charges an approved compoff leave to the user's credits,
+// oldest first, as well as the ledger
+func (db *Database) deductCompOff(q querier, leave *models.Leave, days float64, year int) error {
+	if _, err := lockUser(q, leave.UserID); err != nil {
+		return err
+	}
+	credits, err := spendableCredits(q, leave.UserID, sqlDate(leave.EndDate))
+	if err != nil {
+		return err
+	}
+	usage, err := drawCompOff(leave, credits, days)
+	if err != nil {
+		return err
+	}
+	for _, u := range usage {
+		if _, err := q.Exec("INSERT INTO comp_off_usage (credit_id, leave_id, days) VALUES (?, ?, ?)", u.CreditID, u.LeaveID, u.Days); err != nil {
+			return err
+		}
+	}
+	return insertLedgerEntry(q, leave.UserID, leave.ID, leave.Type, year, days)
+}
+
+// compOffBalance is userID's comp-off balance on date: the credits still
+// valid, what has been spent from them and what pending leave would take
+func (db *Database) compOffBalance(q querier, userID string, date string) (models.LeaveBalance, error) {
+	var balance models.LeaveBalance
+	query := `
+		SELECT COALESCE(SUM(c.days), 0), COALESCE(SUM(cu.used), 0)
+		FROM comp_off_credits c
+		LEFT JOIN (SELECT credit_id, SUM(days) AS used FROM comp_off_usage GROUP BY credit_id) cu ON cu.credit_id = c.id
+		WHERE c.user_id = ? AND c.status = ? AND c.expires_on >= ?
+	`
+	if err := q.QueryRow(query, userID, string(constants.LeaveStatusApproved), date).Scan(&balance.Allowance, &balance.Used); err != nil {
+		return balance, err
+	}
+	pending, err := db.pendingCompOffDays(q, userID, "")
+	if err != nil {
+		return balance, err
+	}
+	balance.Pending = pending
+	balance.Remaining = balance.Allowance - balance.Used - balance.Pending
+	return balance, nil
+}
+
+// CreateCompOffCredit records a claim for a day worked and routes it to
+// the user's manager
+func (db *Database) CreateCompOffCredit(actorID string, credit *models.CompOffCredit) error {
+	if err := checkCompOffDate(db.Calendar, credit.WorkDate); err != nil {
+		return err
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	user, err := lockUser(tx, credit.UserID)
+	if err != nil {
+		return err
+	}
+	var claimed int
+	query := "SELECT COUNT(*) FROM comp_off_credits WHERE user_id = ? AND work_date = ? AND status <> ?"
+	if err := tx.QueryRow(query, credit.UserID, credit.WorkDate, string(constants.LeaveStatusRejected)).Scan(&claimed); err != nil {
+		return err
+	}
+	if claimed > 0 {
+		return ErrDuplicateCompOff
+	}
+
+	credit.ID = uuid.New().String()
+	credit.UserEmail = user.Email
+	credit.Status = string(constants.LeaveStatusPending)
+	credit.ApproverID = user.ManagerID
+	credit.Remaining = credit.Days
+	credit.CreatedAt = time.Now()
+	query = "INSERT INTO comp_off_credits (id, user_id, work_date, days, reason, status, approver_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, credit.ID, credit.UserID, credit.WorkDate, credit.Days, credit.Reason, credit.Status, credit.ApproverID, credit.CreatedAt); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditCompOffRequest, credit.ID, nil, credit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetCompOffCredit returns one credit
+func (db *Database) GetCompOffCredit(creditID string) (*models.CompOffCredit, error) {
+	query := "SELECT " + compOffColumns + " FROM comp_off_credits c JOIN users u ON c.user_id = u.id WHERE c.id = ?"
+	return scanCompOff(db.Conn.QueryRow(query, creditID))
+}
+
+// GetCompOffCredits returns userID's credits, latest day worked first
+func (db *Database) GetCompOffCredits(userID string) ([]models.CompOffCredit, error) {
+	query := `
+		SELECT ` + compOffColumns + `
+		FROM comp_off_credits c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.user_id = ?
+		ORDER BY c.work_date DESC, c.id
+	`
+	return queryCompOff(db.Conn, query, userID)
+}
+
+// GetPendingCompOffCredits returns the claims user may decide, including
+// those of the delegators whose authority they hold today
+func (db *Database) GetPendingCompOffCredits(user *models.User) ([]models.CompOffCredit, error) {
+	delegators, err := activeDelegators(db.Conn, user.ID, today())
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + compOffColumns + `
+		FROM comp_off_credits c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.status = ?
+		ORDER BY c.created_at
+	`
+	credits, err := queryCompOff(db.Conn, query, string(constants.LeaveStatusPending))
+	if err != nil {
+		return nil, err
+	}
+	actionable := make([]models.CompOffCredit, 0)
+	for i := range credits {
+		if _, ok := creditDecider(user, delegators, &credits[i]); ok {
+			actionable = append(actionable, credits[i])
+		}
+	}
+	return actionable, nil
+}
+
+// DecideCompOffCredit approves or rejects a pending claim. Approval issues
+// the credit, which expires constants.CompOffExpiryDays after the day
+// worked.
+func (db *Database) DecideCompOffCredit(creditID string, actor *models.User, approve bool, comment *string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "SELECT " + compOffColumns + " FROM comp_off_credits c JOIN users u ON c.user_id = u.id WHERE c.id = ? FOR UPDATE"
+	before, err := scanCompOff(tx.QueryRow(query, creditID))
+	if err != nil {
+		return err
+	}
+	if before.Status != string(constants.LeaveStatusPending) {
+		return ErrCompOffNotPending
+	}
+	delegators, err := activeDelegators(tx, actor.ID, today())
+	if err != nil {
+		return err
+	}
+	if _, ok := creditDecider(actor, delegators, before); !ok {
+		return ErrNotApprover
+	}
+
+	after := *before
+	after.Status = string(constants.LeaveStatusRejected)
+	if approve {
+		after.Status = string(constants.LeaveStatusApproved)
+		expires := compOffExpiry(before.WorkDate)
+		after.ExpiresOn = &expires
+	}
+	now := time.Now()
+	after.DecidedBy, after.DecidedAt, after.Comment = &actor.ID, &now, comment
+	query = "UPDATE comp_off_credits SET status = ?, decided_by = ?, decided_at = ?, comment = ?, expires_on = ? WHERE id = ?"
+	if _, err := tx.Exec(query, after.Status, actor.ID, now, comment, after.ExpiresOn, creditID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actor.ID, constants.AuditCompOffDecide, creditID, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
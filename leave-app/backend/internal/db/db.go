@@ -4,9 +4,12 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"io/ioutil"
+	"leave-app/internal/approval"
 	"leave-app/internal/constants"
+	"leave-app/internal/migrate"
 	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"leave-app/migrations"
 	"log"
 	"os"
 	"sync"
@@ -17,8 +20,11 @@ import (
 )
 
 type Database struct {
-	Conn *sql.DB
-	mu   sync.Mutex
+	Conn     *sql.DB
+	Calendar *workdays.Calendar
+	mu       sync.Mutex
+
+	analytics analyticsCache
 }
 
 // NewDatabase creates a new database connection
@@ -29,7 +35,7 @@ func NewDatabase() (*Database, error) {
 	dbPort := os.Getenv("DB_PORT")
 	dbName := os.Getenv("DB_NAME")
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true", dbUser, dbPassword, dbHost, dbPort, dbName)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", dbUser, dbPassword, dbHost, dbPort, dbName)
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -45,7 +51,7 @@ func NewDatabase() (*Database, error) {
 		return nil, err
 	}
 
-	d := &Database{Conn: db}
+	d := &Database{Conn: db, Calendar: workdays.New(workdays.DefaultWeekend)}
 
 	log.Println("Database connection established")
 
@@ -98,101 +104,233 @@ func NewDatabase() (*Database, error) {
 	return d, nil
 }
 
-// Migrate runs the database migrations
+// Migrate applies any pending embedded migrations
 func (db *Database) Migrate() error {
-	query, err := ioutil.ReadFile("migrations/001_initial.sql")
+	runner, err := migrate.New(db.Conn, migrations.FS)
 	if err != nil {
-		return fmt.Errorf("could not read migration file: %w", err)
+		return fmt.Errorf("could not load migrations: %w", err)
 	}
 
-	if _, err := db.Conn.Exec(string(query)); err != nil {
-		return fmt.Errorf("could not apply migration: %w", err)
+	applied, err := runner.Up()
+	if err != nil {
+		return fmt.Errorf("could not apply migrations: %w", err)
 	}
 
-	log.Println("Database migration applied successfully")
+	log.Printf("Database migrations up to date (%d applied)", len(applied))
 	return nil
 }
 
-func (db *Database) GetUserByEmail(email string) (*models.User, error) {
+// userColumns is the column list read by scanUser
+const userColumns = "id, email, role, manager_id, sick_allowance, annual_allowance, casual_allowance, created_at"
+
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	query := "SELECT id, email, role, sick_allowance, annual_allowance, casual_allowance, created_at FROM users WHERE email = ?"
-	err := db.Conn.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Role, &user.Allowances.Sick, &user.Allowances.Annual, &user.Allowances.Casual, &user.CreatedAt)
+	err := row.Scan(&user.ID, &user.Email, &user.Role, &user.ManagerID, &user.Allowances.Sick, &user.Allowances.Annual, &user.Allowances.Casual, &user.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (db *Database) UpdateUserRole(userID string, role string) error {
-	query := "UPDATE users SET role = ? WHERE id = ?"
-	_, err := db.Conn.Exec(query, role, userID)
-	return err
+func (db *Database) GetUserByEmail(email string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = ?"
+	return scanUser(db.Conn.QueryRow(query, email))
 }
 
-func (db *Database) GetAllUsers() ([]models.User, error) {
-	rows, err := db.Conn.Query("SELECT id, email, role, sick_allowance, annual_allowance, casual_allowance, created_at FROM users")
+// lockUser reads a user and locks their row for the rest of the transaction
+func lockUser(q querier, userID string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ? FOR UPDATE"
+	return scanUser(q.QueryRow(query, userID))
+}
+
+func (db *Database) UpdateUserRole(actorID string, userID string, role string) error {
+	tx, err := db.Conn.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
+
+	before, err := lockUser(tx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE users SET role = ? WHERE id = ?", role, userID); err != nil {
+		return err
+	}
+	after := *before
+	after.Role = role
+	if err := recordAudit(tx, actorID, constants.AuditUserRole, userID, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateAllUserAllowances sets every user's allowances, auditing each user
+// whose allowances actually change
+func (db *Database) UpdateAllUserAllowances(actorID string, req models.UpdateAllowancesRequest) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	users := make([]models.User, 0)
+	rows, err := tx.Query("SELECT " + userColumns + " FROM users FOR UPDATE")
+	if err != nil {
+		return err
+	}
+	var users []models.User
 	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &user.Allowances.Sick, &user.Allowances.Annual, &user.Allowances.Casual, &user.CreatedAt); err != nil {
-			return nil, err
+		user, err := scanUser(rows)
+		if err != nil {
+			rows.Close()
+			return err
 		}
-		users = append(users, user)
+		users = append(users, *user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
-	return users, nil
-}
 
-func (db *Database) UpdateAllUserAllowances(req models.UpdateAllowancesRequest) error {
 	query := "UPDATE users SET sick_allowance = ?, annual_allowance = ?, casual_allowance = ?"
-	_, err := db.Conn.Exec(query, req.Sick, req.Annual, req.Casual)
-	return err
+	if _, err := tx.Exec(query, req.Sick, req.Annual, req.Casual); err != nil {
+		return err
+	}
+
+	allowances := models.Allowance{Sick: req.Sick, Annual: req.Annual, Casual: req.Casual}
+	for _, before := range users {
+		if before.Allowances == allowances {
+			continue
+		}
+		after := before
+		after.Allowances = allowances
+		if err := recordAudit(tx, actorID, constants.AuditUserAllowances, before.ID, before, after); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func (db *Database) CreateLeave(leave *models.Leave) error {
+// CreateLeave inserts a pending leave after checking it against its type
+// and, for types with a balance, that the user has enough left to cover
+// it alongside their other pending leaves.
+func (db *Database) CreateLeave(actorID string, leave *models.Leave) error {
+	days, err := db.LeaveDuration(leave)
+	if err != nil {
+		return err
+	}
+	if days == 0 {
+		return ErrNoWorkingDays
+	}
+	leave.WorkingDays = days
+	year, err := chargeYear(leave)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leaveType, err := getLeaveType(tx, leave.Type)
+	if err != nil {
+		return err
+	}
+	if err := checkConsecutiveDays(leaveType, leave); err != nil {
+		return err
+	}
+	if leaveType.CountsAgainstBalance {
+		if err := db.checkBalance(tx, leave, leaveType, year, days); err != nil {
+			return err
+		}
+	} else if _, err := lockUser(tx, leave.UserID); err != nil {
+		return err
+	}
+	if err := db.checkOverlap(tx, leave, constants.LeaveStatusPending, constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested); err != nil {
+		return err
+	}
+
+	// Route the leave to the requester's manager
+	if err := tx.QueryRow("SELECT manager_id FROM users WHERE id = ?", leave.UserID).Scan(&leave.ApproverID); err != nil {
+		return err
+	}
+
+	policies, err := getApprovalPolicies(tx)
+	if err != nil {
+		return err
+	}
+	steps := approval.Resolve(policies, leave.Type, days)
+	leave.CurrentStep = &steps[0]
+	restartWait(leave, time.Now())
+
 	leave.ID = uuid.New().String()
-	query := "INSERT INTO leaves (id, user_id, type, start_date, end_date, reason, status) VALUES (?, ?, ?, ?, ?, ?, ?)"
-	_, err := db.Conn.Exec(query, leave.ID, leave.UserID, leave.Type, leave.StartDate, leave.EndDate, leave.Reason, leave.Status)
-	return err
+	query := "INSERT INTO leaves (id, user_id, type, start_date, end_date, portion, start_time, end_time, reason, status, approver_id, current_step, awaiting_since) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, leave.ID, leave.UserID, leave.Type, leave.StartDate, leave.EndDate, leave.Portion, leave.StartTime, leave.EndTime, leave.Reason, leave.Status, leave.ApproverID, leave.CurrentStep, leave.AwaitingSince); err != nil {
+		return err
+	}
+	for i, role := range steps {
+		if _, err := tx.Exec("INSERT INTO leave_approvals (leave_id, step, role) VALUES (?, ?, ?)", leave.ID, i, role); err != nil {
+			return err
+		}
+	}
+	if err := recordAudit(tx, actorID, constants.AuditLeaveCreate, leave.ID, nil, leave); err != nil {
+		return err
+	}
+	if err := enqueueWebhooks(tx, constants.WebhookLeaveCreated, leave); err != nil {
+		return err
+	}
+	return db.commitLeaves(tx)
 }
 
-func (db *Database) GetAllLeaves() ([]models.Leave, error) {
-	query := `
-		SELECT l.id, l.user_id, u.email, l.type, l.start_date, l.end_date, l.reason, l.status, l.approver_comment, l.created_at
-		FROM leaves l
-		JOIN users u ON l.user_id = u.id
-		ORDER BY l.created_at DESC
-	`
-	rows, err := db.Conn.Query(query)
+// checkBalance makes sure days of leaveType fit in what the user has left
+// in year after their other pending leaves
+func (db *Database) checkBalance(q querier, leave *models.Leave, leaveType *models.LeaveType, year int, days float64) error {
+	if isCompOff(leave) {
+		return db.checkCompOffBalance(q, leave, days)
+	}
+	allowance, err := lockAllowance(q, leave.UserID, leaveType, year)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	used, err := usedDays(q, leave.UserID, leave.Type, year)
+	if err != nil {
+		return err
+	}
+	pending, err := db.pendingDays(q, leave.UserID, leave.Type, year, leave.ID)
+	if err != nil {
+		return err
+	}
+	if remaining := allowance - used - pending; days > remaining {
+		return &InsufficientBalanceError{Type: leave.Type, Requested: days, Remaining: remaining}
+	}
+	return nil
+}
 
-	leaves := make([]models.Leave, 0)
-	for rows.Next() {
-		var leave models.Leave
-		if err := rows.Scan(&leave.ID, &leave.UserID, &leave.UserEmail, &leave.Type, &leave.StartDate, &leave.EndDate, &leave.Reason, &leave.Status, &leave.ApproverComment, &leave.CreatedAt); err != nil {
-			return nil, err
-		}
-		leaves = append(leaves, leave)
+// leaveColumns is the column list read by scanLeave; queries must alias
+// leaves as l and join users as u.
+const leaveColumns = "l.id, l.user_id, u.email, l.type, l.start_date, l.end_date, l.portion, l.start_time, l.end_time, l.reason, l.status, l.approver_id, l.current_step, l.awaiting_since, l.reminded_at, l.escalated_at, l.approver_comment, l.sequence, l.created_at"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (db *Database) scanLeave(row rowScanner) (*models.Leave, error) {
+	leave := &models.Leave{}
+	err := row.Scan(&leave.ID, &leave.UserID, &leave.UserEmail, &leave.Type, &leave.StartDate, &leave.EndDate, &leave.Portion, &leave.StartTime, &leave.EndTime, &leave.Reason, &leave.Status, &leave.ApproverID, &leave.CurrentStep, &leave.AwaitingSince, &leave.RemindedAt, &leave.EscalatedAt, &leave.ApproverComment, &leave.Sequence, &leave.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
-	return leaves, nil
+	leave.StartTime = trimSeconds(leave.StartTime)
+	leave.EndTime = trimSeconds(leave.EndTime)
+	db.withWorkingDays(leave)
+	return leave, nil
 }
 
-func (db *Database) GetLeavesByUserID(userID string) ([]models.Leave, error) {
-	query := `
-		SELECT l.id, l.user_id, u.email, l.type, l.start_date, l.end_date, l.reason, l.status, l.approver_comment, l.created_at
-		FROM leaves l
-		JOIN users u ON l.user_id = u.id
-		WHERE l.user_id = ?
-		ORDER BY l.created_at DESC
-	`
-	rows, err := db.Conn.Query(query, userID)
+func (db *Database) queryLeaves(q querier, query string, args ...any) ([]models.Leave, error) {
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -200,32 +338,42 @@ func (db *Database) GetLeavesByUserID(userID string) ([]models.Leave, error) {
 
 	leaves := make([]models.Leave, 0)
 	for rows.Next() {
-		var leave models.Leave
-		if err := rows.Scan(&leave.ID, &leave.UserID, &leave.UserEmail, &leave.Type, &leave.StartDate, &leave.EndDate, &leave.Reason, &leave.Status, &leave.ApproverComment, &leave.CreatedAt); err != nil {
+		leave, err := db.scanLeave(rows)
+		if err != nil {
 			return nil, err
 		}
-		leaves = append(leaves, leave)
+		leaves = append(leaves, *leave)
 	}
-	return leaves, nil
+	return leaves, rows.Err()
 }
 
+// GetLeaveByID returns a leave together with its approval steps and
+// attachments
 func (db *Database) GetLeaveByID(leaveID string) (*models.Leave, error) {
-	leave := &models.Leave{}
 	query := `
-		SELECT l.id, l.user_id, u.email, l.type, l.start_date, l.end_date, l.reason, l.status, l.approver_comment, l.created_at
+		SELECT ` + leaveColumns + `
 		FROM leaves l
 		JOIN users u ON l.user_id = u.id
 		WHERE l.id = ?
 	`
-	err := db.Conn.QueryRow(query, leaveID).Scan(&leave.ID, &leave.UserID, &leave.UserEmail, &leave.Type, &leave.StartDate, &leave.EndDate, &leave.Reason, &leave.Status, &leave.ApproverComment, &leave.CreatedAt)
+	leave, err := db.scanLeave(db.Conn.QueryRow(query, leaveID))
+	if err != nil {
+		return nil, err
+	}
+	leave.Approvals, err = getLeaveApprovals(db.Conn, leaveID)
+	if err != nil {
+		return nil, err
+	}
+	leave.Attachments, err = getAttachments(db.Conn, leaveID)
 	if err != nil {
 		return nil, err
 	}
 	return leave, nil
 }
 
-func (db *Database) CreateUser(email string) (*models.User, error) {
-	user := &models.User{
+// newUser builds a user with the default role and allowances
+func newUser(email string) *models.User {
+	return &models.User{
 		ID:    uuid.New().String(),
 		Email: email,
 		Role:  "user",
@@ -235,22 +383,137 @@ func (db *Database) CreateUser(email string) (*models.User, error) {
 			Casual: 5,
 		},
 	}
+}
+
+// CreateUser provisions a user on their first sign-in, so the new user is
+// recorded as the actor
+func (db *Database) CreateUser(email string) (*models.User, error) {
+	user := newUser(email)
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := "INSERT INTO users (id, email, role, annual_allowance, sick_allowance, casual_allowance) VALUES (?, ?, ?, ?, ?, ?)"
-	_, err := db.Conn.Exec(query, user.ID, user.Email, user.Role, user.Allowances.Annual, user.Allowances.Sick, user.Allowances.Casual)
+	_, err = tx.Exec(query, user.ID, user.Email, user.Role, user.Allowances.Annual, user.Allowances.Sick, user.Allowances.Casual)
 	if err != nil {
 		return nil, err
 	}
+	if err := recordAudit(tx, user.ID, constants.AuditUserCreate, user.ID, nil, user); err != nil {
+		return nil, err
+	}
+	if err := db.commitLeaves(tx); err != nil {
+		return nil, err
+	}
 	return db.GetUserByEmail(email)
 }
 
-func (db *Database) UpdateLeaveStatus(leaveID string, status string, comment *string) error {
-	query := "UPDATE leaves SET status = ?, approver_comment = ? WHERE id = ?"
-	_, err := db.Conn.Exec(query, status, comment, leaveID)
-	return err
+// lockLeave reads a leave and locks its row for the rest of the transaction
+func (db *Database) lockLeave(q querier, leaveID string) (*models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.id = ?
+		FOR UPDATE
+	`
+	return db.scanLeave(q.QueryRow(query, leaveID))
+}
+
+// UpdateLeaveStatus changes a leave's status, deducting its days from the
+// balance when it becomes approved and restoring them when it is cancelled.
+func (db *Database) UpdateLeaveStatus(actorID string, leaveID string, status string, comment *string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leave, err := db.lockLeave(tx, leaveID)
+	if err != nil {
+		return err
+	}
+	if err := db.setLeaveStatus(tx, actorID, leave, status, comment); err != nil {
+		return err
+	}
+	return db.commitLeaves(tx)
 }
 
-func (db *Database) DeleteLeave(leaveID string) error {
+// setLeaveStatus moves a locked leave to status and keeps the balance
+// ledger and audit trail in step with it. Illegal transitions return a
+// *TransitionError.
+func (db *Database) setLeaveStatus(q querier, actorID string, leave *models.Leave, status string, comment *string) error {
+	if err := checkTransition(leave.Status, status); err != nil {
+		return err
+	}
+	if leave.Status == string(constants.LeaveStatusPending) && status == string(constants.LeaveStatusApproved) {
+		if err := db.checkAttachment(q, leave); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case chargesBalance(status) && !chargesBalance(leave.Status):
+		// deductLeave takes the user's row lock, which the overlap check relies on
+		if err := db.deductLeave(q, leave); err != nil {
+			return err
+		}
+		if err := db.checkOverlap(q, leave, constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested); err != nil {
+			return err
+		}
+	case !chargesBalance(status) && chargesBalance(leave.Status):
+		if err := restoreLeave(q, leave); err != nil {
+			return err
+		}
+	}
+
+	after := *leave
+	after.Status = status
+	after.ApproverComment = comment
+	after.CurrentStep = stepFor(leave, status)
+	after.Sequence = leave.Sequence + 1
+	restartWait(&after, time.Now())
+
+	query := "UPDATE leaves SET status = ?, approver_comment = ?, current_step = ?, awaiting_since = ?, reminded_at = NULL, escalated_at = NULL, sequence = sequence + 1 WHERE id = ?"
+	if _, err := q.Exec(query, after.Status, after.ApproverComment, after.CurrentStep, after.AwaitingSince, leave.ID); err != nil {
+		return err
+	}
+	if err := recordAudit(q, actorID, constants.AuditLeaveStatus, leave.ID, leave, after); err != nil {
+		return err
+	}
+	if event, ok := webhookEventFor(leave.Status, status); ok {
+		return enqueueWebhooks(q, event, &after)
+	}
+	return nil
+}
+
+// DeleteLeave removes a leave, giving back any days it had been charged.
+func (db *Database) DeleteLeave(actorID string, leaveID string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leave, err := db.lockLeave(tx, leaveID)
+	if err != nil {
+		return err
+	}
+	if err := restoreLeave(tx, leave); err != nil {
+		return err
+	}
+
 	query := "DELETE FROM leaves WHERE id = ?"
-	_, err := db.Conn.Exec(query, leaveID)
-	return err
+	if _, err := tx.Exec(query, leaveID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditLeaveDelete, leaveID, leave, nil); err != nil {
+		return err
+	}
+	if err := enqueueWebhooks(tx, constants.WebhookLeaveDeleted, leave); err != nil {
+		return err
+	}
+	return db.commitLeaves(tx)
 }
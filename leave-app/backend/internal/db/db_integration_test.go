@@ -0,0 +1,58 @@
+//go:build integration
+
+// internal/db/db_integration_test.go
+package db
+
+import (
+	"database/sql"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/testdb"
+	"leave-app/internal/workdays"
+	"testing"
+)
+
+// openDatabase returns a Database on conn, migrating it on first use
+func openDatabase(t *testing.T, conn *sql.DB) *Database {
+	t.Helper()
+	d := &Database{Conn: conn, Calendar: workdays.New(workdays.DefaultWeekend)}
+	if err := d.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDatabase(t *testing.T) {
+	testStore(t, func(t *testing.T) Store { return openDatabase(t, testdb.Open(t)) })
+}
+
+// Instances share the database but not their caches: analytics cached by
+// one must not outlive leave approved through another
+func TestAnalyticsCacheAcrossInstances(t *testing.T) {
+	conn := testdb.Open(t)
+	first, second := openDatabase(t, conn), openDatabase(t, conn)
+	env := newStoreEnv(t, first)
+
+	cached, err := first.GetLeaveAnalytics("2030-03-01", "2030-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leave := &models.Leave{
+		UserID: env.alice.ID, Type: string(constants.LeaveTypeAnnual), StartDate: "2030-03-04", EndDate: "2030-03-05",
+		Portion: string(constants.LeavePortionFull), Reason: "away", Status: string(constants.LeaveStatusPending),
+	}
+	if err := second.CreateLeave(env.alice.ID, leave); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.UpdateLeaveStatus(env.admin.ID, leave.ID, string(constants.LeaveStatusApproved), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := first.GetLeaveAnalytics("2030-03-01", "2030-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GeneratedAt.Equal(cached.GeneratedAt) || got.Months[0].Days["annual"] != 2 {
+		t.Errorf("march = %+v, want the leave approved on the other instance", got.Months[0])
+	}
+}
@@ -0,0 +1,214 @@
+// internal/db/delegations.go
+package db
+
+import (
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidDelegation = errors.New("invalid delegation")
+
+// canApprove reports whether a user with role has authority to delegate
+func canApprove(role string) bool {
+	return role == string(constants.RoleManager) || role == string(constants.RoleHR) || role == string(constants.RoleAdmin)
+}
+
+// delegatedDecision is what the audit trail records when a delegate
+// decides for a delegator, next to the leave's own change
+type delegatedDecision struct {
+	DelegatorID string `json:"delegatorId"`
+	Decision    string `json:"decision"`
+}
+
+// today is the date delegations are checked against
+func today() string {
+	return time.Now().Format(workdays.DateLayout)
+}
+
+// onBehalfOf is the delegator decider stands for, or nil when actor
+// decided on their own authority
+func onBehalfOf(actor, decider *models.User) *string {
+	if decider.ID == actor.ID {
+		return nil
+	}
+	return &decider.ID
+}
+
+// recordDelegatedDecision notes in the audit trail that actor decided a
+// leave with decider's authority, when decider is somebody else
+func recordDelegatedDecision(q querier, actor, decider *models.User, leaveID string, decision string) error {
+	if decider.ID == actor.ID {
+		return nil
+	}
+	return recordAudit(q, actor.ID, constants.AuditLeaveDelegated, leaveID, nil, delegatedDecision{DelegatorID: decider.ID, Decision: decision})
+}
+
+// checkDelegationDates makes sure a delegation has YYYY-MM-DD dates in
+// order
+func checkDelegationDates(d *models.Delegation) error {
+	start, err := time.Parse(workdays.DateLayout, d.StartDate)
+	if err != nil {
+		return ErrInvalidDateRange
+	}
+	end, err := time.Parse(workdays.DateLayout, d.EndDate)
+	if err != nil || end.Before(start) {
+		return ErrInvalidDateRange
+	}
+	return nil
+}
+
+// activeDelegators returns the users whose authority delegateID holds on
+// date
+func activeDelegators(q querier, delegateID string, date string) ([]models.User, error) {
+	query := `
+		SELECT ` + userColumns + ` FROM users
+		WHERE id IN (SELECT delegator_id FROM approval_delegations WHERE delegate_id = ? AND start_date <= ? AND end_date >= ?)
+		ORDER BY email
+	`
+	return queryUsers(q, query, delegateID, date, date)
+}
+
+// GetActiveDelegators returns the users whose authority userID holds today
+func (db *Database) GetActiveDelegators(userID string) ([]models.User, error) {
+	return activeDelegators(db.Conn, userID, today())
+}
+
+// activeDelegates returns the users holding the authority of any of
+// delegatorIDs on date
+func activeDelegates(q querier, delegatorIDs []string, date string) ([]models.User, error) {
+	if len(delegatorIDs) == 0 {
+		return []models.User{}, nil
+	}
+	args := []any{date, date}
+	for _, id := range delegatorIDs {
+		args = append(args, id)
+	}
+	query := `
+		SELECT ` + userColumns + ` FROM users
+		WHERE id IN (
+			SELECT delegate_id FROM approval_delegations
+			WHERE start_date <= ? AND end_date >= ? AND delegator_id IN (?` + strings.Repeat(", ?", len(delegatorIDs)-1) + `)
+		)
+		ORDER BY email
+	`
+	return queryUsers(q, query, args...)
+}
+
+func queryUsers(q querier, query string, args ...any) ([]models.User, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// CreateDelegation lends the delegator's approval authority to the
+// delegate for the delegation's dates. The delegator must be an approver
+// and the delegate somebody else.
+func (db *Database) CreateDelegation(actorID string, d *models.Delegation) error {
+	if err := checkDelegationDates(d); err != nil {
+		return err
+	}
+	if d.DelegateID == d.DelegatorID {
+		return ErrInvalidDelegation
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	delegator, err := lockUser(tx, d.DelegatorID)
+	if err != nil {
+		return err
+	}
+	if !canApprove(delegator.Role) {
+		return ErrInvalidDelegation
+	}
+	if err := tx.QueryRow("SELECT email FROM users WHERE id = ?", d.DelegateID).Scan(&d.DelegateEmail); err != nil {
+		return ErrInvalidDelegation
+	}
+
+	d.ID = uuid.New().String()
+	d.DelegatorEmail = delegator.Email
+	d.CreatedAt = time.Now()
+	query := "INSERT INTO approval_delegations (id, delegator_id, delegate_id, start_date, end_date, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, d.ID, d.DelegatorID, d.DelegateID, d.StartDate, d.EndDate, d.CreatedAt); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditDelegationCreate, d.ID, nil, d); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDelegations returns the delegations userID gave or received, latest
+// first
+func (db *Database) GetDelegations(userID string) ([]models.Delegation, error) {
+	query := `
+		SELECT d.id, d.delegator_id, dr.email, d.delegate_id, de.email, d.start_date, d.end_date, d.created_at
+		FROM approval_delegations d
+		JOIN users dr ON d.delegator_id = dr.id
+		JOIN users de ON d.delegate_id = de.id
+		WHERE d.delegator_id = ? OR d.delegate_id = ?
+		ORDER BY d.start_date DESC, d.id
+	`
+	rows, err := db.Conn.Query(query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delegations := make([]models.Delegation, 0)
+	for rows.Next() {
+		var d models.Delegation
+		if err := rows.Scan(&d.ID, &d.DelegatorID, &d.DelegatorEmail, &d.DelegateID, &d.DelegateEmail, &d.StartDate, &d.EndDate, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.StartDate, d.EndDate = sqlDate(d.StartDate), sqlDate(d.EndDate)
+		delegations = append(delegations, d)
+	}
+	return delegations, rows.Err()
+}
+
+// DeleteDelegation ends one of delegatorID's delegations straight away
+func (db *Database) DeleteDelegation(actorID string, delegatorID string, delegationID string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before models.Delegation
+	query := "SELECT id, delegator_id, delegate_id, start_date, end_date, created_at FROM approval_delegations WHERE id = ? AND delegator_id = ? FOR UPDATE"
+	err = tx.QueryRow(query, delegationID, delegatorID).Scan(&before.ID, &before.DelegatorID, &before.DelegateID, &before.StartDate, &before.EndDate, &before.CreatedAt)
+	if err != nil {
+		return err
+	}
+	before.StartDate, before.EndDate = sqlDate(before.StartDate), sqlDate(before.EndDate)
+
+	if _, err := tx.Exec("DELETE FROM approval_delegations WHERE id = ?", delegationID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditDelegationDelete, delegationID, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,151 @@
+// internal/db/holidays.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidHolidayDate = errors.New("holiday date must be YYYY-MM-DD")
+	ErrHolidaysReadOnly   = errors.New("holidays are read from HOLIDAYS_ICS_FILE and cannot be edited")
+)
+
+// Holidays implements workdays.HolidaySource using the holidays table
+func (db *Database) Holidays() (map[string]string, error) {
+	rows, err := db.Conn.Query("SELECT date, name FROM holidays")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := make(map[string]string)
+	for rows.Next() {
+		var date time.Time
+		var name string
+		if err := rows.Scan(&date, &name); err != nil {
+			return nil, err
+		}
+		holidays[date.Format(workdays.DateLayout)] = name
+	}
+	return holidays, rows.Err()
+}
+
+// holidaysEditable reports ErrHolidaysReadOnly when cal takes its holidays
+// from somewhere other than store, such as an ICS file
+func holidaysEditable(cal *workdays.Calendar, store workdays.HolidaySource) error {
+	if source := cal.Source(); source != nil && source != store {
+		return ErrHolidaysReadOnly
+	}
+	return nil
+}
+
+// holidayDate checks that date is a plain YYYY-MM-DD date
+func holidayDate(date string) error {
+	if _, err := time.Parse(workdays.DateLayout, date); err != nil {
+		return ErrInvalidHolidayDate
+	}
+	return nil
+}
+
+// holidaysFromMap lists holidays in date order
+func holidaysFromMap(byDate map[string]string) []models.Holiday {
+	holidays := make([]models.Holiday, 0, len(byDate))
+	for date, name := range byDate {
+		holidays = append(holidays, models.Holiday{Date: date, Name: name})
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays
+}
+
+// listHolidays lists the holidays leave is costed with: store's own when
+// it drives cal, otherwise those cal loaded from its other source
+func listHolidays(cal *workdays.Calendar, store workdays.HolidaySource) ([]models.Holiday, error) {
+	if holidaysEditable(cal, store) != nil {
+		return holidaysFromMap(cal.Holidays()), nil
+	}
+	byDate, err := store.Holidays()
+	if err != nil {
+		return nil, err
+	}
+	return holidaysFromMap(byDate), nil
+}
+
+func (db *Database) GetHolidays() ([]models.Holiday, error) {
+	return listHolidays(db.Calendar, db)
+}
+
+// SetHoliday adds a holiday or renames an existing one, then reloads the
+// calendar so new leave is costed with it. Leave already filed keeps what
+// it was charged.
+func (db *Database) SetHoliday(actorID string, holiday models.Holiday) error {
+	if err := holidaysEditable(db.Calendar, db); err != nil {
+		return err
+	}
+	if err := holidayDate(holiday.Date); err != nil {
+		return err
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before *models.Holiday
+	var name string
+	err = tx.QueryRow("SELECT name FROM holidays WHERE date = ? FOR UPDATE", holiday.Date).Scan(&name)
+	switch {
+	case err == nil:
+		before = &models.Holiday{Date: holiday.Date, Name: name}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO holidays (date, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)", holiday.Date, holiday.Name); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditHolidaySet, holiday.Date, before, holiday); err != nil {
+		return err
+	}
+	if err := db.commitLeaves(tx); err != nil {
+		return err
+	}
+	return db.Calendar.Load(db)
+}
+
+// DeleteHoliday removes a holiday and reloads the calendar
+func (db *Database) DeleteHoliday(actorID string, date string) error {
+	if err := holidaysEditable(db.Calendar, db); err != nil {
+		return err
+	}
+	if err := holidayDate(date); err != nil {
+		return err
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before := models.Holiday{Date: date}
+	if err := tx.QueryRow("SELECT name FROM holidays WHERE date = ? FOR UPDATE", date).Scan(&before.Name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM holidays WHERE date = ?", date); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditHolidayDelete, date, before, nil); err != nil {
+		return err
+	}
+	if err := db.commitLeaves(tx); err != nil {
+		return err
+	}
+	return db.Calendar.Load(db)
+}
@@ -0,0 +1,309 @@
+// internal/db/import.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/approval"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// importedUser is a user touched by an import, as it was and will be
+type importedUser struct {
+	row    models.ImportUser
+	before *models.User // nil for a new user
+	after  models.User
+}
+
+// importError builds a row error for one of the imported files
+func importError(file string, row int, column, message string) models.ImportRowError {
+	return models.ImportRowError{File: file, Row: row, Column: column, Message: message}
+}
+
+// applyImportUser copies the non-empty cells of row onto user
+func applyImportUser(user *models.User, row models.ImportUser) {
+	if row.Role != "" {
+		user.Role = row.Role
+	}
+	for _, a := range []struct {
+		value *int
+		dest  *int
+	}{
+		{row.Annual, &user.Allowances.Annual},
+		{row.Sick, &user.Allowances.Sick},
+		{row.Casual, &user.Allowances.Casual},
+	} {
+		if a.value != nil {
+			*a.dest = *a.value
+		}
+	}
+}
+
+// checkImportManager reports what is wrong with making manager the
+// manager of user, or "" when nothing is
+func checkImportManager(user *models.User, manager *models.User) string {
+	switch {
+	case manager == nil:
+		return "unknown manager"
+	case manager.ID == user.ID:
+		return "a user cannot manage themselves"
+	case !canManage(manager.Role):
+		return "manager must have the manager or admin role"
+	}
+	return ""
+}
+
+// buildImportedLeave builds the leave for a leaves row of user
+func buildImportedLeave(row models.ImportLeave, user *models.User) *models.Leave {
+	return &models.Leave{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		UserEmail:  user.Email,
+		Type:       row.Type,
+		StartDate:  row.StartDate,
+		EndDate:    row.EndDate,
+		Portion:    row.Portion,
+		Reason:     row.Reason,
+		Status:     row.Status,
+		ApproverID: copyString(user.ManagerID),
+	}
+}
+
+// importedApprovalComment is left on the approval steps of imported leave
+const importedApprovalComment = "Approved before import"
+
+// importedApprovals is the approval chain of an imported leave. Approved
+// leave, and leave cancelled after approval, had every step of its chain
+// signed off; rejected and withdrawn leave has no record of who decided
+// which step, so it gets no chain.
+func importedApprovals(policies []models.ApprovalPolicy, leave *models.Leave) []models.LeaveApproval {
+	if leave.Status != string(constants.LeaveStatusApproved) && leave.Status != string(constants.LeaveStatusCancelled) {
+		return nil
+	}
+	comment, decidedAt := importedApprovalComment, leave.CreatedAt
+	steps := approval.Resolve(policies, leave.Type, leave.WorkingDays)
+	chain := make([]models.LeaveApproval, len(steps))
+	for i, role := range steps {
+		chain[i] = models.LeaveApproval{Step: i, Role: role, Decision: string(constants.LeaveStatusApproved), Comment: &comment, DecidedAt: &decidedAt}
+	}
+	return chain
+}
+
+// leaveImportError turns a reason a leave cannot be recorded into a row
+// error
+func leaveImportError(row int, err error) (models.ImportRowError, bool) {
+	var overlap *OverlapError
+	var balance *InsufficientBalanceError
+	switch {
+	case errors.Is(err, ErrUnknownLeaveType):
+		return importError("leaves", row, "type", "unknown leave type"), true
+	case errors.Is(err, ErrInvalidDateRange):
+		return importError("leaves", row, "end_date", "end_date must not be before start_date"), true
+	case errors.Is(err, ErrLeaveSpansYears):
+		return importError("leaves", row, "end_date", "leave must start and end in the same year"), true
+	case errors.Is(err, ErrNoWorkingDays):
+		return importError("leaves", row, "start_date", "leave does not cover any working days"), true
+	case errors.Is(err, ErrInvalidPortion):
+		return importError("leaves", row, "portion", "half-day leave must start and end on the same day"), true
+	case errors.As(err, &overlap):
+		return importError("leaves", row, "start_date", err.Error()), true
+	case errors.As(err, &balance):
+		return importError("leaves", row, "type", "not enough comp-off credits: "+err.Error()), true
+	}
+	return models.ImportRowError{}, false
+}
+
+// ImportRecords creates or updates the batch's users, then records its
+// leaves, all in one transaction. Row problems are collected in the
+// result rather than returned; the transaction is only committed when
+// there are none and dryRun is unset. Approved leaves are charged to
+// their balance without checking the allowance, since the leave was
+// already taken.
+func (db *Database) ImportRecords(actorID string, batch *models.ImportBatch, dryRun bool) (*models.ImportResult, error) {
+	result := &models.ImportResult{DryRun: dryRun, Errors: make([]models.ImportRowError, 0)}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	findUser := func(email string) (*models.User, error) {
+		user, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE email = ? FOR UPDATE", email))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return user, err
+	}
+
+	users := make([]*importedUser, 0, len(batch.Users))
+	for _, row := range batch.Users {
+		before, err := findUser(row.Email)
+		if err != nil {
+			return nil, err
+		}
+		u := &importedUser{row: row, before: before}
+		if before == nil {
+			u.after = *newUser(row.Email)
+			applyImportUser(&u.after, row)
+			query := "INSERT INTO users (id, email, role, annual_allowance, sick_allowance, casual_allowance) VALUES (?, ?, ?, ?, ?, ?)"
+			if _, err := tx.Exec(query, u.after.ID, u.after.Email, u.after.Role, u.after.Allowances.Annual, u.after.Allowances.Sick, u.after.Allowances.Casual); err != nil {
+				return nil, err
+			}
+			result.UsersCreated++
+		} else {
+			u.after = *before
+			applyImportUser(&u.after, row)
+			query := "UPDATE users SET role = ?, annual_allowance = ?, sick_allowance = ?, casual_allowance = ? WHERE id = ?"
+			if _, err := tx.Exec(query, u.after.Role, u.after.Allowances.Annual, u.after.Allowances.Sick, u.after.Allowances.Casual, u.after.ID); err != nil {
+				return nil, err
+			}
+			result.UsersUpdated++
+		}
+		users = append(users, u)
+	}
+
+	// Managers are set once every user exists, so a file may name a
+	// manager on a later row
+	for _, u := range users {
+		if u.row.ManagerEmail == "" {
+			continue
+		}
+		manager, err := findUser(u.row.ManagerEmail)
+		if err != nil {
+			return nil, err
+		}
+		if msg := checkImportManager(&u.after, manager); msg != "" {
+			result.Errors = append(result.Errors, importError("users", u.row.Row, "manager", msg))
+			continue
+		}
+		u.after.ManagerID = &manager.ID
+		if _, err := tx.Exec("UPDATE users SET manager_id = ? WHERE id = ?", manager.ID, u.after.ID); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range users {
+		cycle, err := reportsToSelf(tx, u.after.ID)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			result.Errors = append(result.Errors, importError("users", u.row.Row, "manager", "managers form a cycle"))
+		}
+		if err := recordAudit(tx, actorID, constants.AuditUserImport, u.after.ID, u.before, u.after); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, row := range batch.Leaves {
+		user, err := findUser(row.Email)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			result.Errors = append(result.Errors, importError("leaves", row.Row, "email", "unknown user"))
+			continue
+		}
+		leave := buildImportedLeave(row, user)
+		if err := db.importLeave(tx, actorID, leave); err != nil {
+			rowErr, ok := leaveImportError(row.Row, err)
+			if !ok {
+				return nil, err
+			}
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+		result.Leaves++
+	}
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+	if err := db.commitLeaves(tx); err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// reportsToSelf reports whether walking up from userID's manager leads
+// back to userID
+func reportsToSelf(q querier, userID string) (bool, error) {
+	seen := map[string]bool{}
+	next := userID
+	for {
+		var parent *string
+		if err := q.QueryRow("SELECT manager_id FROM users WHERE id = ?", next).Scan(&parent); err != nil {
+			return false, err
+		}
+		if parent == nil || seen[*parent] {
+			return false, nil
+		}
+		if *parent == userID {
+			return true, nil
+		}
+		seen[*parent] = true
+		next = *parent
+	}
+}
+
+// importLeave records one imported leave with its approval chain, charging
+// it to the balance when its status does. Only comp-off is checked against
+// the balance: it has to be drawn from credits. Callers hold the user's
+// row lock.
+func (db *Database) importLeave(q querier, actorID string, leave *models.Leave) error {
+	leaveType, err := getLeaveType(q, leave.Type)
+	if err != nil {
+		return err
+	}
+	days, err := db.LeaveDuration(leave)
+	if err != nil {
+		return err
+	}
+	if days == 0 {
+		return ErrNoWorkingDays
+	}
+	leave.WorkingDays = days
+	year, err := chargeYear(leave)
+	if err != nil {
+		return err
+	}
+	if chargesBalance(leave.Status) {
+		if err := db.checkOverlap(q, leave, constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested); err != nil {
+			return err
+		}
+	}
+
+	leave.CreatedAt = time.Now()
+	query := "INSERT INTO leaves (id, user_id, type, start_date, end_date, portion, reason, status, approver_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := q.Exec(query, leave.ID, leave.UserID, leave.Type, leave.StartDate, leave.EndDate, leave.Portion, leave.Reason, leave.Status, leave.ApproverID, leave.CreatedAt); err != nil {
+		return err
+	}
+	policies, err := getApprovalPolicies(q)
+	if err != nil {
+		return err
+	}
+	leave.Approvals = importedApprovals(policies, leave)
+	for _, a := range leave.Approvals {
+		query := "INSERT INTO leave_approvals (leave_id, step, role, decision, comment, decided_at) VALUES (?, ?, ?, ?, ?, ?)"
+		if _, err := q.Exec(query, leave.ID, a.Step, a.Role, a.Decision, a.Comment, a.DecidedAt); err != nil {
+			return err
+		}
+	}
+	switch {
+	case !chargesBalance(leave.Status) || !leaveType.CountsAgainstBalance:
+	case isCompOff(leave):
+		if err := db.deductCompOff(q, leave, days, year); err != nil {
+			return err
+		}
+	default:
+		if err := insertLedgerEntry(q, leave.UserID, leave.ID, leave.Type, year, days); err != nil {
+			return err
+		}
+	}
+	return recordAudit(q, actorID, constants.AuditLeaveImport, leave.ID, nil, leave)
+}
@@ -0,0 +1,181 @@
+// internal/db/leavetypes.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+)
+
+var (
+	ErrDuplicateLeaveType = errors.New("a leave type with that code already exists")
+	ErrLeaveTypeInUse     = errors.New("leave type is used by existing leaves")
+)
+
+// MaxConsecutiveDaysError is returned when a leave spans more calendar
+// days than its type allows.
+type MaxConsecutiveDaysError struct {
+	Type string
+	Max  int
+	Days int
+}
+
+func (e *MaxConsecutiveDaysError) Error() string {
+	return fmt.Sprintf("%s leave is limited to %d consecutive day(s), requested %d", e.Type, e.Max, e.Days)
+}
+
+// checkConsecutiveDays enforces the type's MaxConsecutiveDays on a leave
+func checkConsecutiveDays(leaveType *models.LeaveType, leave *models.Leave) error {
+	if leaveType.MaxConsecutiveDays == nil {
+		return nil
+	}
+	start, err := parseDate(leave.StartDate)
+	if err != nil {
+		return ErrInvalidDateRange
+	}
+	end, err := parseDate(leave.EndDate)
+	if err != nil {
+		return ErrInvalidDateRange
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	if days > *leaveType.MaxConsecutiveDays {
+		return &MaxConsecutiveDaysError{Type: leaveType.Code, Max: *leaveType.MaxConsecutiveDays, Days: days}
+	}
+	return nil
+}
+
+const leaveTypeColumns = "code, name, paid, requires_attachment, attachment_over_days, max_consecutive_days, counts_against_balance, default_allowance"
+
+func scanLeaveType(row rowScanner) (*models.LeaveType, error) {
+	t := &models.LeaveType{}
+	err := row.Scan(&t.Code, &t.Name, &t.Paid, &t.RequiresAttachment, &t.AttachmentOverDays, &t.MaxConsecutiveDays, &t.CountsAgainstBalance, &t.DefaultAllowance)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// getLeaveType reads one leave type, returning ErrUnknownLeaveType if
+// there is none with that code
+func getLeaveType(q querier, code string) (*models.LeaveType, error) {
+	t, err := scanLeaveType(q.QueryRow("SELECT "+leaveTypeColumns+" FROM leave_types WHERE code = ?", code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownLeaveType
+	}
+	return t, err
+}
+
+func getLeaveTypes(q querier) ([]models.LeaveType, error) {
+	rows, err := q.Query("SELECT " + leaveTypeColumns + " FROM leave_types ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make([]models.LeaveType, 0)
+	for rows.Next() {
+		t, err := scanLeaveType(rows)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, *t)
+	}
+	return types, rows.Err()
+}
+
+func leaveTypesByCode(q querier) (map[string]models.LeaveType, error) {
+	types, err := getLeaveTypes(q)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.LeaveType, len(types))
+	for _, t := range types {
+		byCode[t.Code] = t
+	}
+	return byCode, nil
+}
+
+func (db *Database) GetLeaveTypes() ([]models.LeaveType, error) {
+	return getLeaveTypes(db.Conn)
+}
+
+func (db *Database) CreateLeaveType(actorID string, leaveType *models.LeaveType) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := getLeaveType(tx, leaveType.Code); err == nil {
+		return ErrDuplicateLeaveType
+	} else if !errors.Is(err, ErrUnknownLeaveType) {
+		return err
+	}
+
+	t := leaveType
+	query := "INSERT INTO leave_types (" + leaveTypeColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, t.Code, t.Name, t.Paid, t.RequiresAttachment, t.AttachmentOverDays, t.MaxConsecutiveDays, t.CountsAgainstBalance, t.DefaultAllowance); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditLeaveTypeCreate, t.Code, nil, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateLeaveType changes a type's settings. Leaves already filed keep
+// what they were charged; the new settings apply from the next request.
+func (db *Database) UpdateLeaveType(actorID string, code string, settings models.LeaveTypeSettings) (*models.LeaveType, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanLeaveType(tx.QueryRow("SELECT "+leaveTypeColumns+" FROM leave_types WHERE code = ? FOR UPDATE", code))
+	if err != nil {
+		return nil, err
+	}
+
+	s := settings
+	query := "UPDATE leave_types SET name = ?, paid = ?, requires_attachment = ?, attachment_over_days = ?, max_consecutive_days = ?, counts_against_balance = ?, default_allowance = ? WHERE code = ?"
+	if _, err := tx.Exec(query, s.Name, s.Paid, s.RequiresAttachment, s.AttachmentOverDays, s.MaxConsecutiveDays, s.CountsAgainstBalance, s.DefaultAllowance, code); err != nil {
+		return nil, err
+	}
+	after := &models.LeaveType{Code: code, LeaveTypeSettings: settings}
+	if err := recordAudit(tx, actorID, constants.AuditLeaveTypeUpdate, code, before, after); err != nil {
+		return nil, err
+	}
+	return after, tx.Commit()
+}
+
+// DeleteLeaveType removes a type no leave refers to
+func (db *Database) DeleteLeaveType(actorID string, code string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanLeaveType(tx.QueryRow("SELECT "+leaveTypeColumns+" FROM leave_types WHERE code = ? FOR UPDATE", code))
+	if err != nil {
+		return err
+	}
+	var used int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM leaves WHERE type = ?", code).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return ErrLeaveTypeInUse
+	}
+
+	if _, err := tx.Exec("DELETE FROM leave_types WHERE code = ?", code); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditLeaveTypeDelete, code, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,2212 @@
+// internal/db/memory.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"leave-app/internal/accrual"
+	"leave-app/internal/approval"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// accrualEntry mirrors a row of leave_accruals
+type accrualEntry struct {
+	UserID    string
+	LeaveType string
+	Year      int
+	Kind      string
+	Period    int
+	Days      float64
+}
+
+// ledgerEntry mirrors a row of leave_balance_ledger
+type ledgerEntry struct {
+	UserID    string
+	LeaveID   string
+	LeaveType string
+	Year      int
+	Days      float64
+}
+
+// MemoryStore is an in-memory Store with the same rules as Database.
+// Missing rows are reported as sql.ErrNoRows, like the MySQL driver.
+type MemoryStore struct {
+	Calendar *workdays.Calendar
+
+	mu        sync.Mutex
+	users     map[string]*models.User
+	leaves    map[string]*models.Leave
+	ledger    []ledgerEntry
+	policies  []models.ApprovalPolicy
+	approvals map[string][]models.LeaveApproval
+	audit     []models.AuditEvent
+
+	accrualPolicies map[string]models.AccrualPolicy
+	accruals        []accrualEntry
+
+	allowancePolicies map[string]*models.AllowancePolicy
+	userGroups        map[string][]string
+
+	leaveTypes  map[string]models.LeaveType
+	attachments map[string][]models.Attachment // By leave ID
+
+	holidays map[string]string // Name by date
+
+	calendarTokens map[string]string // Token hash to user ID
+
+	webhooks          map[string]*models.WebhookSubscription
+	webhookDeliveries []*models.WebhookDelivery
+
+	notificationPrefs map[string]models.NotificationPreferences
+
+	leases map[string]jobLease
+
+	minStaffing map[string]int // By manager ID
+
+	delegations []models.Delegation
+
+	compOffCredits map[string]*models.CompOffCredit
+	compOffUsage   []compOffUsage
+
+	analytics    analyticsCache
+	analyticsGen uint64 // Mirrors the analytics row of cache_generations
+}
+
+// jobLease mirrors a row of job_leases
+type jobLease struct {
+	Holder    string
+	ExpiresAt time.Time
+}
+
+// builtinLeaveTypes matches the rows migration 011 seeds leave_types with
+func builtinLeaveTypes() map[string]models.LeaveType {
+	types := make(map[string]models.LeaveType)
+	for code, name := range map[constants.LeaveType]string{
+		constants.LeaveTypeSick:   "Sick leave",
+		constants.LeaveTypeAnnual: "Annual leave",
+		constants.LeaveTypeCasual: "Casual leave",
+		// Seeded by migration 020
+		constants.LeaveTypeCompOff: "Comp-off",
+	} {
+		types[string(code)] = models.LeaveType{
+			Code:              string(code),
+			LeaveTypeSettings: models.LeaveTypeSettings{Name: name, Paid: true, CountsAgainstBalance: true},
+		}
+	}
+	return types
+}
+
+// NewMemoryStore creates an empty store with a Monday to Friday calendar
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Calendar:  workdays.New(workdays.DefaultWeekend),
+		users:     make(map[string]*models.User),
+		leaves:    make(map[string]*models.Leave),
+		approvals: make(map[string][]models.LeaveApproval),
+
+		accrualPolicies: make(map[string]models.AccrualPolicy),
+
+		allowancePolicies: make(map[string]*models.AllowancePolicy),
+		userGroups:        make(map[string][]string),
+
+		leaveTypes:  builtinLeaveTypes(),
+		attachments: make(map[string][]models.Attachment),
+
+		holidays: make(map[string]string),
+
+		calendarTokens: make(map[string]string),
+
+		webhooks: make(map[string]*models.WebhookSubscription),
+
+		notificationPrefs: make(map[string]models.NotificationPreferences),
+
+		leases: make(map[string]jobLease),
+
+		minStaffing: make(map[string]int),
+
+		compOffCredits: make(map[string]*models.CompOffCredit),
+	}
+}
+
+// AddUser inserts or replaces a user, for seeding tests
+func (m *MemoryStore) AddUser(user models.User) *models.User {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	m.users[user.ID] = &user
+	u := user
+	return &u
+}
+
+func (m *MemoryStore) GetUserByEmail(email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Email == email {
+			user := *u
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStore) CreateUser(email string) (*models.User, error) {
+	user := newUser(email)
+	user.CreatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Email == email {
+			return nil, fmt.Errorf("user %s already exists", email)
+		}
+	}
+	if err := m.recordAudit(user.ID, constants.AuditUserCreate, user.ID, nil, user); err != nil {
+		return nil, err
+	}
+	m.users[user.ID] = user
+	m.analyticsGen++
+	created := *user
+	return &created, nil
+}
+
+func (m *MemoryStore) ListUsers(filter models.UserFilter) (*models.UserPage, error) {
+	sortBy := userSort(filter)
+	field, desc := sortField(sortBy)
+	cursor, err := decodeCursor(filter.Cursor, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := make([]models.User, 0)
+	for _, u := range m.users {
+		switch {
+		case filter.Role != "" && u.Role != filter.Role,
+			filter.Email != "" && !strings.Contains(u.Email, filter.Email),
+			!cursor.after(userSortKey(u, field), u.ID, desc):
+			continue
+		}
+		users = append(users, *u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return keyBefore(userSortKey(&users[i], field), users[i].ID, userSortKey(&users[j], field), users[j].ID, desc)
+	})
+
+	page := &models.UserPage{}
+	page.Items, page.NextCursor = trimPage(users, pageLimit(filter.Limit), sortBy, func(u *models.User) (string, string) {
+		return userSortKey(u, field), u.ID
+	})
+	return page, nil
+}
+
+func (m *MemoryStore) UpdateUserRole(actorID string, userID string, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	after := *user
+	after.Role = role
+	if err := m.recordAudit(actorID, constants.AuditUserRole, userID, user, after); err != nil {
+		return err
+	}
+	user.Role = role
+	return nil
+}
+
+func (m *MemoryStore) SetUserManager(actorID string, userID string, managerID *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	if managerID != nil {
+		manager, ok := m.users[*managerID]
+		if !ok || *managerID == userID || !canManage(manager.Role) {
+			return ErrInvalidManager
+		}
+		seen := map[string]bool{}
+		for next := managerID; next != nil && !seen[*next]; {
+			if *next == userID {
+				return ErrInvalidManager
+			}
+			seen[*next] = true
+			if u, ok := m.users[*next]; ok {
+				next = u.ManagerID
+			} else {
+				next = nil
+			}
+		}
+	}
+
+	after := *user
+	after.ManagerID = copyString(managerID)
+	if err := m.recordAudit(actorID, constants.AuditUserManager, userID, user, after); err != nil {
+		return err
+	}
+	user.ManagerID = after.ManagerID
+	m.analyticsGen++
+	for _, l := range m.leaves {
+		if l.UserID == userID && l.Status == string(constants.LeaveStatusPending) {
+			l.ApproverID = copyString(managerID)
+		}
+	}
+	return nil
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func (m *MemoryStore) UpdateAllUserAllowances(actorID string, req models.UpdateAllowancesRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	allowances := models.Allowance{Sick: req.Sick, Annual: req.Annual, Casual: req.Casual}
+	for _, u := range m.users {
+		if u.Allowances == allowances {
+			continue
+		}
+		after := *u
+		after.Allowances = allowances
+		if err := m.recordAudit(actorID, constants.AuditUserAllowances, u.ID, u, after); err != nil {
+			return err
+		}
+		u.Allowances = allowances
+	}
+	return nil
+}
+
+// recordAudit mirrors the audit_events insert. Callers hold m.mu.
+func (m *MemoryStore) recordAudit(actorID string, action constants.AuditAction, entityID string, before, after any) error {
+	event, err := newAuditEvent(actorID, action, entityID, before, after)
+	if err != nil {
+		return err
+	}
+	event.ID = int64(len(m.audit) + 1)
+	m.audit = append(m.audit, *event)
+	return nil
+}
+
+// usedDays sums the ledger for a user, type and year. Callers hold m.mu.
+func (m *MemoryStore) usedDays(userID, leaveType string, year int) float64 {
+	var used float64
+	for _, e := range m.ledger {
+		if e.UserID == userID && e.LeaveType == leaveType && e.Year == year {
+			used += e.Days
+		}
+	}
+	return used
+}
+
+// allowance mirrors lockAllowance. Callers hold m.mu.
+func (m *MemoryStore) allowance(user *models.User, leaveType models.LeaveType, year int) float64 {
+	if _, ok := m.accrualPolicies[leaveType.Code]; !ok {
+		return float64(leaveType.AllowanceFrom(m.effectiveAllowance(user).Allowances))
+	}
+	credited, _ := m.accruedDays(user.ID, leaveType.Code, year)
+	return credited
+}
+
+// accruedDays mirrors the database function of the same name. Callers
+// hold m.mu.
+func (m *MemoryStore) accruedDays(userID, leaveType string, year int) (float64, float64) {
+	var credited, carried float64
+	for _, e := range m.accruals {
+		if e.UserID == userID && e.LeaveType == leaveType && e.Year == year {
+			credited += e.Days
+			if e.Kind == accrualCarryForward {
+				carried += e.Days
+			}
+		}
+	}
+	return credited, carried
+}
+
+// pendingDays sums the user's pending leaves of a type starting in year.
+// Callers hold m.mu.
+func (m *MemoryStore) pendingDays(userID, leaveType string, year int) float64 {
+	var pending float64
+	for _, l := range m.leaves {
+		if l.UserID != userID || l.Type != leaveType || l.Status != string(constants.LeaveStatusPending) {
+			continue
+		}
+		if y, err := leaveYear(l.StartDate); err == nil && y == year {
+			days, _ := leaveDuration(m.Calendar, l)
+			pending += days
+		}
+	}
+	return pending
+}
+
+// checkOverlap mirrors Database.checkOverlap. Callers hold m.mu.
+func (m *MemoryStore) checkOverlap(leave *models.Leave, statuses ...constants.LeaveStatus) error {
+	var clashing []string
+	for _, l := range m.leaves {
+		if l.UserID != leave.UserID || l.ID == leave.ID {
+			continue
+		}
+		for _, status := range statuses {
+			if l.Status == string(status) && leavesOverlap(leave, l) {
+				clashing = append(clashing, l.ID)
+				break
+			}
+		}
+	}
+	if len(clashing) > 0 {
+		sort.Strings(clashing)
+		return &OverlapError{LeaveIDs: clashing}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetLeaveBalances(userID string, year int) (map[string]models.LeaveBalance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	balances := make(map[string]models.LeaveBalance)
+	for code, leaveType := range m.leaveTypes {
+		if !leaveType.CountsAgainstBalance {
+			continue
+		}
+		if code == string(constants.LeaveTypeCompOff) {
+			balances[code] = m.compOffBalance(userID, today())
+			continue
+		}
+		allowance := m.allowance(user, leaveType, year)
+		_, carried := m.accruedDays(userID, code, year)
+		used := m.usedDays(userID, code, year)
+		pending := m.pendingDays(userID, code, year)
+		balances[code] = models.LeaveBalance{
+			Allowance:      allowance,
+			CarriedForward: carried,
+			Used:           used,
+			Pending:        pending,
+			Remaining:      allowance - used - pending,
+		}
+	}
+	return balances, nil
+}
+
+func (m *MemoryStore) CreateLeave(actorID string, leave *models.Leave) error {
+	days, err := leaveDuration(m.Calendar, leave)
+	if err != nil {
+		return err
+	}
+	if days == 0 {
+		return ErrNoWorkingDays
+	}
+	leave.WorkingDays = days
+	year, err := chargeYear(leave)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[leave.UserID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	leaveType, ok := m.leaveTypes[leave.Type]
+	if !ok {
+		return ErrUnknownLeaveType
+	}
+	if err := checkConsecutiveDays(&leaveType, leave); err != nil {
+		return err
+	}
+	if leaveType.CountsAgainstBalance {
+		remaining := m.allowance(user, leaveType, year) - m.usedDays(leave.UserID, leave.Type, year) - m.pendingDays(leave.UserID, leave.Type, year)
+		if isCompOff(leave) {
+			remaining = m.spendableDays(leave) - m.pendingCompOffDays(leave.UserID, leave.ID)
+		}
+		if days > remaining {
+			return &InsufficientBalanceError{Type: leave.Type, Requested: days, Remaining: remaining}
+		}
+	}
+	if err := m.checkOverlap(leave, constants.LeaveStatusPending, constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested); err != nil {
+		return err
+	}
+
+	leave.ApproverID = copyString(user.ManagerID)
+	leave.ID = uuid.New().String()
+	if leave.CreatedAt.IsZero() {
+		leave.CreatedAt = time.Now()
+	}
+
+	steps := approval.Resolve(m.policies, leave.Type, days)
+	leave.CurrentStep = &steps[0]
+	restartWait(leave, time.Now())
+	chain := make([]models.LeaveApproval, len(steps))
+	for i, role := range steps {
+		chain[i] = models.LeaveApproval{Step: i, Role: role, Decision: string(constants.LeaveStatusPending)}
+	}
+	m.approvals[leave.ID] = chain
+	if err := m.recordAudit(actorID, constants.AuditLeaveCreate, leave.ID, nil, leave); err != nil {
+		return err
+	}
+	if err := m.enqueueWebhooks(constants.WebhookLeaveCreated, leave); err != nil {
+		return err
+	}
+
+	stored := *leave
+	m.leaves[leave.ID] = &stored
+	m.analyticsGen++
+	return nil
+}
+
+// leaveCopy returns a detached copy with the derived fields filled in.
+// Callers hold m.mu.
+func (m *MemoryStore) leaveCopy(l *models.Leave) models.Leave {
+	leave := *l
+	if u, ok := m.users[l.UserID]; ok {
+		leave.UserEmail = u.Email
+	}
+	if days, err := leaveDuration(m.Calendar, &leave); err == nil {
+		leave.WorkingDays = days
+	}
+	return leave
+}
+
+func (m *MemoryStore) listLeaves(match func(*models.Leave) bool) []models.Leave {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leaves := make([]models.Leave, 0)
+	for _, l := range m.leaves {
+		if match(l) {
+			leaves = append(leaves, m.leaveCopy(l))
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		if !leaves[i].CreatedAt.Equal(leaves[j].CreatedAt) {
+			return leaves[i].CreatedAt.After(leaves[j].CreatedAt)
+		}
+		return leaves[i].ID < leaves[j].ID
+	})
+	return leaves
+}
+
+func (m *MemoryStore) ListLeaves(filter models.LeaveFilter) (*models.LeavePage, error) {
+	from, to, err := leaveRange(filter)
+	if err != nil {
+		return nil, err
+	}
+	sortBy := leaveSort(filter)
+	field, desc := sortField(sortBy)
+	cursor, err := decodeCursor(filter.Cursor, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := m.listLeaves(func(l *models.Leave) bool {
+		switch {
+		case filter.Status != "" && l.Status != filter.Status,
+			filter.Type != "" && l.Type != filter.Type,
+			filter.UserID != "" && l.UserID != filter.UserID,
+			from != "" && sqlDate(l.EndDate) < from,
+			to != "" && sqlDate(l.StartDate) > to:
+			return false
+		}
+		return cursor.after(leaveSortKey(l, field), l.ID, desc)
+	})
+	sort.Slice(leaves, func(i, j int) bool {
+		return keyBefore(leaveSortKey(&leaves[i], field), leaves[i].ID, leaveSortKey(&leaves[j], field), leaves[j].ID, desc)
+	})
+
+	page := &models.LeavePage{}
+	page.Items, page.NextCursor = trimPage(leaves, pageLimit(filter.Limit), sortBy, func(l *models.Leave) (string, string) {
+		return leaveSortKey(l, field), l.ID
+	})
+	return page, nil
+}
+
+func (m *MemoryStore) GetTeamLeaves(approverID string) ([]models.Leave, error) {
+	return m.listLeaves(func(l *models.Leave) bool { return l.ApproverID != nil && *l.ApproverID == approverID }), nil
+}
+
+func (m *MemoryStore) GetLeaveByID(leaveID string) (*models.Leave, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.leaves[leaveID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	leave := m.leaveCopy(l)
+	leave.Approvals = append([]models.LeaveApproval(nil), m.approvals[leaveID]...)
+	leave.Attachments = append([]models.Attachment(nil), m.attachments[leaveID]...)
+	return &leave, nil
+}
+
+// chargedDays is the net ledger charge for a leave. Callers hold m.mu.
+func (m *MemoryStore) chargedDays(leaveID string) float64 {
+	var days float64
+	for _, e := range m.ledger {
+		if e.LeaveID == leaveID {
+			days += e.Days
+		}
+	}
+	return days
+}
+
+// restoreLeave reverses the ledger charge for a leave. Callers hold m.mu.
+func (m *MemoryStore) restoreLeave(leave *models.Leave) {
+	charged := m.chargedDays(leave.ID)
+	if charged == 0 {
+		return
+	}
+	year, _ := leaveYear(leave.StartDate)
+	m.compOffUsage = slices.DeleteFunc(m.compOffUsage, func(u compOffUsage) bool { return u.LeaveID == leave.ID })
+	m.ledger = append(m.ledger, ledgerEntry{UserID: leave.UserID, LeaveID: leave.ID, LeaveType: leave.Type, Year: year, Days: -charged})
+}
+
+func (m *MemoryStore) UpdateLeaveStatus(actorID string, leaveID string, status string, comment *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leave, ok := m.leaves[leaveID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	return m.setLeaveStatus(actorID, leave, status, comment)
+}
+
+// setLeaveStatus mirrors Database.setLeaveStatus. Callers hold m.mu.
+func (m *MemoryStore) setLeaveStatus(actorID string, leave *models.Leave, status string, comment *string) error {
+	if err := checkTransition(leave.Status, status); err != nil {
+		return err
+	}
+	if leave.Status == string(constants.LeaveStatusPending) && status == string(constants.LeaveStatusApproved) {
+		days, err := leaveDuration(m.Calendar, leave)
+		if err != nil {
+			return err
+		}
+		if m.leaveTypes[leave.Type].NeedsAttachment(days) && len(m.attachments[leave.ID]) == 0 {
+			return ErrAttachmentRequired
+		}
+	}
+
+	switch {
+	case chargesBalance(status) && !chargesBalance(leave.Status):
+		days, err := leaveDuration(m.Calendar, leave)
+		if err != nil {
+			return err
+		}
+		year, err := leaveYear(leave.StartDate)
+		if err != nil {
+			return err
+		}
+		user, ok := m.users[leave.UserID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		leaveType, ok := m.leaveTypes[leave.Type]
+		if !ok {
+			return ErrUnknownLeaveType
+		}
+		var usage []compOffUsage
+		switch {
+		case leaveType.CountsAgainstBalance && isCompOff(leave):
+			if usage, err = drawCompOff(leave, m.spendableCredits(leave), days); err != nil {
+				return err
+			}
+		case leaveType.CountsAgainstBalance:
+			if remaining := m.allowance(user, leaveType, year) - m.usedDays(leave.UserID, leave.Type, year); days > remaining {
+				return &InsufficientBalanceError{Type: leave.Type, Requested: days, Remaining: remaining}
+			}
+		}
+		if err := m.checkOverlap(leave, constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested); err != nil {
+			return err
+		}
+		m.compOffUsage = append(m.compOffUsage, usage...)
+		if leaveType.CountsAgainstBalance {
+			m.ledger = append(m.ledger, ledgerEntry{UserID: leave.UserID, LeaveID: leave.ID, LeaveType: leave.Type, Year: year, Days: days})
+		}
+	case !chargesBalance(status) && chargesBalance(leave.Status):
+		m.restoreLeave(leave)
+	}
+
+	after := *leave
+	after.Status = status
+	after.ApproverComment = comment
+	after.CurrentStep = copyString(stepFor(leave, status))
+	after.Sequence = leave.Sequence + 1
+	restartWait(&after, time.Now())
+	if err := m.recordAudit(actorID, constants.AuditLeaveStatus, leave.ID, leave, after); err != nil {
+		return err
+	}
+	if event, ok := webhookEventFor(leave.Status, status); ok {
+		if err := m.enqueueWebhooks(event, &after); err != nil {
+			return err
+		}
+	}
+	*leave = after
+	m.analyticsGen++
+	return nil
+}
+
+func (m *MemoryStore) DeleteLeave(actorID string, leaveID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leave, ok := m.leaves[leaveID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := m.recordAudit(actorID, constants.AuditLeaveDelete, leaveID, leave, nil); err != nil {
+		return err
+	}
+	if err := m.enqueueWebhooks(constants.WebhookLeaveDeleted, leave); err != nil {
+		return err
+	}
+	m.restoreLeave(leave)
+	delete(m.leaves, leaveID)
+	delete(m.approvals, leaveID)
+	delete(m.attachments, leaveID)
+	m.analyticsGen++
+	return nil
+}
+
+func (m *MemoryStore) GetApprovalPolicies() ([]models.ApprovalPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policies := append([]models.ApprovalPolicy{}, m.policies...)
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].LeaveType != policies[j].LeaveType {
+			return policies[i].LeaveType < policies[j].LeaveType
+		}
+		return policies[i].OverDays < policies[j].OverDays
+	})
+	return policies, nil
+}
+
+func (m *MemoryStore) ReplaceApprovalPolicies(actorID string, policies []models.ApprovalPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range policies {
+		policies[i].ID = uuid.New().String()
+	}
+	if err := m.recordAudit(actorID, constants.AuditApprovalPolicies, "", m.policies, policies); err != nil {
+		return err
+	}
+	m.policies = append([]models.ApprovalPolicy(nil), policies...)
+	return nil
+}
+
+func (m *MemoryStore) DecideLeave(leaveID string, actor *models.User, decision models.LeaveDecision) ([]models.StaffingWarning, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leave, ok := m.leaves[leaveID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delegators := m.activeDelegators(actor.ID, today())
+	if leave.Status == string(constants.LeaveStatusCancellationRequested) && !decision.AllSteps {
+		status, decider, err := decideCancellation(leave, actor, delegators, decision.Approve)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.setLeaveStatus(actor.ID, leave, status, decision.Comment); err != nil {
+			return nil, err
+		}
+		return nil, m.recordDelegatedDecision(actor, decider, leaveID, status)
+	}
+	if leave.Status != string(constants.LeaveStatusPending) {
+		return nil, ErrLeaveNotPending
+	}
+
+	chain := m.approvals[leaveID]
+	steps, decider, err := stepsToDecide(leave, chain, actor, delegators, decision.AllSteps)
+	if err != nil {
+		return nil, err
+	}
+	last := steps[len(steps)-1]
+
+	status := constants.LeaveStatusRejected
+	var warnings []models.StaffingWarning
+	if decision.Approve {
+		status = constants.LeaveStatusApproved
+		if last == len(chain)-1 {
+			warnings = m.staffingWarnings(leave)
+			if err := confirmStaffing(warnings, decision.Confirm); err != nil {
+				return nil, err
+			}
+		}
+	}
+	now := time.Now()
+	if !decision.Approve || last == len(chain)-1 {
+		// Only the final approval charges the balance, and it may still fail
+		if err := m.setLeaveStatus(actor.ID, leave, string(status), decision.Comment); err != nil {
+			return nil, err
+		}
+	} else {
+		after := *leave
+		after.CurrentStep = copyString(&chain[last+1].Role)
+		restartWait(&after, now)
+		if err := m.recordAudit(actor.ID, constants.AuditLeaveStep, leaveID, leave, after); err != nil {
+			return nil, err
+		}
+		*leave = after
+	}
+
+	for _, i := range steps {
+		step := &chain[i]
+		step.Decision = string(status)
+		step.ApproverID = copyString(&actor.ID)
+		step.OnBehalfOfID = onBehalfOf(actor, decider)
+		step.Comment = copyString(decision.Comment)
+		step.DecidedAt = &now
+	}
+	if err := m.recordDelegatedDecision(actor, decider, leaveID, string(status)); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+func (m *MemoryStore) GetPendingApprovals(user *models.User) ([]models.Leave, error) {
+	m.mu.Lock()
+	delegators := m.activeDelegators(user.ID, today())
+	m.mu.Unlock()
+	leaves := m.listLeaves(func(l *models.Leave) bool {
+		if !awaitingDecision(l) {
+			return false
+		}
+		_, ok := approval.Decider(user, delegators, l, *l.CurrentStep)
+		return ok
+	})
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].CreatedAt.Before(leaves[j].CreatedAt) })
+	return leaves, nil
+}
+
+func (m *MemoryStore) GetAuditEvents(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	from, to, err := auditRange(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := make([]models.AuditEvent, 0)
+	for i := len(m.audit) - 1; i >= 0 && len(events) < auditLimit(filter); i-- {
+		e := m.audit[i]
+		actorID := ""
+		if e.ActorID != nil {
+			actorID = *e.ActorID
+		}
+		switch {
+		case filter.ActorID != "" && filter.ActorID != actorID,
+			filter.Action != "" && filter.Action != e.Action,
+			filter.EntityType != "" && filter.EntityType != e.EntityType,
+			filter.EntityID != "" && filter.EntityID != e.EntityID,
+			!from.IsZero() && e.CreatedAt.Before(from),
+			!to.IsZero() && !e.CreatedAt.Before(to):
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (m *MemoryStore) GetAccrualPolicies() ([]models.AccrualPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return sortedAccrualPolicies(m.accrualPolicies), nil
+}
+
+func (m *MemoryStore) ReplaceAccrualPolicies(actorID string, policies []models.AccrualPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.recordAudit(actorID, constants.AuditAccrualPolicies, "", sortedAccrualPolicies(m.accrualPolicies), policies); err != nil {
+		return err
+	}
+	m.accrualPolicies = make(map[string]models.AccrualPolicy)
+	for _, p := range policies {
+		m.accrualPolicies[p.LeaveType] = p
+	}
+	return nil
+}
+
+// insertAccrual mirrors the database function of the same name. Callers
+// hold m.mu.
+func (m *MemoryStore) insertAccrual(entry accrualEntry) bool {
+	for _, e := range m.accruals {
+		if e.UserID == entry.UserID && e.LeaveType == entry.LeaveType && e.Year == entry.Year && e.Kind == entry.Kind && e.Period == entry.Period {
+			return false
+		}
+	}
+	m.accruals = append(m.accruals, entry)
+	return true
+}
+
+func (m *MemoryStore) RunAccruals(actorID string, asOf time.Time) (*models.AccrualRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := &models.AccrualRun{AsOf: asOf.Format(workdays.DateLayout)}
+	year := asOf.Year()
+
+	users := make([]*models.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	for _, user := range users {
+		effective := m.effectiveAllowance(user)
+		for _, p := range sortedAccrualPolicies(m.accrualPolicies) {
+			leaveType, ok := m.leaveTypes[p.LeaveType]
+			if !ok || !leaveType.CountsAgainstBalance {
+				continue
+			}
+			annual := leaveType.AllowanceFrom(effective.Allowances)
+			m.rollover(user, p, year, run)
+			for _, credit := range accrual.Due(constants.AccrualFrequency(p.Frequency), float64(annual), user.CreatedAt, year, asOf) {
+				if m.insertAccrual(accrualEntry{UserID: user.ID, LeaveType: p.LeaveType, Year: year, Kind: accrualCredit, Period: credit.Period, Days: credit.Days}) {
+					run.Accrued += credit.Days
+				}
+			}
+		}
+	}
+
+	if run.Accrued != 0 || run.CarriedForward != 0 || run.Expired != 0 {
+		if err := m.recordAudit(actorID, constants.AuditAccrualRun, "", nil, run); err != nil {
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// rollover mirrors Database.rollover. Callers hold m.mu.
+func (m *MemoryStore) rollover(user *models.User, p models.AccrualPolicy, year int, run *models.AccrualRun) {
+	prev := year - 1
+	if prev < user.CreatedAt.Year() {
+		return
+	}
+	for _, e := range m.accruals {
+		if e.UserID == user.ID && e.LeaveType == p.LeaveType && e.Year == prev && e.Kind == accrualExpiry {
+			return
+		}
+	}
+
+	credited, _ := m.accruedDays(user.ID, p.LeaveType, prev)
+	remaining := credited - m.usedDays(user.ID, p.LeaveType, prev) - m.pendingDays(user.ID, p.LeaveType, prev)
+	carried, expired := accrual.CarryForward(remaining, p.CarryForwardCap)
+
+	m.insertAccrual(accrualEntry{UserID: user.ID, LeaveType: p.LeaveType, Year: prev, Kind: accrualExpiry, Days: -(carried + expired)})
+	if carried > 0 {
+		m.insertAccrual(accrualEntry{UserID: user.ID, LeaveType: p.LeaveType, Year: year, Kind: accrualCarryForward, Days: carried})
+	}
+	run.CarriedForward += carried
+	run.Expired += expired
+}
+
+// effectiveAllowance mirrors the database function of the same name.
+// Callers hold m.mu.
+func (m *MemoryStore) effectiveAllowance(user *models.User) *models.EffectivePolicy {
+	var matches []policyMatch
+	for _, p := range m.allowancePolicies {
+		for _, userID := range p.Users {
+			if userID == user.ID {
+				matches = append(matches, policyMatch{Policy: *p})
+			}
+		}
+		for _, group := range p.Groups {
+			for _, member := range m.userGroups[user.ID] {
+				if group == member {
+					matches = append(matches, policyMatch{Policy: *p, Group: group})
+				}
+			}
+		}
+	}
+	return resolvePolicy(user, matches)
+}
+
+func (m *MemoryStore) GetEffectivePolicy(userID string) (*models.EffectivePolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return m.effectiveAllowance(user), nil
+}
+
+func (m *MemoryStore) GetAllowancePolicies() ([]models.AllowancePolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policies := make([]models.AllowancePolicy, 0, len(m.allowancePolicies))
+	for _, p := range m.allowancePolicies {
+		policies = append(policies, *p)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Priority != policies[j].Priority {
+			return policies[i].Priority > policies[j].Priority
+		}
+		return policies[i].Name < policies[j].Name
+	})
+	return policies, nil
+}
+
+func (m *MemoryStore) CreateAllowancePolicy(actorID string, policy *models.AllowancePolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.allowancePolicies {
+		if p.Name == policy.Name {
+			return ErrDuplicatePolicy
+		}
+	}
+	policy.ID = uuid.New().String()
+	policy.Users, policy.Groups = []string{}, []string{}
+	if err := m.recordAudit(actorID, constants.AuditAllowancePolicyCreate, policy.ID, nil, policy); err != nil {
+		return err
+	}
+	stored := *policy
+	m.allowancePolicies[policy.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) AssignAllowancePolicy(actorID string, policyID string, userIDs []string, groups []string) (*models.AllowancePolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policy, ok := m.allowancePolicies[policyID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	for _, userID := range userIDs {
+		if _, ok := m.users[userID]; !ok {
+			return nil, ErrUnknownUser
+		}
+	}
+
+	after := *policy
+	after.Users, after.Groups = sortedUnique(userIDs), sortedUnique(groups)
+	if err := m.recordAudit(actorID, constants.AuditAllowancePolicyAssign, policyID, policy, after); err != nil {
+		return nil, err
+	}
+
+	// Each user and group keeps a single policy
+	for _, other := range m.allowancePolicies {
+		other.Users = without(other.Users, after.Users)
+		other.Groups = without(other.Groups, after.Groups)
+	}
+	*policy = after
+	return &after, nil
+}
+
+func without(values, remove []string) []string {
+	kept := make([]string, 0, len(values))
+	for _, v := range values {
+		if !slices.Contains(remove, v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func (m *MemoryStore) SetUserGroups(actorID string, userID string, groups []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	before := append([]string{}, m.userGroups[userID]...)
+	after := sortedUnique(groups)
+	if err := m.recordAudit(actorID, constants.AuditUserGroups, userID, map[string][]string{"groups": before}, map[string][]string{"groups": after}); err != nil {
+		return nil, err
+	}
+	m.userGroups[userID] = after
+	return after, nil
+}
+
+func (m *MemoryStore) GetLeaveTypes() ([]models.LeaveType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	types := make([]models.LeaveType, 0, len(m.leaveTypes))
+	for _, t := range m.leaveTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Code < types[j].Code })
+	return types, nil
+}
+
+func (m *MemoryStore) CreateLeaveType(actorID string, leaveType *models.LeaveType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.leaveTypes[leaveType.Code]; ok {
+		return ErrDuplicateLeaveType
+	}
+	if err := m.recordAudit(actorID, constants.AuditLeaveTypeCreate, leaveType.Code, nil, leaveType); err != nil {
+		return err
+	}
+	m.leaveTypes[leaveType.Code] = *leaveType
+	return nil
+}
+
+func (m *MemoryStore) UpdateLeaveType(actorID string, code string, settings models.LeaveTypeSettings) (*models.LeaveType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.leaveTypes[code]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	after := models.LeaveType{Code: code, LeaveTypeSettings: settings}
+	if err := m.recordAudit(actorID, constants.AuditLeaveTypeUpdate, code, before, after); err != nil {
+		return nil, err
+	}
+	m.leaveTypes[code] = after
+	return &after, nil
+}
+
+func (m *MemoryStore) DeleteLeaveType(actorID string, code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.leaveTypes[code]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	for _, l := range m.leaves {
+		if l.Type == code {
+			return ErrLeaveTypeInUse
+		}
+	}
+	if err := m.recordAudit(actorID, constants.AuditLeaveTypeDelete, code, before, nil); err != nil {
+		return err
+	}
+	delete(m.leaveTypes, code)
+	return nil
+}
+
+// Holidays implements workdays.HolidaySource
+func (m *MemoryStore) Holidays() (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.holidays), nil
+}
+
+func (m *MemoryStore) GetHolidays() ([]models.Holiday, error) {
+	return listHolidays(m.Calendar, m)
+}
+
+func (m *MemoryStore) SetHoliday(actorID string, holiday models.Holiday) error {
+	if err := holidaysEditable(m.Calendar, m); err != nil {
+		return err
+	}
+	if err := holidayDate(holiday.Date); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	var before *models.Holiday
+	if name, ok := m.holidays[holiday.Date]; ok {
+		before = &models.Holiday{Date: holiday.Date, Name: name}
+	}
+	if err := m.recordAudit(actorID, constants.AuditHolidaySet, holiday.Date, before, holiday); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.holidays[holiday.Date] = holiday.Name
+	m.analyticsGen++
+	m.mu.Unlock()
+	return m.Calendar.Load(m)
+}
+
+func (m *MemoryStore) DeleteHoliday(actorID string, date string) error {
+	if err := holidaysEditable(m.Calendar, m); err != nil {
+		return err
+	}
+	if err := holidayDate(date); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	name, ok := m.holidays[date]
+	if !ok {
+		m.mu.Unlock()
+		return sql.ErrNoRows
+	}
+	if err := m.recordAudit(actorID, constants.AuditHolidayDelete, date, models.Holiday{Date: date, Name: name}, nil); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	delete(m.holidays, date)
+	m.analyticsGen++
+	m.mu.Unlock()
+	return m.Calendar.Load(m)
+}
+
+func (m *MemoryStore) AddAttachment(actorID string, attachment *models.Attachment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.leaves[attachment.LeaveID]; !ok {
+		return sql.ErrNoRows
+	}
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+	if err := m.recordAudit(actorID, constants.AuditLeaveAttach, attachment.LeaveID, nil, attachment); err != nil {
+		return err
+	}
+	m.attachments[attachment.LeaveID] = append(m.attachments[attachment.LeaveID], *attachment)
+	return nil
+}
+
+func (m *MemoryStore) GetAttachment(leaveID, attachmentID string) (*models.Attachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.attachments[leaveID] {
+		if a.ID == attachmentID {
+			attachment := a
+			return &attachment, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// revokeCalendarToken drops userID's feed token, reporting whether there
+// was one. Callers hold m.mu.
+func (m *MemoryStore) revokeCalendarToken(userID string) bool {
+	for hash, owner := range m.calendarTokens {
+		if owner == userID {
+			delete(m.calendarTokens, hash)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) RotateCalendarToken(actorID string, userID string) (string, error) {
+	token, hash, err := newCalendarToken()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return "", sql.ErrNoRows
+	}
+	existed := m.revokeCalendarToken(userID)
+	if err := m.recordAudit(actorID, constants.AuditUserCalendar, userID, calendarTokenAudit{Active: existed}, calendarTokenAudit{Active: true}); err != nil {
+		return "", err
+	}
+	m.calendarTokens[hash] = userID
+	return token, nil
+}
+
+func (m *MemoryStore) RevokeCalendarToken(actorID string, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.revokeCalendarToken(userID) {
+		return nil
+	}
+	return m.recordAudit(actorID, constants.AuditUserCalendar, userID, calendarTokenAudit{Active: true}, calendarTokenAudit{Active: false})
+}
+
+func (m *MemoryStore) GetUserByCalendarToken(token string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[m.calendarTokens[hashCalendarToken(token)]]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := *u
+	return &user, nil
+}
+
+func (m *MemoryStore) GetCalendarLeaves(owner *models.User, scope string, since string) ([]models.Leave, error) {
+	if err := checkCalendarScope(owner, scope); err != nil {
+		return nil, err
+	}
+
+	// listLeaves holds m.mu while matching
+	leaves := m.listLeaves(func(l *models.Leave) bool {
+		user, ok := m.users[l.UserID]
+		return ok && slices.Contains(calendarStatuses, l.Status) && sqlDate(l.EndDate) >= since &&
+			inCalendarScope(owner, user, constants.CalendarScope(scope))
+	})
+	sort.Slice(leaves, func(i, j int) bool {
+		if a, b := sqlDate(leaves[i].StartDate), sqlDate(leaves[j].StartDate); a != b {
+			return a < b
+		}
+		return leaves[i].ID < leaves[j].ID
+	})
+	return leaves, nil
+}
+
+// enqueueWebhooks mirrors the outbox insert. Callers hold m.mu.
+func (m *MemoryStore) enqueueWebhooks(event constants.WebhookEvent, leave *models.Leave) error {
+	payloadLeave := m.leaveCopy(leave)
+	now := time.Now()
+	for _, sub := range m.webhooks {
+		if sub.Disabled || !slices.Contains(sub.Events, string(event)) {
+			continue
+		}
+		d, err := newWebhookDelivery(sub.ID, event, &payloadLeave, now)
+		if err != nil {
+			return err
+		}
+		m.webhookDeliveries = append(m.webhookDeliveries, d)
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := make([]models.WebhookSubscription, 0, len(m.webhooks))
+	for _, sub := range m.webhooks {
+		subs = append(subs, redactSecret(*sub))
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs, nil
+}
+
+func (m *MemoryStore) CreateWebhookSubscription(actorID string, sub *models.WebhookSubscription) error {
+	if sub.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		sub.Secret = secret
+	}
+	sub.ID = uuid.New().String()
+	sub.Events = sortedUnique(sub.Events)
+	sub.CreatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.recordAudit(actorID, constants.AuditWebhookCreate, sub.ID, nil, redactSecret(*sub)); err != nil {
+		return err
+	}
+	stored := *sub
+	m.webhooks[sub.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) UpdateWebhookSubscription(actorID string, id string, sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.webhooks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	after := sub
+	after.ID, after.CreatedAt = before.ID, before.CreatedAt
+	after.Events = sortedUnique(sub.Events)
+	if after.Secret == "" {
+		after.Secret = before.Secret
+	}
+	if err := m.recordAudit(actorID, constants.AuditWebhookUpdate, id, redactSecret(*before), redactSecret(after)); err != nil {
+		return nil, err
+	}
+	m.webhooks[id] = &after
+	result := redactSecret(after)
+	return &result, nil
+}
+
+func (m *MemoryStore) DeleteWebhookSubscription(actorID string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.webhooks[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := m.recordAudit(actorID, constants.AuditWebhookDelete, id, redactSecret(*before), nil); err != nil {
+		return err
+	}
+	delete(m.webhooks, id)
+	m.webhookDeliveries = slices.DeleteFunc(m.webhookDeliveries, func(d *models.WebhookDelivery) bool { return d.SubscriptionID == id })
+	return nil
+}
+
+func (m *MemoryStore) GetWebhookDeliveries(subscriptionID string, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[subscriptionID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, d := range m.webhookDeliveries {
+		if d.SubscriptionID == subscriptionID && (filter.Status == "" || d.Status == filter.Status) {
+			deliveries = append(deliveries, *d)
+		}
+	}
+	sortedWebhookDeliveries(deliveries)
+	if limit := pageLimit(filter.Limit); len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (m *MemoryStore) ClaimWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []*models.WebhookDelivery
+	for _, d := range m.webhookDeliveries {
+		sub, ok := m.webhooks[d.SubscriptionID]
+		if ok && !sub.Disabled && d.Status == string(constants.WebhookStatusPending) && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimedUntil := now.Add(constants.WebhookClaimSeconds * time.Second)
+	claimed := make([]models.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		c := *d
+		c.URL, c.Secret = m.webhooks[d.SubscriptionID].URL, m.webhooks[d.SubscriptionID].Secret
+		claimed = append(claimed, c)
+		d.NextAttemptAt = &claimedUntil
+	}
+	return claimed, nil
+}
+
+func (m *MemoryStore) RecordWebhookAttempt(deliveryID string, statusCode int, errMsg string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.webhookDeliveries {
+		if d.ID == deliveryID {
+			webhookOutcome(d, statusCode, errMsg, at)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetStepApprovers(leave *models.Leave) ([]models.User, error) {
+	userID, role := stepDeciders(leave)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := make([]models.User, 0)
+	for _, u := range m.users {
+		if u.ID == leave.UserID {
+			continue
+		}
+		if (userID != nil && u.ID == *userID) || (userID == nil && role != "" && u.Role == role) {
+			users = append(users, *u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+
+	ids := make([]string, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	return withDelegates(users, m.activeDelegates(ids, today()), leave.UserID), nil
+}
+
+func (m *MemoryStore) GetNotificationPreferences(userID string) (*models.NotificationPreferences, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefs, ok := m.notificationPrefs[userID]
+	if !ok {
+		prefs = defaultNotificationPreferences
+	}
+	return &prefs, nil
+}
+
+func (m *MemoryStore) UpdateNotificationPreferences(actorID string, userID string, prefs models.NotificationPreferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	before, ok := m.notificationPrefs[userID]
+	if !ok {
+		before = defaultNotificationPreferences
+	}
+	if err := m.recordAudit(actorID, constants.AuditUserNotify, userID, before, prefs); err != nil {
+		return err
+	}
+	m.notificationPrefs[userID] = prefs
+	return nil
+}
+
+func (m *MemoryStore) AcquireLease(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease, ok := m.leases[name]; ok && lease.Holder != holder && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	m.leases[name] = jobLease{Holder: holder, ExpiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *MemoryStore) GetAwaitingLeaves() ([]models.Leave, error) {
+	leaves := m.listLeaves(awaitingDecision)
+	sort.SliceStable(leaves, func(i, j int) bool {
+		return leaves[i].AwaitingSince != nil && (leaves[j].AwaitingSince == nil || leaves[i].AwaitingSince.Before(*leaves[j].AwaitingSince))
+	})
+	return leaves, nil
+}
+
+func (m *MemoryStore) MarkLeaveReminded(leaveID string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leave, ok := m.leaves[leaveID]
+	if !ok || leave.RemindedAt != nil || leave.CurrentStep == nil {
+		return false, nil
+	}
+	leave.RemindedAt = &at
+	return true, nil
+}
+
+func (m *MemoryStore) EscalateLeave(leaveID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leave, ok := m.leaves[leaveID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if !awaitingDecision(leave) || leave.EscalatedAt != nil {
+		return ErrLeaveNotPending
+	}
+	var next *string
+	if leave.ApproverID != nil {
+		if approver, ok := m.users[*leave.ApproverID]; ok {
+			next = copyString(approver.ManagerID)
+		}
+	}
+
+	after := escalate(leave, next, at)
+	if err := m.recordAudit("", constants.AuditLeaveEscalate, leaveID, leave, after); err != nil {
+		return err
+	}
+	*leave = after
+	return nil
+}
+
+func (m *MemoryStore) AutoApproveLeave(leaveID string, comment string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leave, ok := m.leaves[leaveID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if leave.Status != string(constants.LeaveStatusPending) {
+		return ErrLeaveNotPending
+	}
+	if err := m.setLeaveStatus("", leave, string(constants.LeaveStatusApproved), &comment); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range m.approvals[leaveID] {
+		step := &m.approvals[leaveID][i]
+		if step.Decision == string(constants.LeaveStatusPending) {
+			step.Decision = string(constants.LeaveStatusApproved)
+			step.Comment = copyString(&comment)
+			step.DecidedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetTeamAvailability(managerID string, from, to string) (*models.TeamAvailability, error) {
+	start, end, err := availabilityRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[managerID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return m.teamAvailability(managerID, start, end, nil), nil
+}
+
+func (m *MemoryStore) GetStaffingWarnings(leave *models.Leave) ([]models.StaffingWarning, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[leave.UserID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return m.staffingWarnings(leave), nil
+}
+
+// staffingWarnings mirrors Database.staffingWarnings. Callers hold m.mu.
+func (m *MemoryStore) staffingWarnings(leave *models.Leave) []models.StaffingWarning {
+	owner, ok := m.users[leave.UserID]
+	if !ok || owner.ManagerID == nil {
+		return []models.StaffingWarning{}
+	}
+	start, _ := parseDate(leave.StartDate)
+	end, _ := parseDate(leave.EndDate)
+	return staffingWarnings(m.teamAvailability(*owner.ManagerID, start, end, leave))
+}
+
+// teamAvailability mirrors Database.teamAvailability. Callers hold m.mu.
+func (m *MemoryStore) teamAvailability(managerID string, start, end time.Time, project *models.Leave) *models.TeamAvailability {
+	from, to := start.Format(workdays.DateLayout), end.Format(workdays.DateLayout)
+	members := make([]models.User, 0)
+	for _, u := range m.users {
+		if u.ManagerID != nil && *u.ManagerID == managerID {
+			members = append(members, *u)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Email < members[j].Email })
+
+	leaves := make([]models.Leave, 0)
+	for _, l := range m.leaves {
+		owner, ok := m.users[l.UserID]
+		if ok && owner.ManagerID != nil && *owner.ManagerID == managerID && slices.Contains(availabilityStatuses, l.Status) &&
+			sqlDate(l.StartDate) <= to && sqlDate(l.EndDate) >= from {
+			leaves = append(leaves, m.leaveCopy(l))
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		if a, b := sqlDate(leaves[i].StartDate), sqlDate(leaves[j].StartDate); a != b {
+			return a < b
+		}
+		return leaves[i].ID < leaves[j].ID
+	})
+	if project != nil {
+		leaves = projectApproval(leaves, project)
+	}
+	return buildAvailability(m.Calendar, managerID, start, end, members, leaves, m.minStaffing[managerID])
+}
+
+func (m *MemoryStore) SetMinStaffing(actorID string, managerID string, minStaffing int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	manager, ok := m.users[managerID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if !canManage(manager.Role) {
+		return ErrInvalidManager
+	}
+	if err := m.recordAudit(actorID, constants.AuditUserStaffing, managerID, staffingAudit{m.minStaffing[managerID]}, staffingAudit{minStaffing}); err != nil {
+		return err
+	}
+	if minStaffing == 0 {
+		delete(m.minStaffing, managerID)
+	} else {
+		m.minStaffing[managerID] = minStaffing
+	}
+	return nil
+}
+
+// activeDelegators mirrors the function of the same name. Callers hold
+// m.mu.
+func (m *MemoryStore) activeDelegators(delegateID string, date string) []models.User {
+	delegators := make([]models.User, 0)
+	for _, d := range m.delegations {
+		if u, ok := m.users[d.DelegatorID]; ok && d.DelegateID == delegateID && d.StartDate <= date && d.EndDate >= date &&
+			!slices.ContainsFunc(delegators, func(v models.User) bool { return v.ID == u.ID }) {
+			delegators = append(delegators, *u)
+		}
+	}
+	sort.Slice(delegators, func(i, j int) bool { return delegators[i].Email < delegators[j].Email })
+	return delegators
+}
+
+// activeDelegates mirrors the function of the same name. Callers hold
+// m.mu.
+func (m *MemoryStore) activeDelegates(delegatorIDs []string, date string) []models.User {
+	delegates := make([]models.User, 0)
+	for _, d := range m.delegations {
+		if u, ok := m.users[d.DelegateID]; ok && slices.Contains(delegatorIDs, d.DelegatorID) && d.StartDate <= date && d.EndDate >= date &&
+			!slices.ContainsFunc(delegates, func(v models.User) bool { return v.ID == u.ID }) {
+			delegates = append(delegates, *u)
+		}
+	}
+	sort.Slice(delegates, func(i, j int) bool { return delegates[i].Email < delegates[j].Email })
+	return delegates
+}
+
+// recordDelegatedDecision mirrors the function of the same name. Callers
+// hold m.mu.
+func (m *MemoryStore) recordDelegatedDecision(actor, decider *models.User, leaveID string, decision string) error {
+	if decider.ID == actor.ID {
+		return nil
+	}
+	return m.recordAudit(actor.ID, constants.AuditLeaveDelegated, leaveID, nil, delegatedDecision{DelegatorID: decider.ID, Decision: decision})
+}
+
+func (m *MemoryStore) CreateDelegation(actorID string, d *models.Delegation) error {
+	if err := checkDelegationDates(d); err != nil {
+		return err
+	}
+	if d.DelegateID == d.DelegatorID {
+		return ErrInvalidDelegation
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delegator, ok := m.users[d.DelegatorID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if !canApprove(delegator.Role) {
+		return ErrInvalidDelegation
+	}
+	delegate, ok := m.users[d.DelegateID]
+	if !ok {
+		return ErrInvalidDelegation
+	}
+
+	d.ID = uuid.New().String()
+	d.DelegatorEmail = delegator.Email
+	d.DelegateEmail = delegate.Email
+	d.CreatedAt = time.Now()
+	if err := m.recordAudit(actorID, constants.AuditDelegationCreate, d.ID, nil, d); err != nil {
+		return err
+	}
+	m.delegations = append(m.delegations, *d)
+	return nil
+}
+
+func (m *MemoryStore) GetActiveDelegators(userID string) ([]models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.activeDelegators(userID, today()), nil
+}
+
+func (m *MemoryStore) GetDelegations(userID string) ([]models.Delegation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delegations := make([]models.Delegation, 0)
+	for _, d := range m.delegations {
+		if d.DelegatorID == userID || d.DelegateID == userID {
+			d.DelegatorEmail, d.DelegateEmail = m.users[d.DelegatorID].Email, m.users[d.DelegateID].Email
+			delegations = append(delegations, d)
+		}
+	}
+	sort.Slice(delegations, func(i, j int) bool {
+		if delegations[i].StartDate != delegations[j].StartDate {
+			return delegations[i].StartDate > delegations[j].StartDate
+		}
+		return delegations[i].ID < delegations[j].ID
+	})
+	return delegations, nil
+}
+
+func (m *MemoryStore) DeleteDelegation(actorID string, delegatorID string, delegationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.delegations, func(d models.Delegation) bool { return d.ID == delegationID && d.DelegatorID == delegatorID })
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	before := m.delegations[i]
+	before.DelegatorEmail, before.DelegateEmail = "", ""
+	if err := m.recordAudit(actorID, constants.AuditDelegationDelete, delegationID, before, nil); err != nil {
+		return err
+	}
+	m.delegations = slices.Delete(m.delegations, i, i+1)
+	return nil
+}
+
+func (m *MemoryStore) StreamLeaveReport(filter models.LeaveReportFilter, emit func(models.LeaveReportRow) error) error {
+	from, to, err := leaveRange(models.LeaveFilter{From: filter.From, To: filter.To})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	rows := make([]models.LeaveReportRow, 0)
+	for _, l := range m.leaves {
+		switch {
+		case filter.Status != "" && l.Status != filter.Status,
+			filter.Type != "" && l.Type != filter.Type,
+			from != "" && sqlDate(l.EndDate) < from,
+			to != "" && sqlDate(l.StartDate) > to:
+			continue
+		}
+		approverID := l.ApproverID
+		for _, step := range m.approvals[l.ID] {
+			if step.DecidedAt != nil && step.ApproverID != nil {
+				approverID = step.ApproverID
+			}
+		}
+		row := models.LeaveReportRow{Leave: m.leaveCopy(l)}
+		if approverID != nil {
+			if u, ok := m.users[*approverID]; ok {
+				row.Approver = &u.Email
+			}
+		}
+		rows = append(rows, row)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := &rows[i].Leave, &rows[j].Leave
+		if a.StartDate != b.StartDate {
+			return a.StartDate < b.StartDate
+		}
+		if a.UserEmail != b.UserEmail {
+			return a.UserEmail < b.UserEmail
+		}
+		return a.ID < b.ID
+	})
+	for _, row := range rows {
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) StreamBalanceReport(filter models.BalanceReportFilter, emit func(models.BalanceReportRow) error) error {
+	m.mu.Lock()
+	users := make([]models.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, *u)
+	}
+	m.mu.Unlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+
+	year := reportYear(filter)
+	for _, u := range users {
+		balances, err := m.GetLeaveBalances(u.ID, year)
+		if err != nil {
+			return err
+		}
+		if err := emitBalances(u, balances, filter.Type, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userByEmail finds a user by email, or nil. Callers hold m.mu.
+func (m *MemoryStore) userByEmail(email string) *models.User {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ImportRecords(actorID string, batch *models.ImportBatch, dryRun bool) (*models.ImportResult, error) {
+	result := &models.ImportResult{DryRun: dryRun, Errors: make([]models.ImportRowError, 0)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Keep what the import changes so it can be put back, like a rollback
+	users, leaves, approvals := maps.Clone(m.users), maps.Clone(m.leaves), maps.Clone(m.approvals)
+	ledger, usage, audit := len(m.ledger), len(m.compOffUsage), len(m.audit)
+	rollback := func() {
+		m.users, m.leaves, m.approvals = users, leaves, approvals
+		m.ledger, m.compOffUsage, m.audit = m.ledger[:ledger], m.compOffUsage[:usage], m.audit[:audit]
+	}
+
+	imported := make([]*importedUser, 0, len(batch.Users))
+	for _, row := range batch.Users {
+		u := &importedUser{row: row}
+		if before := m.userByEmail(row.Email); before != nil {
+			b := *before
+			u.before = &b
+			u.after = b
+			result.UsersUpdated++
+		} else {
+			u.after = *newUser(row.Email)
+			u.after.CreatedAt = time.Now()
+			result.UsersCreated++
+		}
+		applyImportUser(&u.after, row)
+		after := u.after
+		m.users[after.ID] = &after
+		imported = append(imported, u)
+	}
+
+	for _, u := range imported {
+		if u.row.ManagerEmail == "" {
+			continue
+		}
+		manager := m.userByEmail(u.row.ManagerEmail)
+		if msg := checkImportManager(&u.after, manager); msg != "" {
+			result.Errors = append(result.Errors, importError("users", u.row.Row, "manager", msg))
+			continue
+		}
+		u.after.ManagerID = &manager.ID
+		m.users[u.after.ID].ManagerID = copyString(&manager.ID)
+	}
+	for _, u := range imported {
+		seen := map[string]bool{}
+		for next := m.users[u.after.ID].ManagerID; next != nil && !seen[*next]; {
+			if *next == u.after.ID {
+				result.Errors = append(result.Errors, importError("users", u.row.Row, "manager", "managers form a cycle"))
+				break
+			}
+			seen[*next] = true
+			next = m.users[*next].ManagerID
+		}
+		if err := m.recordAudit(actorID, constants.AuditUserImport, u.after.ID, u.before, u.after); err != nil {
+			rollback()
+			return nil, err
+		}
+	}
+
+	for _, row := range batch.Leaves {
+		user := m.userByEmail(row.Email)
+		if user == nil {
+			result.Errors = append(result.Errors, importError("leaves", row.Row, "email", "unknown user"))
+			continue
+		}
+		if err := m.importLeave(actorID, buildImportedLeave(row, user)); err != nil {
+			rowErr, ok := leaveImportError(row.Row, err)
+			if !ok {
+				rollback()
+				return nil, err
+			}
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+		result.Leaves++
+	}
+
+	if dryRun || len(result.Errors) > 0 {
+		rollback()
+		return result, nil
+	}
+	m.analyticsGen++
+	result.Applied = true
+	return result, nil
+}
+
+// importLeave mirrors Database.importLeave. Callers hold m.mu.
+func (m *MemoryStore) importLeave(actorID string, leave *models.Leave) error {
+	leaveType, ok := m.leaveTypes[leave.Type]
+	if !ok {
+		return ErrUnknownLeaveType
+	}
+	days, err := leaveDuration(m.Calendar, leave)
+	if err != nil {
+		return err
+	}
+	if days == 0 {
+		return ErrNoWorkingDays
+	}
+	leave.WorkingDays = days
+	year, err := chargeYear(leave)
+	if err != nil {
+		return err
+	}
+	if chargesBalance(leave.Status) {
+		if err := m.checkOverlap(leave, constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested); err != nil {
+			return err
+		}
+	}
+
+	leave.CreatedAt = time.Now()
+	leave.Approvals = importedApprovals(m.policies, leave)
+	if chargesBalance(leave.Status) && leaveType.CountsAgainstBalance {
+		if isCompOff(leave) {
+			usage, err := drawCompOff(leave, m.spendableCredits(leave), days)
+			if err != nil {
+				return err
+			}
+			m.compOffUsage = append(m.compOffUsage, usage...)
+		}
+		m.ledger = append(m.ledger, ledgerEntry{UserID: leave.UserID, LeaveID: leave.ID, LeaveType: leave.Type, Year: year, Days: days})
+	}
+	if err := m.recordAudit(actorID, constants.AuditLeaveImport, leave.ID, nil, leave); err != nil {
+		return err
+	}
+	if leave.Approvals != nil {
+		m.approvals[leave.ID] = append([]models.LeaveApproval(nil), leave.Approvals...)
+	}
+	stored := *leave
+	stored.Approvals = nil
+	m.leaves[leave.ID] = &stored
+	return nil
+}
+
+// creditCopy returns a detached copy of a credit with its email and
+// remaining days filled in. Callers hold m.mu.
+func (m *MemoryStore) creditCopy(c *models.CompOffCredit) models.CompOffCredit {
+	credit := *c
+	if u, ok := m.users[c.UserID]; ok {
+		credit.UserEmail = u.Email
+	}
+	credit.Remaining = credit.Days
+	for _, u := range m.compOffUsage {
+		if u.CreditID == c.ID {
+			credit.Remaining -= u.Days
+		}
+	}
+	return credit
+}
+
+// spendableCredits mirrors the function of the same name for a leave.
+// Callers hold m.mu.
+func (m *MemoryStore) spendableCredits(leave *models.Leave) []models.CompOffCredit {
+	date := sqlDate(leave.EndDate)
+	credits := make([]models.CompOffCredit, 0)
+	for _, c := range m.compOffCredits {
+		if c.UserID != leave.UserID || c.Status != string(constants.LeaveStatusApproved) || c.ExpiresOn == nil || *c.ExpiresOn < date {
+			continue
+		}
+		if credit := m.creditCopy(c); credit.Remaining > 0 {
+			credits = append(credits, credit)
+		}
+	}
+	sort.Slice(credits, func(i, j int) bool {
+		if credits[i].WorkDate != credits[j].WorkDate {
+			return credits[i].WorkDate < credits[j].WorkDate
+		}
+		return credits[i].ID < credits[j].ID
+	})
+	return credits
+}
+
+// spendableDays sums the credits a leave could be drawn from. Callers
+// hold m.mu.
+func (m *MemoryStore) spendableDays(leave *models.Leave) float64 {
+	var days float64
+	for _, c := range m.spendableCredits(leave) {
+		days += c.Remaining
+	}
+	return days
+}
+
+// pendingCompOffDays mirrors Database.pendingCompOffDays. Callers hold m.mu.
+func (m *MemoryStore) pendingCompOffDays(userID string, excludeLeaveID string) float64 {
+	var total float64
+	for _, l := range m.leaves {
+		if l.UserID == userID && isCompOff(l) && l.Status == string(constants.LeaveStatusPending) && l.ID != excludeLeaveID {
+			if days, err := leaveDuration(m.Calendar, l); err == nil {
+				total += days
+			}
+		}
+	}
+	return total
+}
+
+// compOffBalance mirrors Database.compOffBalance. Callers hold m.mu.
+func (m *MemoryStore) compOffBalance(userID string, date string) models.LeaveBalance {
+	var balance models.LeaveBalance
+	for _, c := range m.compOffCredits {
+		if c.UserID == userID && c.Status == string(constants.LeaveStatusApproved) && c.ExpiresOn != nil && *c.ExpiresOn >= date {
+			credit := m.creditCopy(c)
+			balance.Allowance += credit.Days
+			balance.Used += credit.Days - credit.Remaining
+		}
+	}
+	balance.Pending = m.pendingCompOffDays(userID, "")
+	balance.Remaining = balance.Allowance - balance.Used - balance.Pending
+	return balance
+}
+
+func (m *MemoryStore) CreateCompOffCredit(actorID string, credit *models.CompOffCredit) error {
+	if err := checkCompOffDate(m.Calendar, credit.WorkDate); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[credit.UserID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	for _, c := range m.compOffCredits {
+		if c.UserID == credit.UserID && c.WorkDate == credit.WorkDate && c.Status != string(constants.LeaveStatusRejected) {
+			return ErrDuplicateCompOff
+		}
+	}
+
+	credit.ID = uuid.New().String()
+	credit.UserEmail = user.Email
+	credit.Status = string(constants.LeaveStatusPending)
+	credit.ApproverID = copyString(user.ManagerID)
+	credit.Remaining = credit.Days
+	credit.CreatedAt = time.Now()
+	if err := m.recordAudit(actorID, constants.AuditCompOffRequest, credit.ID, nil, credit); err != nil {
+		return err
+	}
+	stored := *credit
+	m.compOffCredits[credit.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) GetCompOffCredit(creditID string) (*models.CompOffCredit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.compOffCredits[creditID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	credit := m.creditCopy(c)
+	return &credit, nil
+}
+
+func (m *MemoryStore) GetCompOffCredits(userID string) ([]models.CompOffCredit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credits := make([]models.CompOffCredit, 0)
+	for _, c := range m.compOffCredits {
+		if c.UserID == userID {
+			credits = append(credits, m.creditCopy(c))
+		}
+	}
+	sort.Slice(credits, func(i, j int) bool {
+		if credits[i].WorkDate != credits[j].WorkDate {
+			return credits[i].WorkDate > credits[j].WorkDate
+		}
+		return credits[i].ID < credits[j].ID
+	})
+	return credits, nil
+}
+
+func (m *MemoryStore) GetPendingCompOffCredits(user *models.User) ([]models.CompOffCredit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delegators := m.activeDelegators(user.ID, today())
+	credits := make([]models.CompOffCredit, 0)
+	for _, c := range m.compOffCredits {
+		if c.Status != string(constants.LeaveStatusPending) {
+			continue
+		}
+		if _, ok := creditDecider(user, delegators, c); ok {
+			credits = append(credits, m.creditCopy(c))
+		}
+	}
+	sort.Slice(credits, func(i, j int) bool { return credits[i].CreatedAt.Before(credits[j].CreatedAt) })
+	return credits, nil
+}
+
+func (m *MemoryStore) DecideCompOffCredit(creditID string, actor *models.User, approve bool, comment *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.compOffCredits[creditID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if c.Status != string(constants.LeaveStatusPending) {
+		return ErrCompOffNotPending
+	}
+	if _, ok := creditDecider(actor, m.activeDelegators(actor.ID, today()), c); !ok {
+		return ErrNotApprover
+	}
+
+	before := m.creditCopy(c)
+	after := before
+	after.Status = string(constants.LeaveStatusRejected)
+	if approve {
+		after.Status = string(constants.LeaveStatusApproved)
+		expires := compOffExpiry(before.WorkDate)
+		after.ExpiresOn = &expires
+	}
+	now := time.Now()
+	after.DecidedBy, after.DecidedAt, after.Comment = copyString(&actor.ID), &now, copyString(comment)
+	if err := m.recordAudit(actor.ID, constants.AuditCompOffDecide, creditID, before, after); err != nil {
+		return err
+	}
+	m.compOffCredits[creditID] = &after
+	return nil
+}
+
+func (m *MemoryStore) GetLeaveAnalytics(from, to string) (*models.LeaveAnalytics, error) {
+	start, end, err := analyticsRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := from + "/" + to
+	now := time.Now()
+	if cached := m.analytics.lookup(key, m.analyticsGen, now); cached != nil {
+		return cached, nil
+	}
+
+	users := make([]models.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, *u)
+	}
+	var leaves []models.Leave
+	for _, l := range m.leaves {
+		if slices.Contains(calendarStatuses, l.Status) && sqlDate(l.StartDate) <= to && sqlDate(l.EndDate) >= from {
+			leaves = append(leaves, m.leaveCopy(l))
+		}
+	}
+	analytics := buildAnalytics(m.Calendar, start, end, users, leaves)
+	bradfordStart, bradfordEnd := bradfordRange(now)
+	analytics.BradfordFrom, analytics.BradfordTo = bradfordStart.Format(workdays.DateLayout), bradfordEnd.Format(workdays.DateLayout)
+	var sick []models.Leave
+	for _, l := range m.leaves {
+		if l.Type == string(constants.LeaveTypeSick) && slices.Contains(calendarStatuses, l.Status) && sqlDate(l.EndDate) >= analytics.BradfordFrom && sqlDate(l.StartDate) <= analytics.BradfordTo {
+			sick = append(sick, m.leaveCopy(l))
+		}
+	}
+	analytics.Bradford = buildBradford(m.Calendar, bradfordStart, bradfordEnd, users, sick)
+
+	// Mirrors the GROUP BY type of approvalTurnaround and rejectionRates
+	type tally struct {
+		decided, rejected, timed int
+		waited, longest          float64
+	}
+	tallies := make(map[string]*tally)
+	until := end.AddDate(0, 0, 1)
+	for _, l := range m.leaves {
+		if !slices.Contains(decidedStatuses, l.Status) || l.CreatedAt.Before(start) || !l.CreatedAt.Before(until) {
+			continue
+		}
+		t := tallies[l.Type]
+		if t == nil {
+			t = &tally{}
+			tallies[l.Type] = t
+		}
+		t.decided++
+		if l.Status == string(constants.LeaveStatusRejected) {
+			t.rejected++
+		}
+		var decidedAt *time.Time
+		for _, step := range m.approvals[l.ID] {
+			if step.DecidedAt != nil && (decidedAt == nil || step.DecidedAt.After(*decidedAt)) {
+				decidedAt = step.DecidedAt
+			}
+		}
+		if decidedAt != nil {
+			waited := decidedAt.Sub(l.CreatedAt).Truncate(time.Second).Seconds()
+			t.timed++
+			t.waited += waited
+			t.longest = max(t.longest, waited)
+		}
+	}
+	analytics.Turnaround = make([]models.ApprovalTurnaround, 0)
+	analytics.Rejections = make([]models.RejectionRate, 0)
+	for _, leaveType := range slices.Sorted(maps.Keys(tallies)) {
+		t := tallies[leaveType]
+		if t.timed > 0 {
+			analytics.Turnaround = append(analytics.Turnaround, newTurnaround(leaveType, t.timed, t.waited/float64(t.timed), t.longest))
+		}
+		analytics.Rejections = append(analytics.Rejections, newRejectionRate(leaveType, t.decided, t.rejected))
+	}
+	analytics.GeneratedAt = now
+	m.analytics.store(key, m.analyticsGen, analytics)
+	return analytics, nil
+}
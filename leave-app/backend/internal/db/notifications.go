@@ -0,0 +1,123 @@
+// internal/db/notifications.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"slices"
+)
+
+// defaultNotificationPreferences apply to users who never changed them
+var defaultNotificationPreferences = models.NotificationPreferences{Email: true}
+
+// stepDeciders returns who is asked to decide a leave's current step:
+// either one user or everyone with a role. Admins can decide any step but
+// are only asked when nobody else is, or the step was escalated to them.
+func stepDeciders(leave *models.Leave) (userID *string, role string) {
+	if leave.CurrentStep == nil {
+		return nil, ""
+	}
+	if leave.EscalatedAt != nil {
+		return nil, string(constants.RoleAdmin)
+	}
+	switch constants.Role(*leave.CurrentStep) {
+	case constants.RoleManager:
+		if leave.ApproverID != nil {
+			return leave.ApproverID, ""
+		}
+	case constants.RoleHR:
+		return nil, string(constants.RoleHR)
+	}
+	return nil, string(constants.RoleAdmin)
+}
+
+// GetStepApprovers returns the users asked to decide the leave's current
+// step and anyone they delegated to today, never including its owner
+func (db *Database) GetStepApprovers(leave *models.Leave) ([]models.User, error) {
+	userID, role := stepDeciders(leave)
+	var query, arg string
+	switch {
+	case userID != nil:
+		query, arg = "SELECT "+userColumns+" FROM users WHERE id = ? AND id <> ?", *userID
+	case role != "":
+		query, arg = "SELECT "+userColumns+" FROM users WHERE role = ? AND id <> ? ORDER BY email", role
+	default:
+		return []models.User{}, nil
+	}
+
+	rows, err := db.Conn.Query(query, arg, leave.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	delegates, err := activeDelegates(db.Conn, ids, today())
+	if err != nil {
+		return nil, err
+	}
+	return withDelegates(users, delegates, leave.UserID), nil
+}
+
+// withDelegates adds the delegates who are neither approvers already nor
+// the leave's owner
+func withDelegates(approvers, delegates []models.User, ownerID string) []models.User {
+	for _, d := range delegates {
+		if d.ID != ownerID && !slices.ContainsFunc(approvers, func(a models.User) bool { return a.ID == d.ID }) {
+			approvers = append(approvers, d)
+		}
+	}
+	return approvers
+}
+
+func (db *Database) GetNotificationPreferences(userID string) (*models.NotificationPreferences, error) {
+	prefs := defaultNotificationPreferences
+	err := db.Conn.QueryRow("SELECT email FROM notification_preferences WHERE user_id = ?", userID).Scan(&prefs.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (db *Database) UpdateNotificationPreferences(actorID string, userID string, prefs models.NotificationPreferences) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockUser(tx, userID); err != nil {
+		return err
+	}
+	before := defaultNotificationPreferences
+	err = tx.QueryRow("SELECT email FROM notification_preferences WHERE user_id = ?", userID).Scan(&before.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	query := "INSERT INTO notification_preferences (user_id, email) VALUES (?, ?) ON DUPLICATE KEY UPDATE email = VALUES(email)"
+	if _, err := tx.Exec(query, userID, prefs.Email); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditUserNotify, userID, before, prefs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,100 @@
+// internal/db/overlap.go
+package db
+
+import (
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"strings"
+)
+
+// OverlapError is returned when a leave clashes with other pending or
+// approved leaves of the same user.
+type OverlapError struct {
+	LeaveIDs []string
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("leave overlaps with existing leave(s): %s", strings.Join(e.LeaveIDs, ", "))
+}
+
+const minutesPerDay = 24 * 60
+
+// dayInterval returns the minutes of the day a single-day leave covers
+func dayInterval(leave *models.Leave) (int, int) {
+	switch constants.LeavePortion(leave.Portion) {
+	case constants.LeavePortionFirstHalf:
+		return 0, minutesPerDay / 2
+	case constants.LeavePortionSecondHalf:
+		return minutesPerDay / 2, minutesPerDay
+	case constants.LeavePortionHourly:
+		if leave.StartTime == nil || leave.EndTime == nil {
+			break
+		}
+		start, err1 := parseClock(*leave.StartTime)
+		end, err2 := parseClock(*leave.EndTime)
+		if err1 != nil || err2 != nil {
+			break
+		}
+		return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute()
+	}
+	return 0, minutesPerDay
+}
+
+// leavesOverlap reports whether two leaves claim any of the same time.
+// Multi-day leaves are always full days; two leaves on the same single day
+// only clash if their portions of that day intersect.
+func leavesOverlap(a, b *models.Leave) bool {
+	aStart, err1 := parseDate(a.StartDate)
+	aEnd, err2 := parseDate(a.EndDate)
+	bStart, err3 := parseDate(b.StartDate)
+	bEnd, err4 := parseDate(b.EndDate)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return true
+	}
+	if aEnd.Before(bStart) || bEnd.Before(aStart) {
+		return false
+	}
+	if !aStart.Equal(aEnd) || !bStart.Equal(bEnd) {
+		return true
+	}
+
+	aFrom, aTo := dayInterval(a)
+	bFrom, bTo := dayInterval(b)
+	return aFrom < bTo && bFrom < aTo
+}
+
+// checkOverlap looks for the user's other leaves in the given statuses that
+// clash with leave. Callers must already hold the user's row lock so two
+// transactions can't both pass the check.
+func (db *Database) checkOverlap(q querier, leave *models.Leave, statuses ...constants.LeaveStatus) error {
+	placeholders := make([]string, len(statuses))
+	args := []any{leave.UserID, leave.ID, sqlDate(leave.EndDate), sqlDate(leave.StartDate)}
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args = append(args, string(status))
+	}
+
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.user_id = ? AND l.id <> ? AND l.start_date <= ? AND l.end_date >= ?
+		AND l.status IN (` + strings.Join(placeholders, ", ") + `)
+	`
+	candidates, err := db.queryLeaves(q, query, args...)
+	if err != nil {
+		return err
+	}
+
+	var clashing []string
+	for i := range candidates {
+		if leavesOverlap(leave, &candidates[i]) {
+			clashing = append(clashing, candidates[i].ID)
+		}
+	}
+	if len(clashing) > 0 {
+		return &OverlapError{LeaveIDs: clashing}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+// internal/db/page.go
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"strings"
+	"time"
+)
+
+// DefaultPageLimit is the page size of list endpoints when no limit is given
+const DefaultPageLimit = 50
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorTimeLayout formats times in cursors at a fixed width so that they
+// sort as strings
+const cursorTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// pageCursor points just past the last row of a page: the sort it was
+// made for, that row's sort key and its ID as a tie-breaker
+type pageCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"id"`
+}
+
+func (c pageCursor) String() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor, which must have been made for sort.
+// An empty cursor is the first page and decodes to nil.
+func decodeCursor(s, sort string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sort || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// sortField splits a sort like "-createdAt" into its field and direction
+func sortField(sort string) (string, bool) {
+	if field, ok := strings.CutPrefix(sort, "-"); ok {
+		return field, true
+	}
+	return sort, false
+}
+
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	return limit
+}
+
+// keyBefore orders rows by sort key and then ID, reversed when desc. A
+// row never comes before itself, whichever the direction.
+func keyBefore(key1, id1, key2, id2 string, desc bool) bool {
+	if key1 != key2 {
+		return (key1 < key2) != desc
+	}
+	if id1 == id2 {
+		return false
+	}
+	return (id1 < id2) != desc
+}
+
+// after reports whether the row with key and id comes after the cursor
+func (c *pageCursor) after(key, id string, desc bool) bool {
+	return c == nil || keyBefore(c.Key, c.ID, key, id, desc)
+}
+
+// keysetCondition is the SQL equivalent of pageCursor.after
+func keysetCondition(column, idColumn string, desc bool) string {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", column, op, column, idColumn, op)
+}
+
+// trimPage cuts items fetched with one row to spare down to limit, and
+// returns the cursor for the next page if there is one
+func trimPage[T any](items []T, limit int, sort string, key func(*T) (string, string)) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	k, id := key(&items[limit-1])
+	return items, pageCursor{Sort: sort, Key: k, ID: id}.String()
+}
+
+// leaveSortKey is the cursor key of a leave for a sort field
+func leaveSortKey(leave *models.Leave, field string) string {
+	if field == "startDate" {
+		return sqlDate(leave.StartDate)
+	}
+	return leave.CreatedAt.UTC().Format(cursorTimeLayout)
+}
+
+func userSortKey(user *models.User, field string) string {
+	if field == "createdAt" {
+		return user.CreatedAt.UTC().Format(cursorTimeLayout)
+	}
+	return user.Email
+}
+
+// cursorArg converts a cursor key back into a query argument
+func cursorArg(field, key string) (any, error) {
+	if field != "createdAt" {
+		return key, nil
+	}
+	t, err := time.Parse(cursorTimeLayout, key)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// leaveRange parses the optional from and to dates of a leave filter
+func leaveRange(filter models.LeaveFilter) (string, string, error) {
+	for _, d := range []string{filter.From, filter.To} {
+		if _, err := time.Parse(workdays.DateLayout, d); d != "" && err != nil {
+			return "", "", ErrInvalidDateRange
+		}
+	}
+	if filter.From != "" && filter.To != "" && filter.From > filter.To {
+		return "", "", ErrInvalidDateRange
+	}
+	return filter.From, filter.To, nil
+}
+
+func leaveSort(filter models.LeaveFilter) string {
+	if filter.Sort == "" {
+		return "-createdAt"
+	}
+	return filter.Sort
+}
+
+func userSort(filter models.UserFilter) string {
+	if filter.Sort == "" {
+		return "email"
+	}
+	return filter.Sort
+}
+
+// ListLeaves returns one page of the leaves matching filter
+func (db *Database) ListLeaves(filter models.LeaveFilter) (*models.LeavePage, error) {
+	from, to, err := leaveRange(filter)
+	if err != nil {
+		return nil, err
+	}
+	sort := leaveSort(filter)
+	field, desc := sortField(sort)
+	cursor, err := decodeCursor(filter.Cursor, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	for _, f := range []struct{ column, value string }{
+		{"l.status", filter.Status},
+		{"l.type", filter.Type},
+		{"l.user_id", filter.UserID},
+	} {
+		if f.value != "" {
+			where = append(where, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if from != "" {
+		where = append(where, "l.end_date >= ?")
+		args = append(args, from)
+	}
+	if to != "" {
+		where = append(where, "l.start_date <= ?")
+		args = append(args, to)
+	}
+
+	column := "l.created_at"
+	if field == "startDate" {
+		column = "l.start_date"
+	}
+	if cursor != nil {
+		key, err := cursorArg(field, cursor.Key)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, keysetCondition(column, "l.id", desc))
+		args = append(args, key, key, cursor.ID)
+	}
+
+	query := "SELECT " + leaveColumns + " FROM leaves l JOIN users u ON l.user_id = u.id"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	limit := pageLimit(filter.Limit)
+	query += fmt.Sprintf(" ORDER BY %s %s, l.id %s LIMIT ?", column, dir, dir)
+	args = append(args, limit+1)
+
+	leaves, err := db.queryLeaves(db.Conn, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.LeavePage{}
+	page.Items, page.NextCursor = trimPage(leaves, limit, sort, func(l *models.Leave) (string, string) {
+		return leaveSortKey(l, field), l.ID
+	})
+	return page, nil
+}
+
+// ListUsers returns one page of the users matching filter
+func (db *Database) ListUsers(filter models.UserFilter) (*models.UserPage, error) {
+	sort := userSort(filter)
+	field, desc := sortField(sort)
+	cursor, err := decodeCursor(filter.Cursor, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	if filter.Role != "" {
+		where = append(where, "role = ?")
+		args = append(args, filter.Role)
+	}
+	if filter.Email != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Email)
+		where = append(where, "email LIKE ?")
+		args = append(args, "%"+escaped+"%")
+	}
+
+	column := "email"
+	if field == "createdAt" {
+		column = "created_at"
+	}
+	if cursor != nil {
+		key, err := cursorArg(field, cursor.Key)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, keysetCondition(column, "id", desc))
+		args = append(args, key, key, cursor.ID)
+	}
+
+	query := "SELECT " + userColumns + " FROM users"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	limit := pageLimit(filter.Limit)
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, dir, dir)
+	args = append(args, limit+1)
+
+	rows, err := db.Conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	page := &models.UserPage{}
+	page.Items, page.NextCursor = trimPage(users, limit, sort, func(u *models.User) (string, string) {
+		return userSortKey(u, field), u.ID
+	})
+	return page, nil
+}
@@ -0,0 +1,149 @@
+// internal/db/reminders.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"time"
+)
+
+// awaitingDecision reports whether a leave is waiting on one of its steps
+func awaitingDecision(leave *models.Leave) bool {
+	awaiting := leave.Status == string(constants.LeaveStatusPending) || leave.Status == string(constants.LeaveStatusCancellationRequested)
+	return awaiting && leave.CurrentStep != nil
+}
+
+// escalate moves a leave's current step up a level at now. A manager step
+// goes to next, the approver's own manager, when there is one other than
+// the requester; anything else is escalated to admins.
+func escalate(leave *models.Leave, next *string, now time.Time) models.Leave {
+	after := *leave
+	restartWait(&after, now)
+	if next != nil && *next != leave.UserID && after.ApproverID != nil && constants.Role(*after.CurrentStep) == constants.RoleManager {
+		after.ApproverID = next
+	} else {
+		after.EscalatedAt = &now
+	}
+	return after
+}
+
+// AcquireLease takes or renews the named lease for holder until now+ttl.
+// It reports false while another holder's lease has not expired.
+func (db *Database) AcquireLease(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	// Create the row already expired, so that replicas racing for a new
+	// lease queue on its row lock instead of deadlocking on the gap where
+	// it would go
+	if _, err := db.Conn.Exec("INSERT IGNORE INTO job_leases (name, holder, expires_at) VALUES (?, '', ?)", name, now); err != nil {
+		return false, err
+	}
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var expiresAt time.Time
+	if err := tx.QueryRow("SELECT holder, expires_at FROM job_leases WHERE name = ? FOR UPDATE", name).Scan(&current, &expiresAt); err != nil {
+		return false, err
+	}
+	if current != holder && expiresAt.After(now) {
+		return false, nil
+	}
+	if _, err := tx.Exec("UPDATE job_leases SET holder = ?, expires_at = ? WHERE name = ?", holder, now.Add(ttl), name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetAwaitingLeaves returns every leave waiting on a decision, longest
+// waiting first
+func (db *Database) GetAwaitingLeaves() ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.status IN (?, ?) AND l.current_step IS NOT NULL
+		ORDER BY l.awaiting_since
+	`
+	return db.queryLeaves(db.Conn, query, string(constants.LeaveStatusPending), string(constants.LeaveStatusCancellationRequested))
+}
+
+// MarkLeaveReminded records that the approvers of a leave's current step
+// were reminded at. It reports false when they already were, or the leave
+// no longer waits on a decision, so each step is reminded once.
+func (db *Database) MarkLeaveReminded(leaveID string, at time.Time) (bool, error) {
+	query := "UPDATE leaves SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL AND current_step IS NOT NULL"
+	res, err := db.Conn.Exec(query, at, leaveID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EscalateLeave moves a waiting leave to the next approver up, or to
+// admins, and restarts its clock. Leaves no longer waiting on a decision
+// or already with admins give ErrLeaveNotPending.
+func (db *Database) EscalateLeave(leaveID string, at time.Time) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leave, err := db.lockLeave(tx, leaveID)
+	if err != nil {
+		return err
+	}
+	if !awaitingDecision(leave) || leave.EscalatedAt != nil {
+		return ErrLeaveNotPending
+	}
+	var next *string
+	if leave.ApproverID != nil {
+		if err := tx.QueryRow("SELECT manager_id FROM users WHERE id = ?", *leave.ApproverID).Scan(&next); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	after := escalate(leave, next, at)
+	query := "UPDATE leaves SET approver_id = ?, awaiting_since = ?, reminded_at = NULL, escalated_at = ? WHERE id = ?"
+	if _, err := tx.Exec(query, after.ApproverID, after.AwaitingSince, after.EscalatedAt, leaveID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, "", constants.AuditLeaveEscalate, leaveID, leave, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AutoApproveLeave approves a pending leave on nobody's behalf, closing
+// every step still open with comment. It fails like any approval would,
+// for example when the balance no longer covers the leave.
+func (db *Database) AutoApproveLeave(leaveID string, comment string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leave, err := db.lockLeave(tx, leaveID)
+	if err != nil {
+		return err
+	}
+	if leave.Status != string(constants.LeaveStatusPending) {
+		return ErrLeaveNotPending
+	}
+	query := "UPDATE leave_approvals SET decision = ?, comment = ?, decided_at = ? WHERE leave_id = ? AND decision = ?"
+	approved, pending := string(constants.LeaveStatusApproved), string(constants.LeaveStatusPending)
+	if _, err := tx.Exec(query, approved, comment, time.Now(), leaveID, pending); err != nil {
+		return err
+	}
+	if err := db.setLeaveStatus(tx, "", leave, approved, &comment); err != nil {
+		return err
+	}
+	return db.commitLeaves(tx)
+}
@@ -0,0 +1,234 @@
+// internal/db/reports.go
+package db
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// withExtra scans a row whose first columns are read by another scanner
+// and whose last columns go into extra
+type withExtra struct {
+	row   rowScanner
+	extra []any
+}
+
+func (w withExtra) Scan(dest ...any) error {
+	return w.row.Scan(append(dest, w.extra...)...)
+}
+
+// reportYear is the year of a balance report, the current one by default
+func reportYear(filter models.BalanceReportFilter) int {
+	if filter.Year == 0 {
+		return time.Now().Year()
+	}
+	return filter.Year
+}
+
+// emitBalances emits a user's balances in type order, keeping only
+// typeCode when it is set
+func emitBalances(user models.User, balances map[string]models.LeaveBalance, typeCode string, emit func(models.BalanceReportRow) error) error {
+	types := make([]string, 0, len(balances))
+	for t := range balances {
+		if typeCode == "" || t == typeCode {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		if err := emit(models.BalanceReportRow{UserID: user.ID, Email: user.Email, Type: t, LeaveBalance: balances[t]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamLeaveReport passes the leaves matching filter to emit one at a
+// time, ordered by start date and employee, without loading them all
+func (db *Database) StreamLeaveReport(filter models.LeaveReportFilter, emit func(models.LeaveReportRow) error) error {
+	from, to, err := leaveRange(models.LeaveFilter{From: filter.From, To: filter.To})
+	if err != nil {
+		return err
+	}
+
+	where := []string{"TRUE"}
+	var args []any
+	for _, f := range []struct{ column, value string }{
+		{"l.status", filter.Status},
+		{"l.type", filter.Type},
+	} {
+		if f.value != "" {
+			where = append(where, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if from != "" {
+		where = append(where, "l.end_date >= ?")
+		args = append(args, from)
+	}
+	if to != "" {
+		where = append(where, "l.start_date <= ?")
+		args = append(args, to)
+	}
+
+	query := `
+		SELECT ` + leaveColumns + `, a.email
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		LEFT JOIN users a ON a.id = COALESCE(
+			(SELECT la.approver_id FROM leave_approvals la WHERE la.leave_id = l.id AND la.decided_at IS NOT NULL ORDER BY la.step DESC LIMIT 1),
+			l.approver_id
+		)
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY l.start_date, u.email, l.id
+	`
+	rows, err := db.Conn.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var approver *string
+		leave, err := db.scanLeave(withExtra{rows, []any{&approver}})
+		if err != nil {
+			return err
+		}
+		leave.StartDate, leave.EndDate = sqlDate(leave.StartDate), sqlDate(leave.EndDate)
+		if err := emit(models.LeaveReportRow{Leave: *leave, Approver: approver}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// balanceReportQuery sums the ledger, accruals and comp-off credits of
+// every user for each balance type, with the allowances of the user's
+// winning allowance policy. The policy is picked the way
+// policyMatch.outranks does: direct assignments, then priority, then name.
+const balanceReportQuery = `
+	SELECT u.id, u.email,
+		COALESCE(p.sick_allowance, u.sick_allowance), COALESCE(p.annual_allowance, u.annual_allowance), COALESCE(p.casual_allowance, u.casual_allowance),
+		t.code, COALESCE(led.used, 0), COALESCE(acc.credited, 0), COALESCE(acc.carried, 0), COALESCE(co.credited, 0), COALESCE(co.used, 0)
+	FROM users u
+	LEFT JOIN allowance_policies p ON p.id = (
+		SELECT a.policy_id
+		FROM allowance_policy_assignments a
+		JOIN allowance_policies ap ON ap.id = a.policy_id
+		WHERE (a.subject_type = 'user' AND a.subject_id = u.id)
+			OR (a.subject_type = 'group' AND a.subject_id IN (SELECT group_name FROM user_groups WHERE user_id = u.id))
+		ORDER BY a.subject_type = 'user' DESC, ap.priority DESC, CAST(ap.name AS BINARY)
+		LIMIT 1
+	)
+	JOIN leave_types t ON t.counts_against_balance AND (? = '' OR t.code = ?)
+	LEFT JOIN (
+		SELECT user_id, leave_type, SUM(days) AS used
+		FROM leave_balance_ledger
+		WHERE year = ?
+		GROUP BY user_id, leave_type
+	) led ON led.user_id = u.id AND led.leave_type = t.code
+	LEFT JOIN (
+		SELECT user_id, leave_type, SUM(days) AS credited, SUM(CASE WHEN kind = ? THEN days ELSE 0 END) AS carried
+		FROM leave_accruals
+		WHERE year = ?
+		GROUP BY user_id, leave_type
+	) acc ON acc.user_id = u.id AND acc.leave_type = t.code
+	LEFT JOIN (
+		SELECT c.user_id, SUM(c.days) AS credited, SUM(COALESCE(cu.used, 0)) AS used
+		FROM comp_off_credits c
+		LEFT JOIN (SELECT credit_id, SUM(days) AS used FROM comp_off_usage GROUP BY credit_id) cu ON cu.credit_id = c.id
+		WHERE c.status = ? AND c.expires_on >= ?
+		GROUP BY c.user_id
+	) co ON co.user_id = u.id AND t.code = ?
+	ORDER BY u.email, u.id, t.code
+`
+
+// pendingBalanceDays sums the pending leave that balances in year count,
+// by user and type. Pending leave is few rows, but its days depend on the
+// working-day calendar, so it is summed here rather than by the database.
+func (db *Database) pendingBalanceDays(year int) (map[string]map[string]float64, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.status = ? AND (YEAR(l.start_date) = ? OR l.type = ?)
+	`
+	leaves, err := db.queryLeaves(db.Conn, query, string(constants.LeaveStatusPending), year, string(constants.LeaveTypeCompOff))
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]map[string]float64)
+	for _, l := range leaves {
+		if pending[l.UserID] == nil {
+			pending[l.UserID] = make(map[string]float64)
+		}
+		pending[l.UserID][l.Type] += l.WorkingDays
+	}
+	return pending, nil
+}
+
+// StreamBalanceReport passes every user's balances for the filter's year
+// to emit, one user at a time in email order, computing them the way
+// GetLeaveBalances does with a single aggregate query
+func (db *Database) StreamBalanceReport(filter models.BalanceReportFilter, emit func(models.BalanceReportRow) error) error {
+	year := reportYear(filter)
+	policies, err := getAccrualPolicies(db.Conn)
+	if err != nil {
+		return err
+	}
+	leaveTypes, err := getLeaveTypes(db.Conn)
+	if err != nil {
+		return err
+	}
+	byCode := make(map[string]models.LeaveType, len(leaveTypes))
+	for _, t := range leaveTypes {
+		byCode[t.Code] = t
+	}
+	pending, err := db.pendingBalanceDays(year)
+	if err != nil {
+		return err
+	}
+
+	compOff := string(constants.LeaveTypeCompOff)
+	rows, err := db.Conn.Query(balanceReportQuery,
+		filter.Type, filter.Type,
+		year,
+		accrualCarryForward, year,
+		string(constants.LeaveStatusApproved), today(), compOff,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.BalanceReportRow
+		var allowances models.Allowance
+		var code string
+		var used, accrued, carried, credits, creditsUsed float64
+		if err := rows.Scan(&row.UserID, &row.Email, &allowances.Sick, &allowances.Annual, &allowances.Casual,
+			&code, &used, &accrued, &carried, &credits, &creditsUsed); err != nil {
+			return err
+		}
+
+		row.Type = code
+		balance := &row.LeaveBalance
+		balance.Pending = pending[row.UserID][code]
+		switch _, accrues := policies[code]; {
+		case code == compOff:
+			balance.Allowance, balance.Used = credits, creditsUsed
+		case accrues:
+			balance.Allowance, balance.CarriedForward, balance.Used = accrued, carried, used
+		default:
+			balance.Allowance, balance.Used = float64(byCode[code].AllowanceFrom(allowances)), used
+		}
+		balance.Remaining = balance.Allowance - balance.Used - balance.Pending
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
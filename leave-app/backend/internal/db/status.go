@@ -0,0 +1,86 @@
+// internal/db/status.go
+package db
+
+import (
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"time"
+)
+
+// transitions lists the statuses a leave may move to from each status.
+// Rejected, withdrawn and cancelled are final.
+var transitions = map[constants.LeaveStatus][]constants.LeaveStatus{
+	constants.LeaveStatusPending: {
+		constants.LeaveStatusApproved,
+		constants.LeaveStatusRejected,
+		constants.LeaveStatusWithdrawn,
+	},
+	constants.LeaveStatusApproved: {
+		constants.LeaveStatusCancellationRequested,
+	},
+	// Refusing a cancellation puts the leave back to approved
+	constants.LeaveStatusCancellationRequested: {
+		constants.LeaveStatusCancelled,
+		constants.LeaveStatusApproved,
+	},
+}
+
+// TransitionError is returned when a leave cannot move between two statuses
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("leave cannot move from %s to %s", e.From, e.To)
+}
+
+// CanTransition reports whether a leave in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[constants.LeaveStatus(from)] {
+		if string(next) == to {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTransition(from, to string) error {
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// chargesBalance reports whether a leave in status holds its days in the
+// ledger. A requested cancellation keeps the charge until it is granted.
+func chargesBalance(status string) bool {
+	return status == string(constants.LeaveStatusApproved) || status == string(constants.LeaveStatusCancellationRequested)
+}
+
+// stepFor returns the role that must decide a leave moving to status, or
+// nil when the new status needs no decision. Pending leaves keep their
+// chain's step; cancellations are decided like a manager step.
+func stepFor(leave *models.Leave, status string) *string {
+	switch constants.LeaveStatus(status) {
+	case constants.LeaveStatusPending:
+		return leave.CurrentStep
+	case constants.LeaveStatusCancellationRequested:
+		step := string(constants.RoleManager)
+		return &step
+	}
+	return nil
+}
+
+// restartWait starts the clock on a leave's current step at now, clearing
+// any reminder or escalation of the step before. Leaves awaiting no
+// decision have no clock.
+func restartWait(leave *models.Leave, now time.Time) {
+	leave.AwaitingSince = nil
+	if leave.CurrentStep != nil {
+		leave.AwaitingSince = &now
+	}
+	leave.RemindedAt = nil
+	leave.EscalatedAt = nil
+}
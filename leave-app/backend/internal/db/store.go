@@ -0,0 +1,102 @@
+// internal/db/store.go
+package db
+
+import (
+	"leave-app/internal/models"
+	"time"
+)
+
+// Store is the persistence layer used by the handlers and the authenticator.
+// Database is the MySQL implementation; MemoryStore keeps everything in
+// memory for tests. Mutations take the ID of the user making the change,
+// which is recorded in the audit trail.
+type Store interface {
+	GetUserByEmail(email string) (*models.User, error)
+	CreateUser(email string) (*models.User, error)
+	ListUsers(filter models.UserFilter) (*models.UserPage, error)
+	UpdateUserRole(actorID string, userID string, role string) error
+	SetUserManager(actorID string, userID string, managerID *string) error
+	UpdateAllUserAllowances(actorID string, req models.UpdateAllowancesRequest) error
+	GetLeaveBalances(userID string, year int) (map[string]models.LeaveBalance, error)
+	ImportRecords(actorID string, batch *models.ImportBatch, dryRun bool) (*models.ImportResult, error)
+	StreamBalanceReport(filter models.BalanceReportFilter, emit func(models.BalanceReportRow) error) error
+
+	CreateLeave(actorID string, leave *models.Leave) error
+	ListLeaves(filter models.LeaveFilter) (*models.LeavePage, error)
+	GetLeaveByID(leaveID string) (*models.Leave, error)
+	GetTeamLeaves(approverID string) ([]models.Leave, error)
+	StreamLeaveReport(filter models.LeaveReportFilter, emit func(models.LeaveReportRow) error) error
+	GetLeaveAnalytics(from, to string) (*models.LeaveAnalytics, error)
+	GetTeamAvailability(managerID string, from, to string) (*models.TeamAvailability, error)
+	GetStaffingWarnings(leave *models.Leave) ([]models.StaffingWarning, error)
+	SetMinStaffing(actorID string, managerID string, minStaffing int) error
+	UpdateLeaveStatus(actorID string, leaveID string, status string, comment *string) error
+	DeleteLeave(actorID string, leaveID string) error
+
+	GetApprovalPolicies() ([]models.ApprovalPolicy, error)
+	ReplaceApprovalPolicies(actorID string, policies []models.ApprovalPolicy) error
+	DecideLeave(leaveID string, actor *models.User, decision models.LeaveDecision) ([]models.StaffingWarning, error)
+	GetPendingApprovals(user *models.User) ([]models.Leave, error)
+	CreateDelegation(actorID string, delegation *models.Delegation) error
+	GetDelegations(userID string) ([]models.Delegation, error)
+	GetActiveDelegators(userID string) ([]models.User, error)
+	DeleteDelegation(actorID string, delegatorID string, delegationID string) error
+
+	CreateCompOffCredit(actorID string, credit *models.CompOffCredit) error
+	GetCompOffCredit(creditID string) (*models.CompOffCredit, error)
+	GetCompOffCredits(userID string) ([]models.CompOffCredit, error)
+	GetPendingCompOffCredits(user *models.User) ([]models.CompOffCredit, error)
+	DecideCompOffCredit(creditID string, actor *models.User, approve bool, comment *string) error
+
+	GetAuditEvents(filter models.AuditFilter) ([]models.AuditEvent, error)
+
+	GetAccrualPolicies() ([]models.AccrualPolicy, error)
+	ReplaceAccrualPolicies(actorID string, policies []models.AccrualPolicy) error
+	RunAccruals(actorID string, asOf time.Time) (*models.AccrualRun, error)
+
+	GetAllowancePolicies() ([]models.AllowancePolicy, error)
+	CreateAllowancePolicy(actorID string, policy *models.AllowancePolicy) error
+	AssignAllowancePolicy(actorID string, policyID string, userIDs []string, groups []string) (*models.AllowancePolicy, error)
+	SetUserGroups(actorID string, userID string, groups []string) ([]string, error)
+	GetEffectivePolicy(userID string) (*models.EffectivePolicy, error)
+
+	GetLeaveTypes() ([]models.LeaveType, error)
+	CreateLeaveType(actorID string, leaveType *models.LeaveType) error
+	UpdateLeaveType(actorID string, code string, settings models.LeaveTypeSettings) (*models.LeaveType, error)
+	DeleteLeaveType(actorID string, code string) error
+
+	GetHolidays() ([]models.Holiday, error)
+	SetHoliday(actorID string, holiday models.Holiday) error
+	DeleteHoliday(actorID string, date string) error
+
+	AddAttachment(actorID string, attachment *models.Attachment) error
+	GetAttachment(leaveID, attachmentID string) (*models.Attachment, error)
+
+	RotateCalendarToken(actorID string, userID string) (string, error)
+	RevokeCalendarToken(actorID string, userID string) error
+	GetUserByCalendarToken(token string) (*models.User, error)
+	GetCalendarLeaves(owner *models.User, scope string, since string) ([]models.Leave, error)
+
+	GetWebhookSubscriptions() ([]models.WebhookSubscription, error)
+	CreateWebhookSubscription(actorID string, sub *models.WebhookSubscription) error
+	UpdateWebhookSubscription(actorID string, id string, sub models.WebhookSubscription) (*models.WebhookSubscription, error)
+	DeleteWebhookSubscription(actorID string, id string) error
+	GetWebhookDeliveries(subscriptionID string, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	ClaimWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(deliveryID string, statusCode int, errMsg string, at time.Time) error
+
+	GetStepApprovers(leave *models.Leave) ([]models.User, error)
+	GetNotificationPreferences(userID string) (*models.NotificationPreferences, error)
+	UpdateNotificationPreferences(actorID string, userID string, prefs models.NotificationPreferences) error
+
+	AcquireLease(name, holder string, now time.Time, ttl time.Duration) (bool, error)
+	GetAwaitingLeaves() ([]models.Leave, error)
+	MarkLeaveReminded(leaveID string, at time.Time) (bool, error)
+	EscalateLeave(leaveID string, at time.Time) error
+	AutoApproveLeave(leaveID string, comment string) error
+}
+
+var (
+	_ Store = (*Database)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
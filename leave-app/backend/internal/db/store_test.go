@@ -0,0 +1,578 @@
+// internal/db/store_test.go
+package db
+
+import (
+	"errors"
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// storeEnv is a store with an admin and a manager whose reports are alice
+// and bob. Their emails are on example.org, apart from anyone a migration
+// seeds.
+type storeEnv struct {
+	t     *testing.T
+	store Store
+
+	admin, manager, alice, bob *models.User
+}
+
+func newStoreEnv(t *testing.T, store Store) *storeEnv {
+	t.Helper()
+	env := &storeEnv{t: t, store: store}
+	env.admin = env.user("admin@example.org", constants.RoleAdmin, nil)
+	env.manager = env.user("manager@example.org", constants.RoleManager, nil)
+	env.alice = env.user("alice@example.org", constants.RoleUser, &env.manager.ID)
+	env.bob = env.user("bob@example.org", constants.RoleUser, &env.manager.ID)
+	return env
+}
+
+// user signs up email and gives them role and managerID
+func (e *storeEnv) user(email string, role constants.Role, managerID *string) *models.User {
+	e.t.Helper()
+	user, err := e.store.CreateUser(email)
+	if err != nil {
+		e.t.Fatalf("create %s: %v", email, err)
+	}
+	if err := e.store.UpdateUserRole(user.ID, user.ID, string(role)); err != nil {
+		e.t.Fatal(err)
+	}
+	if managerID != nil {
+		if err := e.store.SetUserManager(user.ID, user.ID, managerID); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+	if user, err = e.store.GetUserByEmail(email); err != nil {
+		e.t.Fatal(err)
+	}
+	return user
+}
+
+// file has user ask for a full-day leave of leaveType from start to end
+func (e *storeEnv) file(user *models.User, leaveType constants.LeaveType, start, end string) *models.Leave {
+	e.t.Helper()
+	leave := &models.Leave{
+		UserID:    user.ID,
+		Type:      string(leaveType),
+		StartDate: start,
+		EndDate:   end,
+		Portion:   string(constants.LeavePortionFull),
+		Reason:    "away",
+		Status:    string(constants.LeaveStatusPending),
+	}
+	if err := e.store.CreateLeave(user.ID, leave); err != nil {
+		e.t.Fatalf("file %s leave %s to %s: %v", leaveType, start, end, err)
+	}
+	return leave
+}
+
+// setStatus moves a leave to status as the admin
+func (e *storeEnv) setStatus(leave *models.Leave, status constants.LeaveStatus) {
+	e.t.Helper()
+	if err := e.store.UpdateLeaveStatus(e.admin.ID, leave.ID, string(status), nil); err != nil {
+		e.t.Fatalf("move leave to %s: %v", status, err)
+	}
+}
+
+func (e *storeEnv) balance(user *models.User, leaveType constants.LeaveType, year int) models.LeaveBalance {
+	e.t.Helper()
+	balances, err := e.store.GetLeaveBalances(user.ID, year)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return balances[string(leaveType)]
+}
+
+// monday returns the Monday weeks weeks after this week's, or before it
+// when weeks is negative
+func monday(weeks int) time.Time {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	for day.Weekday() != time.Monday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day.AddDate(0, 0, 7*weeks)
+}
+
+// date formats the day days after day
+func date(day time.Time, days int) string {
+	return day.AddDate(0, 0, days).Format(workdays.DateLayout)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store { return NewMemoryStore() })
+}
+
+// testStore checks the behaviour both stores must share. open returns an
+// empty store for each subtest.
+func testStore(t *testing.T, open func(t *testing.T) Store) {
+	t.Run("the ledger charges approved leave until it is cancelled", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		leave := env.file(env.alice, constants.LeaveTypeAnnual, "2030-03-04", "2030-03-06")
+		if got := env.balance(env.alice, constants.LeaveTypeAnnual, 2030); got.Pending != 3 || got.Used != 0 || got.Remaining != 17 {
+			t.Errorf("while pending = %+v, want 3 days pending", got)
+		}
+
+		env.setStatus(leave, constants.LeaveStatusApproved)
+		if got := env.balance(env.alice, constants.LeaveTypeAnnual, 2030); got.Pending != 0 || got.Used != 3 || got.Remaining != 17 {
+			t.Errorf("once approved = %+v, want 3 days used", got)
+		}
+
+		env.setStatus(leave, constants.LeaveStatusCancellationRequested)
+		if got := env.balance(env.alice, constants.LeaveTypeAnnual, 2030); got.Used != 3 {
+			t.Errorf("while cancelling = %+v, want the days still used", got)
+		}
+		env.setStatus(leave, constants.LeaveStatusCancelled)
+		if got := env.balance(env.alice, constants.LeaveTypeAnnual, 2030); got.Used != 0 || got.Remaining != 20 {
+			t.Errorf("once cancelled = %+v, want the days back", got)
+		}
+
+		// Five weeks is more than the 20 days alice has
+		err := env.store.CreateLeave(env.alice.ID, &models.Leave{
+			UserID: env.alice.ID, Type: string(constants.LeaveTypeAnnual), StartDate: "2030-06-03", EndDate: "2030-07-05",
+			Portion: string(constants.LeavePortionFull), Reason: "away", Status: string(constants.LeaveStatusPending),
+		})
+		var insufficient *InsufficientBalanceError
+		if !errors.As(err, &insufficient) || insufficient.Requested != 25 || insufficient.Remaining != 20 {
+			t.Errorf("too long a leave: err = %v, want 25 days requested and 20 remaining", err)
+		}
+
+		// Balances are per year, so leave over New Year is refused outright
+		err = env.store.CreateLeave(env.alice.ID, &models.Leave{
+			UserID: env.alice.ID, Type: string(constants.LeaveTypeAnnual), StartDate: "2030-12-30", EndDate: "2031-01-02",
+			Portion: string(constants.LeavePortionFull), Reason: "away", Status: string(constants.LeaveStatusPending),
+		})
+		if !errors.Is(err, ErrLeaveSpansYears) {
+			t.Errorf("leave over New Year: err = %v, want ErrLeaveSpansYears", err)
+		}
+	})
+
+	t.Run("lists page through every row once", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		var want []string
+		for week := range 5 {
+			leave := env.file(env.alice, constants.LeaveTypeAnnual, date(time.Date(2030, 9, 2, 0, 0, 0, 0, time.UTC), 7*week), date(time.Date(2030, 9, 2, 0, 0, 0, 0, time.UTC), 7*week))
+			want = append(want, leave.ID)
+		}
+
+		for _, sort := range []string{"startDate", "-startDate", "createdAt", "-createdAt"} {
+			var got []string
+			var last string
+			filter := models.LeaveFilter{UserID: env.alice.ID, Sort: sort, Limit: 2}
+			for pages := 1; ; pages++ {
+				page, err := env.store.ListLeaves(filter)
+				if err != nil {
+					t.Fatalf("%s: %v", sort, err)
+				}
+				for _, l := range page.Items {
+					got = append(got, l.ID)
+					if start := sqlDate(l.StartDate); sort == "startDate" && start < last {
+						t.Errorf("%s: %s came after %s", sort, start, last)
+					} else {
+						last = start
+					}
+				}
+				if page.NextCursor == "" {
+					if pages != 3 {
+						t.Errorf("%s: %d pages, want 3", sort, pages)
+					}
+					break
+				}
+				filter.Cursor = page.NextCursor
+				if pages > len(want) {
+					t.Fatalf("%s: the cursor never ends", sort)
+				}
+			}
+			if !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(want))) {
+				t.Errorf("%s: paged through %v, want each of %v once", sort, got, want)
+			}
+			if sort == "startDate" && !slices.Equal(got, want) {
+				t.Errorf("startDate: order = %v, want %v", got, want)
+			}
+		}
+
+		first, err := env.store.ListLeaves(models.LeaveFilter{UserID: env.alice.ID, Sort: "startDate", Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := env.store.ListLeaves(models.LeaveFilter{UserID: env.alice.ID, Sort: "-startDate", Cursor: first.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor of another sort: err = %v, want ErrInvalidCursor", err)
+		}
+
+		var emails []string
+		filter := models.UserFilter{Email: "example.org", Sort: "email", Limit: 3}
+		for {
+			page, err := env.store.ListUsers(filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range page.Items {
+				emails = append(emails, u.Email)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+		if want := []string{env.admin.Email, env.alice.Email, env.bob.Email, env.manager.Email}; !slices.Equal(emails, want) {
+			t.Errorf("users = %v, want %v", emails, want)
+		}
+	})
+
+	t.Run("webhook deliveries are claimed by one sender at a time", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		sub := &models.WebhookSubscription{URL: "https://hooks.example.org/leave", Events: []string{string(constants.WebhookLeaveCreated)}}
+		if err := env.store.CreateWebhookSubscription(env.admin.ID, sub); err != nil {
+			t.Fatal(err)
+		}
+		for _, day := range []string{"2030-10-07", "2030-10-14", "2030-10-21"} {
+			env.file(env.alice, constants.LeaveTypeAnnual, day, day)
+		}
+
+		// Two senders racing for two deliveries each share the three out
+		claimAt := time.Now().Add(time.Minute)
+		claims := make([][]models.WebhookDelivery, 2)
+		errs := make([]error, len(claims))
+		var wg sync.WaitGroup
+		for i := range claims {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				claims[i], errs[i] = env.store.ClaimWebhookDeliveries(claimAt, 2)
+			}()
+		}
+		wg.Wait()
+		claimed := make(map[string]bool)
+		for i := range claims {
+			if errs[i] != nil {
+				t.Fatal(errs[i])
+			}
+			for _, d := range claims[i] {
+				if claimed[d.ID] {
+					t.Errorf("delivery %s claimed twice", d.ID)
+				}
+				claimed[d.ID] = true
+				if d.URL != sub.URL || d.Secret == "" {
+					t.Errorf("delivery %s: url %q, want the subscription's with its secret", d.ID, d.URL)
+				}
+			}
+		}
+		if len(claimed) != 3 {
+			t.Errorf("claimed %d deliveries, want 3", len(claimed))
+		}
+		if again, err := env.store.ClaimWebhookDeliveries(claimAt, 10); err != nil || len(again) != 0 {
+			t.Errorf("claim again = %d, err %v; want none while they are being sent", len(again), err)
+		}
+
+		// A sender that never reports back leaves them to be retried
+		retryAt := claimAt.Add(constants.WebhookClaimSeconds*time.Second + time.Second)
+		if retried, err := env.store.ClaimWebhookDeliveries(retryAt, 10); err != nil || len(retried) != 3 {
+			t.Errorf("claim after the claim ran out = %d, err %v; want all 3", len(retried), err)
+		}
+	})
+
+	t.Run("job leases go to one holder at a time", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		now := time.Now().Truncate(time.Second)
+		won := make([]bool, 4)
+		errs := make([]error, len(won))
+		var wg sync.WaitGroup
+		for i := range won {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				won[i], errs[i] = env.store.AcquireLease("reminders", fmt.Sprintf("replica-%d", i), now, time.Minute)
+			}()
+		}
+		wg.Wait()
+		holder := -1
+		for i := range won {
+			if errs[i] != nil {
+				t.Fatal(errs[i])
+			}
+			if won[i] {
+				if holder >= 0 {
+					t.Fatalf("replicas %d and %d both hold the lease", holder, i)
+				}
+				holder = i
+			}
+		}
+		if holder < 0 {
+			t.Fatal("no replica holds the lease")
+		}
+
+		other := fmt.Sprintf("replica-%d", (holder+1)%len(won))
+		if ok, err := env.store.AcquireLease("reminders", fmt.Sprintf("replica-%d", holder), now.Add(30*time.Second), time.Minute); err != nil || !ok {
+			t.Errorf("renew = %v, err %v; want the holder to keep it", ok, err)
+		}
+		if ok, err := env.store.AcquireLease("reminders", other, now.Add(time.Minute), time.Minute); err != nil || ok {
+			t.Errorf("take a renewed lease = %v, err %v; want false", ok, err)
+		}
+		if ok, err := env.store.AcquireLease("reminders", other, now.Add(2*time.Minute), time.Minute); err != nil || !ok {
+			t.Errorf("take an expired lease = %v, err %v; want true", ok, err)
+		}
+	})
+
+	t.Run("reminders and escalations follow each step once", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		if err := env.store.SetUserManager(env.admin.ID, env.manager.ID, &env.admin.ID); err != nil {
+			t.Fatal(err)
+		}
+		leave := env.file(env.alice, constants.LeaveTypeAnnual, "2030-11-04", "2030-11-05")
+		awaiting, err := env.store.GetAwaitingLeaves()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(awaiting) != 1 || awaiting[0].ID != leave.ID {
+			t.Fatalf("awaiting = %+v, want alice's leave", awaiting)
+		}
+
+		now := time.Now().Truncate(time.Second)
+		remind := func(want bool) {
+			t.Helper()
+			if ok, err := env.store.MarkLeaveReminded(leave.ID, now); err != nil || ok != want {
+				t.Errorf("remind = %v, err %v; want %v", ok, err, want)
+			}
+		}
+		remind(true)
+		remind(false)
+
+		// Up to the manager's manager, and then to admins
+		if err := env.store.EscalateLeave(leave.ID, now); err != nil {
+			t.Fatal(err)
+		}
+		got, err := env.store.GetLeaveByID(leave.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ApproverID == nil || *got.ApproverID != env.admin.ID || got.EscalatedAt != nil || got.RemindedAt != nil {
+			t.Errorf("after escalating = %+v, want it routed to the admin with a fresh clock", got)
+		}
+		remind(true)
+		if err := env.store.EscalateLeave(leave.ID, now); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = env.store.GetLeaveByID(leave.ID); err != nil || got.EscalatedAt == nil {
+			t.Errorf("after escalating twice = %+v, err %v; want it with admins", got, err)
+		}
+		if err := env.store.EscalateLeave(leave.ID, now); !errors.Is(err, ErrLeaveNotPending) {
+			t.Errorf("escalate past admins: err = %v, want ErrLeaveNotPending", err)
+		}
+
+		if err := env.store.AutoApproveLeave(leave.ID, "approved after no reply"); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = env.store.GetLeaveByID(leave.ID); err != nil || got.Status != string(constants.LeaveStatusApproved) {
+			t.Errorf("after auto-approval = %+v, err %v; want approved", got, err)
+		}
+		if got := env.balance(env.alice, constants.LeaveTypeAnnual, 2030); got.Used != 2 {
+			t.Errorf("balance = %+v, want the auto-approved days used", got)
+		}
+		if awaiting, err := env.store.GetAwaitingLeaves(); err != nil || len(awaiting) != 0 {
+			t.Errorf("awaiting = %+v, err %v; want nothing", awaiting, err)
+		}
+		remind(false)
+	})
+
+	t.Run("comp-off credits are spent oldest first", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		var credits []*models.CompOffCredit
+		for _, saturday := range []string{date(monday(-2), 5), date(monday(-1), 5)} {
+			credit := &models.CompOffCredit{UserID: env.alice.ID, WorkDate: saturday, Days: 1, Reason: "release"}
+			if err := env.store.CreateCompOffCredit(env.alice.ID, credit); err != nil {
+				t.Fatal(err)
+			}
+			if err := env.store.DecideCompOffCredit(credit.ID, env.manager, true, nil); err != nil {
+				t.Fatal(err)
+			}
+			credits = append(credits, credit)
+		}
+		if err := env.store.CreateCompOffCredit(env.alice.ID, &models.CompOffCredit{UserID: env.alice.ID, WorkDate: date(monday(-1), 5), Days: 1}); !errors.Is(err, ErrDuplicateCompOff) {
+			t.Errorf("claim the same day twice: err = %v, want ErrDuplicateCompOff", err)
+		}
+
+		year := time.Now().Year()
+		day := date(monday(1), 0)
+		leave := env.file(env.alice, constants.LeaveTypeCompOff, day, day)
+		if got := env.balance(env.alice, constants.LeaveTypeCompOff, year); got.Allowance != 2 || got.Pending != 1 || got.Remaining != 1 {
+			t.Errorf("while pending = %+v, want 1 of 2 days pending", got)
+		}
+		env.setStatus(leave, constants.LeaveStatusApproved)
+		if got := env.balance(env.alice, constants.LeaveTypeCompOff, year); got.Used != 1 || got.Pending != 0 || got.Remaining != 1 {
+			t.Errorf("once approved = %+v, want 1 of 2 days used", got)
+		}
+		remaining := func() []float64 {
+			t.Helper()
+			var left []float64
+			for _, c := range credits {
+				got, err := env.store.GetCompOffCredit(c.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				left = append(left, got.Remaining)
+			}
+			return left
+		}
+		if got := remaining(); !slices.Equal(got, []float64{0, 1}) {
+			t.Errorf("remaining = %v, want the older credit spent", got)
+		}
+
+		env.setStatus(leave, constants.LeaveStatusCancellationRequested)
+		env.setStatus(leave, constants.LeaveStatusCancelled)
+		if got := remaining(); !slices.Equal(got, []float64{1, 1}) {
+			t.Errorf("after cancelling = %v, want both credits whole", got)
+		}
+
+		err := env.store.CreateLeave(env.alice.ID, &models.Leave{
+			UserID: env.alice.ID, Type: string(constants.LeaveTypeCompOff), StartDate: day, EndDate: date(monday(1), 2),
+			Portion: string(constants.LeavePortionFull), Reason: "away", Status: string(constants.LeaveStatusPending),
+		})
+		var insufficient *InsufficientBalanceError
+		if !errors.As(err, &insufficient) {
+			t.Errorf("three days from two credits: err = %v, want InsufficientBalanceError", err)
+		}
+	})
+
+	t.Run("analytics count approved days by month, team and sick spell", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		env.setStatus(env.file(env.alice, constants.LeaveTypeAnnual, "2030-03-28", "2030-04-02"), constants.LeaveStatusApproved)
+		env.setStatus(env.file(env.bob, constants.LeaveTypeAnnual, "2030-03-04", "2030-03-05"), constants.LeaveStatusRejected)
+		halfDay := &models.Leave{
+			UserID: env.bob.ID, Type: string(constants.LeaveTypeSick), StartDate: "2030-03-12", EndDate: "2030-03-12",
+			Portion: string(constants.LeavePortionFirstHalf), Reason: "dentist", Status: string(constants.LeaveStatusPending),
+		}
+		start, end := "09:00", "13:00"
+		hours := &models.Leave{
+			UserID: env.alice.ID, Type: string(constants.LeaveTypeCasual), StartDate: "2030-05-06", EndDate: "2030-05-06",
+			Portion: string(constants.LeavePortionHourly), StartTime: &start, EndTime: &end, Reason: "errand", Status: string(constants.LeaveStatusPending),
+		}
+		for _, leave := range []*models.Leave{halfDay, hours} {
+			if err := env.store.CreateLeave(leave.UserID, leave); err != nil {
+				t.Fatal(err)
+			}
+			env.setStatus(leave, constants.LeaveStatusApproved)
+		}
+
+		// Bradford factors look back from today: bob's first two days are
+		// one spell with only a weekend between, and alice's older leave
+		// is out of the window
+		base := monday(-5)
+		for _, day := range []string{date(base, -3), date(base, 0), date(base, 14)} {
+			env.setStatus(env.file(env.bob, constants.LeaveTypeSick, day, day), constants.LeaveStatusApproved)
+		}
+		env.setStatus(env.file(env.alice, constants.LeaveTypeSick, date(base, 7), date(base, 7)), constants.LeaveStatusApproved)
+		old := monday(-constants.BradfordWeeks - 2)
+		env.setStatus(env.file(env.alice, constants.LeaveTypeSick, date(old, 0), date(old, 1)), constants.LeaveStatusApproved)
+
+		got, err := env.store.GetLeaveAnalytics("2030-03-01", "2030-06-30")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Months) != 4 {
+			t.Fatalf("months = %+v, want March to June", got.Months)
+		}
+		wantMonths := []models.MonthlyLeaveDays{
+			{Month: "2030-03", Days: map[string]float64{"annual": 2, "sick": 0.5}, Total: 2.5},
+			{Month: "2030-04", Days: map[string]float64{"annual": 2}, Total: 2},
+			{Month: "2030-05", Days: map[string]float64{"casual": 0.5}, Total: 0.5},
+			{Month: "2030-06", Days: map[string]float64{}, Total: 0},
+		}
+		for i, want := range wantMonths {
+			m := got.Months[i]
+			if m.Month != want.Month || m.Total != want.Total || len(m.Days) != len(want.Days) {
+				t.Errorf("months[%d] = %+v, want %+v", i, m, want)
+				continue
+			}
+			for leaveType, days := range want.Days {
+				if m.Days[leaveType] != days {
+					t.Errorf("months[%d] = %+v, want %+v", i, m, want)
+				}
+			}
+		}
+
+		i := slices.IndexFunc(got.Teams, func(team models.TeamLeaveDays) bool {
+			return team.ManagerID != nil && *team.ManagerID == env.manager.ID
+		})
+		if i < 0 {
+			t.Fatalf("teams = %+v, want the manager's", got.Teams)
+		}
+		if team := got.Teams[i]; team.ManagerEmail != env.manager.Email || team.Members != 2 || team.Days["annual"] != 4 || team.Total != 5 {
+			t.Errorf("manager's team = %+v, want alice and bob's 5 days", team)
+		}
+		if last := got.Teams[len(got.Teams)-1]; last.ManagerID != nil {
+			t.Errorf("last team = %+v, want the one without a manager", last)
+		}
+
+		want := []models.BradfordScore{
+			{UserID: env.bob.ID, UserEmail: env.bob.Email, Spells: 2, Days: 3, Score: 12},
+			{UserID: env.alice.ID, UserEmail: env.alice.Email, Spells: 1, Days: 1, Score: 1},
+		}
+		if !slices.Equal(got.Bradford, want) {
+			t.Errorf("bradford = %+v, want %+v", got.Bradford, want)
+		}
+		if got.BradfordTo != date(time.Now().UTC(), 0) {
+			t.Errorf("bradfordTo = %s, want today", got.BradfordTo)
+		}
+
+		if again, err := env.store.GetLeaveAnalytics("2030-03-01", "2030-06-30"); err != nil || !again.GeneratedAt.Equal(got.GeneratedAt) {
+			t.Errorf("again: generatedAt = %v, err %v; want the cached %v", again.GeneratedAt, err, got.GeneratedAt)
+		}
+		env.user("carol@example.org", constants.RoleUser, &env.manager.ID)
+		if again, err := env.store.GetLeaveAnalytics("2030-03-01", "2030-06-30"); err != nil || again.GeneratedAt.Equal(got.GeneratedAt) {
+			t.Errorf("after a new user: generatedAt = %v, err %v; want fresh analytics", again.GeneratedAt, err)
+		}
+	})
+
+	t.Run("reports stream balances and leave", func(t *testing.T) {
+		env := newStoreEnv(t, open(t))
+		approved := env.file(env.alice, constants.LeaveTypeAnnual, "2030-03-04", "2030-03-06")
+		if _, err := env.store.DecideLeave(approved.ID, env.manager, models.LeaveDecision{Approve: true}); err != nil {
+			t.Fatal(err)
+		}
+		env.file(env.bob, constants.LeaveTypeAnnual, "2030-03-11", "2030-03-11")
+
+		balances := make(map[string]models.BalanceReportRow)
+		err := env.store.StreamBalanceReport(models.BalanceReportFilter{Year: 2030, Type: string(constants.LeaveTypeAnnual)}, func(row models.BalanceReportRow) error {
+			if row.Type != string(constants.LeaveTypeAnnual) {
+				t.Errorf("row %+v: want only annual leave", row)
+			}
+			balances[row.UserID] = row
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := balances[env.alice.ID]; got.Email != env.alice.Email || got.Allowance != 20 || got.Used != 3 || got.Remaining != 17 {
+			t.Errorf("alice = %+v, want 3 of 20 days used", got)
+		}
+		if got := balances[env.bob.ID]; got.Used != 0 || got.Pending != 1 || got.Remaining != 19 {
+			t.Errorf("bob = %+v, want 1 day pending", got)
+		}
+
+		var rows []models.LeaveReportRow
+		err = env.store.StreamLeaveReport(models.LeaveReportFilter{From: "2030-03-01", To: "2030-03-31"}, func(row models.LeaveReportRow) error {
+			rows = append(rows, row)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("leave report = %+v, want alice's and bob's leave", rows)
+		}
+		for i, want := range []struct {
+			user   *models.User
+			status constants.LeaveStatus
+		}{{env.alice, constants.LeaveStatusApproved}, {env.bob, constants.LeaveStatusPending}} {
+			row := rows[i]
+			if row.Leave.UserID != want.user.ID || row.Leave.Status != string(want.status) || row.Approver == nil || *row.Approver != env.manager.Email {
+				t.Errorf("row %d = %+v approved by %v, want %s's %s leave with the manager", i, row.Leave, row.Approver, want.user.Email, want.status)
+			}
+		}
+	})
+}
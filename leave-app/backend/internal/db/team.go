@@ -0,0 +1,87 @@
+// internal/db/team.go
+package db
+
+import (
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+)
+
+var ErrInvalidManager = errors.New("invalid manager")
+
+// canManage reports whether a user with role may have reports
+func canManage(role string) bool {
+	return role == string(constants.RoleManager) || role == string(constants.RoleAdmin)
+}
+
+// SetUserManager sets or clears a user's manager and re-routes the user's
+// pending leaves to the new manager. The manager must hold the manager or
+// admin role and the change must not create a reporting cycle.
+func (db *Database) SetUserManager(actorID string, userID string, managerID *string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockUser(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if managerID != nil {
+		if *managerID == userID {
+			return ErrInvalidManager
+		}
+		var role string
+		if err := tx.QueryRow("SELECT role FROM users WHERE id = ?", *managerID).Scan(&role); err != nil {
+			return ErrInvalidManager
+		}
+		if !canManage(role) {
+			return ErrInvalidManager
+		}
+
+		// Walk up from the new manager; reaching userID would form a cycle
+		seen := map[string]bool{}
+		for next := managerID; next != nil; {
+			if *next == userID {
+				return ErrInvalidManager
+			}
+			if seen[*next] {
+				break
+			}
+			seen[*next] = true
+			var parent *string
+			if err := tx.QueryRow("SELECT manager_id FROM users WHERE id = ?", *next).Scan(&parent); err != nil {
+				return err
+			}
+			next = parent
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE users SET manager_id = ? WHERE id = ?", managerID, userID); err != nil {
+		return err
+	}
+	query := "UPDATE leaves SET approver_id = ? WHERE user_id = ? AND status = ?"
+	if _, err := tx.Exec(query, managerID, userID, string(constants.LeaveStatusPending)); err != nil {
+		return err
+	}
+	after := *before
+	after.ManagerID = managerID
+	if err := recordAudit(tx, actorID, constants.AuditUserManager, userID, before, after); err != nil {
+		return err
+	}
+	return db.commitLeaves(tx)
+}
+
+// GetTeamLeaves returns the leaves routed to approverID
+func (db *Database) GetTeamLeaves(approverID string) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.approver_id = ?
+		ORDER BY l.created_at DESC
+	`
+	return db.queryLeaves(db.Conn, query, approverID)
+}
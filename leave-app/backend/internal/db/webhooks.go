@@ -0,0 +1,450 @@
+// internal/db/webhooks.go
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newWebhookSecret returns a random signing secret for subscriptions
+// created without one
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// redactSecret returns a copy of sub safe to audit or list
+func redactSecret(sub models.WebhookSubscription) models.WebhookSubscription {
+	sub.Secret = ""
+	return sub
+}
+
+// webhookEventFor returns the event raised by a status change, if any. A
+// refused cancellation returns the leave to approved without a new event.
+func webhookEventFor(from, to string) (constants.WebhookEvent, bool) {
+	switch {
+	case to == string(constants.LeaveStatusApproved) && from == string(constants.LeaveStatusPending):
+		return constants.WebhookLeaveApproved, true
+	case to == string(constants.LeaveStatusRejected):
+		return constants.WebhookLeaveRejected, true
+	case to == string(constants.LeaveStatusCancelled):
+		return constants.WebhookLeaveCancelled, true
+	}
+	return "", false
+}
+
+// webhookBackoff is the delay before retrying a delivery that has failed
+// attempts times
+func webhookBackoff(attempts int) time.Duration {
+	delay := constants.WebhookRetryBaseSeconds * time.Second
+	for i := 1; i < attempts && delay < constants.WebhookRetryMaxSeconds*time.Second; i++ {
+		delay *= 2
+	}
+	return min(delay, constants.WebhookRetryMaxSeconds*time.Second)
+}
+
+// webhookOutcome works out a delivery's state after an attempt that got
+// statusCode (0 if no response arrived)
+func webhookOutcome(d *models.WebhookDelivery, statusCode int, errMsg string, at time.Time) {
+	d.Attempts++
+	d.LastStatusCode, d.LastError = nil, nil
+	if statusCode != 0 {
+		d.LastStatusCode = &statusCode
+	}
+	if errMsg != "" {
+		d.LastError = &errMsg
+	}
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		d.Status = string(constants.WebhookStatusSucceeded)
+		d.NextAttemptAt = nil
+		d.DeliveredAt = &at
+	case d.Attempts >= constants.WebhookMaxAttempts:
+		d.Status = string(constants.WebhookStatusFailed)
+		d.NextAttemptAt = nil
+	default:
+		next := at.Add(webhookBackoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+}
+
+// newWebhookDelivery builds the outbox row for one subscription
+func newWebhookDelivery(subscriptionID string, event constants.WebhookEvent, leave *models.Leave, now time.Time) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: subscriptionID,
+		Event:          string(event),
+		Status:         string(constants.WebhookStatusPending),
+		NextAttemptAt:  &now,
+		CreatedAt:      now,
+	}
+	payload, err := json.Marshal(models.WebhookPayload{ID: d.ID, Event: d.Event, CreatedAt: now, Leave: *leave})
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return d, nil
+}
+
+// enqueueWebhooks queues event for every enabled subscription to it, in
+// the caller's transaction, so it is only sent if the change commits
+func enqueueWebhooks(q querier, event constants.WebhookEvent, leave *models.Leave) error {
+	query := `
+		SELECT s.id FROM webhook_subscriptions s
+		JOIN webhook_subscription_events e ON e.subscription_id = s.id
+		WHERE e.event = ? AND NOT s.disabled
+	`
+	rows, err := q.Query(query, string(event))
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+	if leave.UserEmail == "" {
+		withEmail := *leave
+		if err := q.QueryRow("SELECT email FROM users WHERE id = ?", leave.UserID).Scan(&withEmail.UserEmail); err != nil {
+			return err
+		}
+		leave = &withEmail
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		d, err := newWebhookDelivery(id, event, leave, now)
+		if err != nil {
+			return err
+		}
+		query := "INSERT INTO webhook_deliveries (id, subscription_id, event, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+		if _, err := q.Exec(query, d.ID, d.SubscriptionID, d.Event, string(d.Payload), d.Status, now, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setWebhookEvents(q querier, subscriptionID string, events []string) error {
+	if _, err := q.Exec("DELETE FROM webhook_subscription_events WHERE subscription_id = ?", subscriptionID); err != nil {
+		return err
+	}
+	for _, event := range events {
+		if _, err := q.Exec("INSERT INTO webhook_subscription_events (subscription_id, event) VALUES (?, ?)", subscriptionID, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getWebhookSubscriptions reads subscriptions with their events, all of
+// them when id is empty
+func getWebhookSubscriptions(q querier, id string) ([]models.WebhookSubscription, error) {
+	query := "SELECT s.id, s.url, s.secret, s.disabled, s.created_at, COALESCE(GROUP_CONCAT(e.event ORDER BY e.event), '') FROM webhook_subscriptions s LEFT JOIN webhook_subscription_events e ON e.subscription_id = s.id"
+	var args []any
+	if id != "" {
+		query += " WHERE s.id = ?"
+		args = append(args, id)
+	}
+	query += " GROUP BY s.id ORDER BY s.created_at, s.id"
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]models.WebhookSubscription, 0)
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		var events string
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.Disabled, &sub.CreatedAt, &events); err != nil {
+			return nil, err
+		}
+		sub.Events = []string{}
+		if events != "" {
+			sub.Events = strings.Split(events, ",")
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// lockWebhookSubscription reads one subscription for update, returning
+// sql.ErrNoRows if there is none
+func lockWebhookSubscription(q querier, id string) (*models.WebhookSubscription, error) {
+	if err := q.QueryRow("SELECT id FROM webhook_subscriptions WHERE id = ? FOR UPDATE", id).Scan(&id); err != nil {
+		return nil, err
+	}
+	subs, err := getWebhookSubscriptions(q, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &subs[0], nil
+}
+
+// GetWebhookSubscriptions lists subscriptions without their secrets
+func (db *Database) GetWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	subs, err := getWebhookSubscriptions(db.Conn, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i] = redactSecret(subs[i])
+	}
+	return subs, nil
+}
+
+// CreateWebhookSubscription stores sub, generating its ID and, if it has
+// none, its secret
+func (db *Database) CreateWebhookSubscription(actorID string, sub *models.WebhookSubscription) error {
+	if sub.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		sub.Secret = secret
+	}
+	sub.ID = uuid.New().String()
+	sub.Events = sortedUnique(sub.Events)
+	sub.CreatedAt = time.Now()
+
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO webhook_subscriptions (id, url, secret, disabled, created_at) VALUES (?, ?, ?, ?, ?)"
+	if _, err := tx.Exec(query, sub.ID, sub.URL, sub.Secret, sub.Disabled, sub.CreatedAt); err != nil {
+		return err
+	}
+	if err := setWebhookEvents(tx, sub.ID, sub.Events); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditWebhookCreate, sub.ID, nil, redactSecret(*sub)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateWebhookSubscription replaces a subscription's settings. An empty
+// secret keeps the current one. Deliveries already queued are unaffected.
+func (db *Database) UpdateWebhookSubscription(actorID string, id string, sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWebhookSubscription(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	after := sub
+	after.ID, after.CreatedAt = before.ID, before.CreatedAt
+	after.Events = sortedUnique(sub.Events)
+	if after.Secret == "" {
+		after.Secret = before.Secret
+	}
+
+	query := "UPDATE webhook_subscriptions SET url = ?, secret = ?, disabled = ? WHERE id = ?"
+	if _, err := tx.Exec(query, after.URL, after.Secret, after.Disabled, id); err != nil {
+		return nil, err
+	}
+	if err := setWebhookEvents(tx, id, after.Events); err != nil {
+		return nil, err
+	}
+	after = redactSecret(after)
+	if err := recordAudit(tx, actorID, constants.AuditWebhookUpdate, id, redactSecret(*before), after); err != nil {
+		return nil, err
+	}
+	return &after, tx.Commit()
+}
+
+// DeleteWebhookSubscription removes a subscription with its delivery log
+func (db *Database) DeleteWebhookSubscription(actorID string, id string) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWebhookSubscription(tx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM webhook_subscriptions WHERE id = ?", id); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, constants.AuditWebhookDelete, id, redactSecret(*before), nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const webhookDeliveryColumns = "d.id, d.subscription_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at"
+
+func scanWebhookDelivery(row rowScanner, extra ...any) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	var payload []byte
+	var next time.Time
+	dest := append([]any{&d.ID, &d.SubscriptionID, &d.Event, &payload, &d.Status, &d.Attempts, &next, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	if d.Status == string(constants.WebhookStatusPending) {
+		d.NextAttemptAt = &next
+	}
+	return d, nil
+}
+
+// GetWebhookDeliveries returns a subscription's delivery log, newest
+// first, optionally only deliveries with status
+func (db *Database) GetWebhookDeliveries(subscriptionID string, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	var exists bool
+	if err := db.Conn.QueryRow("SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = ?)", subscriptionID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries d WHERE d.subscription_id = ?"
+	args := []any{subscriptionID}
+	if filter.Status != "" {
+		query += " AND d.status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY d.created_at DESC, d.id DESC LIMIT ?"
+	args = append(args, pageLimit(filter.Limit))
+
+	rows, err := db.Conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+// due, with their subscription's URL and secret. Claimed rows are pushed
+// back by WebhookClaimSeconds so other replicas skip them while they are
+// being sent; a replica that dies mid-send leaves them to be retried.
+func (db *Database) ClaimWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = ? AND d.next_attempt_at <= ? AND NOT s.disabled
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?
+		FOR UPDATE OF d SKIP LOCKED
+	`
+	rows, err := tx.Query(query, string(constants.WebhookStatusPending), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var url, secret string
+		d, err := scanWebhookDelivery(rows, &url, &secret)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.URL, d.Secret = url, secret
+		deliveries = append(deliveries, *d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	claimedUntil := now.Add(constants.WebhookClaimSeconds * time.Second)
+	for _, d := range deliveries {
+		if _, err := tx.Exec("UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?", claimedUntil, d.ID); err != nil {
+			return nil, err
+		}
+	}
+	return deliveries, tx.Commit()
+}
+
+// RecordWebhookAttempt stores the outcome of sending a delivery:
+// statusCode is 0 when no response arrived. Non-2xx responses are retried
+// with exponential backoff until WebhookMaxAttempts.
+func (db *Database) RecordWebhookAttempt(deliveryID string, statusCode int, errMsg string, at time.Time) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	d, err := scanWebhookDelivery(tx.QueryRow("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries d WHERE d.id = ? FOR UPDATE", deliveryID))
+	if err != nil {
+		return err
+	}
+	webhookOutcome(d, statusCode, errMsg, at)
+
+	next := at
+	if d.NextAttemptAt != nil {
+		next = *d.NextAttemptAt
+	}
+	query := "UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ? WHERE id = ?"
+	if _, err := tx.Exec(query, d.Status, d.Attempts, next, d.LastStatusCode, d.LastError, d.DeliveredAt, deliveryID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sortedWebhookDeliveries orders a delivery log newest first
+func sortedWebhookDeliveries(deliveries []models.WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+}
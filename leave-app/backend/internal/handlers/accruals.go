@@ -0,0 +1,119 @@
+// internal/handlers/accruals.go
+package handlers
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAccrualPolicies handles GET /api/admin/accrual-policies
+func (h *Handler) GetAccrualPolicies(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	policies, err := h.DB.GetAccrualPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get accrual policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// UpdateAccrualPolicies handles PUT /api/admin/accrual-policies
+func (h *Handler) UpdateAccrualPolicies(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var req models.UpdateAccrualPoliciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	known, err := h.leaveTypeCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leave types"})
+		return
+	}
+	seen := make(map[string]bool)
+	for _, p := range req.Policies {
+		if !known[p.LeaveType] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown leave type " + p.LeaveType})
+			return
+		}
+		if seen[p.LeaveType] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate policy for " + p.LeaveType})
+			return
+		}
+		seen[p.LeaveType] = true
+	}
+
+	if err := h.DB.ReplaceAccrualPolicies(currentUser.ID, req.Policies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update accrual policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, req.Policies)
+}
+
+// RunAccruals handles POST /api/admin/accruals/run. The scheduled job does
+// the same daily; running it by hand for a date already processed is safe.
+func (h *Handler) RunAccruals(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var req models.RunAccrualsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// We can ignore the error if the body is empty, asOf is optional
+	}
+
+	asOf := time.Now()
+	if req.AsOf != "" {
+		var err error
+		if asOf, err = time.ParseInLocation(workdays.DateLayout, req.AsOf, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "asOf must be a YYYY-MM-DD date"})
+			return
+		}
+	}
+
+	run, err := h.DB.RunAccruals(currentUser.ID, asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run accruals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
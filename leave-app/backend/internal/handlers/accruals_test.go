@@ -0,0 +1,120 @@
+// internal/handlers/accruals_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func (e *testEnv) setAccrualPolicies(policies ...models.AccrualPolicy) {
+	e.t.Helper()
+	rec := e.do(http.MethodPut, "/api/admin/accrual-policies", e.admin, models.UpdateAccrualPoliciesRequest{Policies: policies})
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("set accrual policies: status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func (e *testEnv) runAccruals(asOf string) models.AccrualRun {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/admin/accruals/run", e.admin, models.RunAccrualsRequest{AsOf: asOf})
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("run accruals: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var run models.AccrualRun
+	if err := json.Unmarshal(rec.Body.Bytes(), &run); err != nil {
+		e.t.Fatal(err)
+	}
+	return run
+}
+
+func (e *testEnv) annualBalance(user *models.User, year int) models.LeaveBalance {
+	e.t.Helper()
+	balances, err := e.store.GetLeaveBalances(user.ID, year)
+	if err != nil {
+		e.t.Fatalf("balances: %v", err)
+	}
+	return balances[string(constants.LeaveTypeAnnual)]
+}
+
+func TestAccruals(t *testing.T) {
+	t.Run("monthly accrual with carry-forward", func(t *testing.T) {
+		env := newTestEnv(t)
+		joiner := env.store.AddUser(models.User{
+			Email:      "joiner@example.com",
+			Role:       string(constants.RoleUser),
+			Allowances: models.Allowance{Annual: 20},
+			CreatedAt:  time.Date(2029, time.July, 15, 0, 0, 0, 0, time.Local),
+		})
+		env.setAccrualPolicies(models.AccrualPolicy{LeaveType: "annual", Frequency: "monthly", CarryForwardCap: 5})
+
+		env.runAccruals("2029-12-01")
+		// July to December of a 20 day entitlement
+		if got := env.annualBalance(joiner, 2029).Allowance; got != 10 {
+			t.Errorf("2029 allowance = %v, want 10", got)
+		}
+		if again := env.runAccruals("2029-12-01"); again.Accrued != 0 || again.CarriedForward != 0 {
+			t.Errorf("second run = %+v, want no changes", again)
+		}
+
+		// 10 days left: 5 carried forward, 5 expire
+		env.runAccruals("2030-01-01")
+		balance := env.annualBalance(joiner, 2030)
+		if balance.CarriedForward != 5 || balance.Allowance != 6.67 {
+			t.Errorf("2030 balance = %+v, want 5 carried of 6.67", balance)
+		}
+		if got := env.annualBalance(joiner, 2029).Remaining; got != 0 {
+			t.Errorf("2029 remaining after rollover = %v, want 0", got)
+		}
+		if again := env.runAccruals("2030-01-01"); again.CarriedForward != 0 || again.Accrued != 0 {
+			t.Errorf("second rollover = %+v, want no changes", again)
+		}
+	})
+
+	t.Run("yearly accrual is pro-rated for joiners", func(t *testing.T) {
+		env := newTestEnv(t)
+		joiner := env.store.AddUser(models.User{
+			Email:      "joiner@example.com",
+			Role:       string(constants.RoleUser),
+			Allowances: models.Allowance{Annual: 20},
+			CreatedAt:  time.Date(2030, time.April, 15, 0, 0, 0, 0, time.Local),
+		})
+		env.setAccrualPolicies(models.AccrualPolicy{LeaveType: "annual", Frequency: "yearly"})
+
+		env.runAccruals("2030-06-01")
+		if got := env.annualBalance(joiner, 2030).Allowance; got != 15 {
+			t.Errorf("allowance = %v, want 15", got)
+		}
+	})
+
+	t.Run("leave is checked against accrued days", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.setAccrualPolicies(models.AccrualPolicy{LeaveType: "annual", Frequency: "monthly"})
+		env.runAccruals("2030-01-01")
+
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-01-07", EndDate: "2030-01-09", Reason: "trip",
+		})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+		}
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		env := newTestEnv(t)
+		req := models.UpdateAccrualPoliciesRequest{Policies: []models.AccrualPolicy{{LeaveType: "annual", Frequency: "weekly"}}}
+		if rec := env.do(http.MethodPut, "/api/admin/accrual-policies", env.admin, req); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec := env.do(http.MethodPost, "/api/admin/accruals/run", env.manager, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
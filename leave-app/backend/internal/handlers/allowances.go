@@ -0,0 +1,137 @@
+// internal/handlers/allowances.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAllowancePolicies handles GET /api/admin/allowance-policies
+func (h *Handler) GetAllowancePolicies(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	policies, err := h.DB.GetAllowancePolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get allowance policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreateAllowancePolicy handles POST /api/admin/allowance-policies
+func (h *Handler) CreateAllowancePolicy(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var policy models.AllowancePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.CreateAllowancePolicy(currentUser.ID, &policy); err != nil {
+		if errors.Is(err, db.ErrDuplicatePolicy) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create allowance policy"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// AssignAllowancePolicy handles PUT /api/admin/allowance-policies/:id/assignments
+func (h *Handler) AssignAllowancePolicy(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var req models.AssignAllowancePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.DB.AssignAllowancePolicy(currentUser.ID, c.Param("id"), req.Users, req.Groups)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrUnknownUser):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Allowance policy not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign allowance policy"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateUserGroups handles PUT /api/users/:id/groups
+func (h *Handler) UpdateUserGroups(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var req models.UpdateUserGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups, err := h.DB.SetUserGroups(currentUser.ID, c.Param("id"), req.Groups)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
@@ -0,0 +1,142 @@
+// internal/handlers/allowances_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"testing"
+)
+
+func (e *testEnv) createAllowancePolicy(name string, priority int, annual int) models.AllowancePolicy {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/admin/allowance-policies", e.admin, models.AllowancePolicy{
+		Name: name, Priority: priority, Allowances: models.Allowance{Sick: 10, Annual: annual, Casual: 5},
+	})
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("create allowance policy: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var policy models.AllowancePolicy
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		e.t.Fatal(err)
+	}
+	return policy
+}
+
+func (e *testEnv) assignPolicy(policy models.AllowancePolicy, req models.AssignAllowancePolicyRequest) {
+	e.t.Helper()
+	rec := e.do(http.MethodPut, "/api/admin/allowance-policies/"+policy.ID+"/assignments", e.admin, req)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("assign policy: status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func (e *testEnv) setGroups(user *models.User, groups ...string) {
+	e.t.Helper()
+	rec := e.do(http.MethodPut, "/api/users/"+user.ID+"/groups", e.admin, models.UpdateUserGroupsRequest{Groups: groups})
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("set groups: status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func (e *testEnv) me(user *models.User) models.User {
+	e.t.Helper()
+	rec := e.do(http.MethodGet, "/api/me", user, nil)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("me: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var me models.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &me); err != nil {
+		e.t.Fatal(err)
+	}
+	return me
+}
+
+func TestAllowancePolicies(t *testing.T) {
+	t.Run("group policy applies to members", func(t *testing.T) {
+		env := newTestEnv(t)
+		india := env.createAllowancePolicy("India", 0, 24)
+		env.assignPolicy(india, models.AssignAllowancePolicyRequest{Groups: []string{"in"}})
+		env.setGroups(env.alice, "in")
+
+		me := env.me(env.alice)
+		if me.EffectivePolicy == nil || me.EffectivePolicy.Source != string(constants.AllowanceSourceGroup) ||
+			me.EffectivePolicy.PolicyID != india.ID || me.EffectivePolicy.Group != "in" {
+			t.Fatalf("effective policy = %+v, want India through group in", me.EffectivePolicy)
+		}
+		if me.Allowances.Annual != 24 {
+			t.Errorf("annual allowance = %d, want 24", me.Allowances.Annual)
+		}
+		if got := env.annualBalance(env.alice, 2030).Allowance; got != 24 {
+			t.Errorf("annual balance allowance = %v, want 24", got)
+		}
+		if got := env.me(env.bob).EffectivePolicy.Source; got != string(constants.AllowanceSourceDefault) {
+			t.Errorf("bob source = %q, want default", got)
+		}
+	})
+
+	t.Run("user assignment beats groups and priority orders groups", func(t *testing.T) {
+		env := newTestEnv(t)
+		low := env.createAllowancePolicy("Low", 1, 12)
+		high := env.createAllowancePolicy("High", 5, 30)
+		personal := env.createAllowancePolicy("Personal", 0, 2)
+		env.assignPolicy(low, models.AssignAllowancePolicyRequest{Groups: []string{"contractors"}})
+		env.assignPolicy(high, models.AssignAllowancePolicyRequest{Groups: []string{"seniors"}})
+		env.setGroups(env.alice, "contractors", "seniors")
+
+		if got := env.me(env.alice).EffectivePolicy.PolicyID; got != high.ID {
+			t.Errorf("policy = %q, want High (%s)", got, high.ID)
+		}
+
+		env.assignPolicy(personal, models.AssignAllowancePolicyRequest{Users: []string{env.alice.ID}})
+		me := env.me(env.alice)
+		if me.EffectivePolicy.PolicyID != personal.ID || me.EffectivePolicy.Source != string(constants.AllowanceSourceUser) {
+			t.Errorf("effective policy = %+v, want Personal assigned to the user", me.EffectivePolicy)
+		}
+
+		// The effective allowance bounds new requests
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-06", Reason: "trip",
+		})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("leave beyond policy allowance: status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("duplicate names conflict", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.createAllowancePolicy("India", 0, 24)
+		rec := env.do(http.MethodPost, "/api/admin/allowance-policies", env.admin, models.AllowancePolicy{Name: "India"})
+		if rec.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("unknown users and policies are refused", func(t *testing.T) {
+		env := newTestEnv(t)
+		policy := env.createAllowancePolicy("India", 0, 24)
+		rec := env.do(http.MethodPut, "/api/admin/allowance-policies/"+policy.ID+"/assignments", env.admin,
+			models.AssignAllowancePolicyRequest{Users: []string{"missing"}})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown user: status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+		rec = env.do(http.MethodPut, "/api/admin/allowance-policies/missing/assignments", env.admin, models.AssignAllowancePolicyRequest{})
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("unknown policy: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		for _, req := range []struct{ method, path string }{
+			{http.MethodGet, "/api/admin/allowance-policies"},
+			{http.MethodPost, "/api/admin/allowance-policies"},
+			{http.MethodPut, "/api/users/" + env.alice.ID + "/groups"},
+		} {
+			if rec := env.do(req.method, req.path, env.manager, nil); rec.Code != http.StatusForbidden {
+				t.Errorf("%s %s: status = %d, want %d", req.method, req.path, rec.Code, http.StatusForbidden)
+			}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+// internal/handlers/analytics.go
+package handlers
+
+import (
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLeaveAnalytics handles GET /api/admin/analytics
+func (h *Handler) GetLeaveAnalytics(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var filter models.AnalyticsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To == "" {
+		filter.To = time.Now().Format(workdays.DateLayout)
+	}
+	if to, err := time.Parse(workdays.DateLayout, filter.To); err == nil && filter.From == "" {
+		filter.From = to.AddDate(-1, 0, 0).Format(workdays.DateLayout)
+	}
+
+	analytics, err := h.DB.GetLeaveAnalytics(filter.From, filter.To)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidDateRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD dates with from on or before to, at most " + strconv.Itoa(constants.MaxAnalyticsDays) + " days apart"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leave analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
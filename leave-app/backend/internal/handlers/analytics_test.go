@@ -0,0 +1,188 @@
+// internal/handlers/analytics_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// analytics fetches GET /api/admin/analytics with query as the admin
+func (e *testEnv) analytics(query string) models.LeaveAnalytics {
+	e.t.Helper()
+	rec := e.do(http.MethodGet, "/api/admin/analytics"+query, e.admin, nil)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("analytics: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var analytics models.LeaveAnalytics
+	if err := json.Unmarshal(rec.Body.Bytes(), &analytics); err != nil {
+		e.t.Fatal(err)
+	}
+	return analytics
+}
+
+// seedSick files a sick leave for user and approves it
+func (e *testEnv) seedSick(user *models.User, start, end string) {
+	e.t.Helper()
+	leave := &models.Leave{
+		UserID:    user.ID,
+		Type:      string(constants.LeaveTypeSick),
+		StartDate: start,
+		EndDate:   end,
+		Portion:   string(constants.LeavePortionFull),
+		Reason:    "flu",
+		Status:    string(constants.LeaveStatusPending),
+	}
+	if err := e.store.CreateLeave(user.ID, leave); err != nil {
+		e.t.Fatalf("seed sick leave: %v", err)
+	}
+	if err := e.store.UpdateLeaveStatus(e.admin.ID, leave.ID, string(constants.LeaveStatusApproved), nil); err != nil {
+		e.t.Fatalf("approve sick leave: %v", err)
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	t.Run("approved days are counted by month and team", func(t *testing.T) {
+		env := newTestEnv(t)
+		for _, u := range []*models.User{env.alice, env.bob} {
+			if err := env.store.SetUserManager(env.admin.ID, u.ID, &env.manager.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		env.seedLeave(env.alice, "2030-03-28", "2030-04-02", constants.LeaveStatusApproved)
+		env.seedLeave(env.bob, "2030-03-04", "2030-03-05", constants.LeaveStatusRejected)
+		env.seedSick(env.bob, "2030-03-08", "2030-03-08")
+		env.seedSick(env.bob, "2030-03-11", "2030-03-11")
+		env.seedSick(env.bob, "2030-05-06", "2030-05-06")
+		env.seedSick(env.alice, "2030-06-03", "2030-06-03")
+
+		got := env.analytics("?from=2030-03-01&to=2030-06-30")
+		if len(got.Months) != 4 {
+			t.Fatalf("months = %+v, want March to June", got.Months)
+		}
+		march, april := got.Months[0], got.Months[1]
+		if march.Month != "2030-03" || march.Days["annual"] != 2 || march.Days["sick"] != 2 || march.Total != 4 {
+			t.Errorf("march = %+v, want 2 annual and 2 sick days", march)
+		}
+		if april.Days["annual"] != 2 || april.Total != 2 {
+			t.Errorf("april = %+v, want the rest of alice's leave", april)
+		}
+
+		if len(got.Teams) != 2 {
+			t.Fatalf("teams = %+v, want the manager's and no manager", got.Teams)
+		}
+		if team := got.Teams[0]; team.ManagerEmail != env.manager.Email || team.Members != 2 || team.Days["annual"] != 4 || team.Total != 8 {
+			t.Errorf("manager's team = %+v, want alice and bob's 8 days", team)
+		}
+		if team := got.Teams[1]; team.ManagerID != nil || team.Members != 2 || team.Total != 0 {
+			t.Errorf("last team = %+v, want the admin and manager without days", team)
+		}
+	})
+
+	t.Run("bradford factors cover the last 52 weeks", func(t *testing.T) {
+		env := newTestEnv(t)
+		day := func(monday time.Time, offset int) string {
+			return monday.AddDate(0, 0, offset).Format(workdays.DateLayout)
+		}
+		monday := nextMonday().AddDate(0, 0, -35)
+		env.seedSick(env.bob, day(monday, -3), day(monday, -3))
+		env.seedSick(env.bob, day(monday, 0), day(monday, 0)) // Same spell: only a weekend between
+		env.seedSick(env.bob, day(monday, 14), day(monday, 14))
+		env.seedSick(env.alice, day(monday, 7), day(monday, 7))
+		old := nextMonday().AddDate(0, 0, -7*(constants.BradfordWeeks+2))
+		env.seedSick(env.alice, day(old, 0), day(old, 1))
+
+		// Whatever range is asked for
+		got := env.analytics("?from=2030-03-01&to=2030-06-30")
+		if from := time.Now().AddDate(0, 0, 1-7*constants.BradfordWeeks).Format(workdays.DateLayout); got.BradfordFrom != from {
+			t.Errorf("bradfordFrom = %s, want %s", got.BradfordFrom, from)
+		}
+		want := []models.BradfordScore{
+			{UserID: env.bob.ID, UserEmail: env.bob.Email, Spells: 2, Days: 3, Score: 12},
+			{UserID: env.alice.ID, UserEmail: env.alice.Email, Spells: 1, Days: 1, Score: 1},
+		}
+		if len(got.Bradford) != len(want) {
+			t.Fatalf("bradford = %+v, want %+v", got.Bradford, want)
+		}
+		for i := range want {
+			if got.Bradford[i] != want[i] {
+				t.Errorf("bradford[%d] = %+v, want %+v", i, got.Bradford[i], want[i])
+			}
+		}
+	})
+
+	t.Run("turnaround and rejection rates cover leave filed in the range", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		for day, decision := range map[string]string{"2030-03-04": "approve", "2030-03-11": "reject", "2030-03-18": "approve"} {
+			leave := decodeLeave(t, env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+				Type: "annual", StartDate: day, EndDate: day, Reason: "trip",
+			}))
+			if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/"+decision, env.manager, nil); rec.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", decision, rec.Code, rec.Body.String())
+			}
+		}
+		env.seedLeave(env.alice, "2030-04-01", "2030-04-01", constants.LeaveStatusPending)
+
+		got := env.analytics("")
+		if len(got.Turnaround) != 1 || got.Turnaround[0].Type != "annual" || got.Turnaround[0].Decided != 3 || got.Turnaround[0].AverageHours != 0 {
+			t.Errorf("turnaround = %+v, want three annual leaves decided at once", got.Turnaround)
+		}
+		if len(got.Rejections) != 1 || got.Rejections[0].Decided != 3 || got.Rejections[0].Rejected != 1 || got.Rejections[0].Rate != 0.33 {
+			t.Errorf("rejections = %+v, want one in three rejected", got.Rejections)
+		}
+
+		if got := env.analytics("?from=2020-01-01&to=2020-12-31"); len(got.Turnaround) != 0 || len(got.Rejections) != 0 {
+			t.Errorf("earlier range = %+v, want nothing filed", got)
+		}
+	})
+
+	t.Run("results are cached until leave or users change", func(t *testing.T) {
+		env := newTestEnv(t)
+		const query = "?from=2030-01-01&to=2030-12-31"
+		first := env.analytics(query)
+		if again := env.analytics(query); !again.GeneratedAt.Equal(first.GeneratedAt) {
+			t.Errorf("generatedAt = %v, want the cached %v", again.GeneratedAt, first.GeneratedAt)
+		}
+
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+		got := env.analytics(query)
+		if got.GeneratedAt.Equal(first.GeneratedAt) || got.Months[2].Days["annual"] != 5 {
+			t.Errorf("after approval: march = %+v, want the new leave counted", got.Months[2])
+		}
+
+		if err := env.store.DeleteLeave(env.admin.ID, leave.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := env.analytics(query); got.Months[2].Total != 0 {
+			t.Errorf("after delete: march = %+v, want nothing", got.Months[2])
+		}
+
+		// A new user joins the team without a manager
+		before := env.analytics(query)
+		if _, err := env.store.CreateUser("carol@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if got := env.analytics(query); got.GeneratedAt.Equal(before.GeneratedAt) || got.Teams[len(got.Teams)-1].Members != before.Teams[len(before.Teams)-1].Members+1 {
+			t.Errorf("after new user: teams = %+v, want one more member", got.Teams)
+		}
+	})
+
+	t.Run("only admins may ask and the range is checked", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec := env.do(http.MethodGet, "/api/admin/analytics", env.manager, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("as manager = %d, want 403", rec.Code)
+		}
+		for _, query := range []string{"?from=2030-02-01&to=2030-01-01", "?from=2020-01-01&to=2030-01-01", "?to=tomorrow"} {
+			if rec := env.do(http.MethodGet, "/api/admin/analytics"+query, env.admin, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s = %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
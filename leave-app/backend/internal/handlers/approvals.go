@@ -0,0 +1,98 @@
+// internal/handlers/approvals.go
+package handlers
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPendingApprovals handles GET /api/approvals/pending
+func (h *Handler) GetPendingApprovals(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	leaves, err := h.DB.GetPendingApprovals(user.(*models.User))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending approvals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaves)
+}
+
+// GetApprovalPolicies handles GET /api/admin/approval-policies
+func (h *Handler) GetApprovalPolicies(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	policies, err := h.DB.GetApprovalPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get approval policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// UpdateApprovalPolicies handles PUT /api/admin/approval-policies
+func (h *Handler) UpdateApprovalPolicies(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var req models.UpdateApprovalPoliciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	known, err := h.leaveTypeCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leave types"})
+		return
+	}
+	seen := make(map[string]bool)
+	for _, p := range req.Policies {
+		if !known[p.LeaveType] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown leave type " + p.LeaveType})
+			return
+		}
+		key := p.LeaveType + "/" + strconv.FormatFloat(p.OverDays, 'f', -1, 64)
+		if seen[key] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate policy for " + p.LeaveType})
+			return
+		}
+		seen[key] = true
+	}
+
+	if err := h.DB.ReplaceApprovalPolicies(currentUser.ID, req.Policies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update approval policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, req.Policies)
+}
@@ -0,0 +1,182 @@
+// internal/handlers/approvals_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newChainEnv routes alice to the manager, adds an hr user and requires
+// manager then hr sign-off for casual leave over 2 days
+func newChainEnv(t *testing.T) (*testEnv, *models.User) {
+	t.Helper()
+	env := newTestEnv(t)
+	if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+		t.Fatal(err)
+	}
+	hr := env.store.AddUser(models.User{Email: "hr@example.com", Role: string(constants.RoleHR)})
+
+	policies := models.UpdateApprovalPoliciesRequest{Policies: []models.ApprovalPolicy{
+		{LeaveType: string(constants.LeaveTypeCasual), OverDays: 2, Steps: []string{"manager", "hr"}},
+	}}
+	if rec := env.do(http.MethodPut, "/api/admin/approval-policies", env.admin, policies); rec.Code != http.StatusOK {
+		t.Fatalf("set policies: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	return env, hr
+}
+
+func (e *testEnv) fileCasual(user *models.User, start, end string) models.Leave {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/leaves", user, models.CreateLeaveRequest{
+		Type: string(constants.LeaveTypeCasual), StartDate: start, EndDate: end, Reason: "family",
+	})
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("create leave: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	return decodeLeave(e.t, rec)
+}
+
+func TestApprovalChain(t *testing.T) {
+	t.Run("short leave needs only the manager", func(t *testing.T) {
+		env, _ := newChainEnv(t)
+		leave := env.fileCasual(env.alice, "2030-03-04", "2030-03-05")
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusApproved) {
+			t.Errorf("status = %q, want approved", got.Status)
+		}
+	})
+
+	t.Run("long leave needs manager then hr", func(t *testing.T) {
+		env, hr := newChainEnv(t)
+		leave := env.fileCasual(env.alice, "2030-03-04", "2030-03-06")
+		if leave.CurrentStep == nil || *leave.CurrentStep != "manager" {
+			t.Fatalf("current step = %v, want manager", leave.CurrentStep)
+		}
+
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", hr, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("hr before manager: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, map[string]string{"comment": "ok by me"})
+		got := decodeLeave(t, rec)
+		if got.Status != string(constants.LeaveStatusPending) || got.CurrentStep == nil || *got.CurrentStep != "hr" {
+			t.Fatalf("after manager: status = %q step = %v, want pending at hr", got.Status, got.CurrentStep)
+		}
+
+		rec = env.do(http.MethodGet, "/api/approvals/pending", hr, nil)
+		var pending []models.Leave
+		if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].ID != leave.ID {
+			t.Errorf("hr pending approvals = %v, want %s", pending, leave.ID)
+		}
+
+		rec = env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", hr, nil)
+		got = decodeLeave(t, rec)
+		if got.Status != string(constants.LeaveStatusApproved) || got.CurrentStep != nil {
+			t.Errorf("after hr: status = %q step = %v, want approved", got.Status, got.CurrentStep)
+		}
+		if len(got.Approvals) != 2 || got.Approvals[0].Decision != "approved" || got.Approvals[1].Decision != "approved" {
+			t.Errorf("approvals = %+v, want both steps approved", got.Approvals)
+		}
+	})
+
+	t.Run("staffing is checked only at the final step", func(t *testing.T) {
+		env, hr := newChainEnv(t)
+		one := 1
+		if rec := env.do(http.MethodPut, "/api/users/"+env.manager.ID+"/min-staffing", env.admin, models.UpdateMinStaffingRequest{MinStaffing: &one}); rec.Code != http.StatusOK {
+			t.Fatalf("set minimum: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		leave := env.fileCasual(env.alice, "2030-03-04", "2030-03-06")
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusPending) || len(got.StaffingWarnings) != 0 {
+			t.Fatalf("manager step: status = %q warnings = %+v, want pending without warnings", got.Status, got.StaffingWarnings)
+		}
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", hr, nil); rec.Code != http.StatusConflict {
+			t.Errorf("hr step without confirm: status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+
+		// Whoever cannot decide the leave learns nothing of the team's staffing
+		rec = env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.bob, nil)
+		if rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "staffingWarnings") {
+			t.Errorf("approve as bob: status = %d body = %s, want 403 without warnings", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("an admin setting the status decides every open step", func(t *testing.T) {
+		env, _ := newChainEnv(t)
+		one := 1
+		if rec := env.do(http.MethodPut, "/api/users/"+env.manager.ID+"/min-staffing", env.admin, models.UpdateMinStaffingRequest{MinStaffing: &one}); rec.Code != http.StatusOK {
+			t.Fatalf("set minimum: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		leave := env.fileCasual(env.alice, "2030-03-04", "2030-03-06")
+
+		approve := models.UpdateLeaveStatusRequest{Status: string(constants.LeaveStatusApproved)}
+		if rec := env.do(http.MethodPut, "/api/leaves/"+leave.ID, env.admin, approve); rec.Code != http.StatusConflict {
+			t.Fatalf("without confirm: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+		}
+
+		approve.Confirm = true
+		got := decodeLeave(t, env.do(http.MethodPut, "/api/leaves/"+leave.ID, env.admin, approve))
+		if got.Status != string(constants.LeaveStatusApproved) || len(got.StaffingWarnings) != 3 {
+			t.Fatalf("with confirm: status = %q warnings = %+v, want approved despite 3 days short", got.Status, got.StaffingWarnings)
+		}
+		if len(got.Approvals) != 2 {
+			t.Fatalf("approvals = %+v, want both steps", got.Approvals)
+		}
+		for _, a := range got.Approvals {
+			if a.Decision != string(constants.LeaveStatusApproved) || a.ApproverID == nil || *a.ApproverID != env.admin.ID {
+				t.Errorf("step %d = %+v, want approved by the admin", a.Step, a)
+			}
+		}
+	})
+
+	t.Run("rejecting any step rejects the leave", func(t *testing.T) {
+		env, hr := newChainEnv(t)
+		leave := env.fileCasual(env.alice, "2030-03-04", "2030-03-06")
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", hr, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusRejected) {
+			t.Errorf("status = %q, want rejected", got.Status)
+		}
+	})
+}
+
+func TestUpdateApprovalPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy models.ApprovalPolicy
+		want   int
+	}{
+		{"valid", models.ApprovalPolicy{LeaveType: "annual", OverDays: 5, Steps: []string{"manager", "admin"}}, http.StatusOK},
+		{"unknown step", models.ApprovalPolicy{LeaveType: "annual", Steps: []string{"ceo"}}, http.StatusBadRequest},
+		{"no steps", models.ApprovalPolicy{LeaveType: "annual"}, http.StatusBadRequest},
+		{"unknown type", models.ApprovalPolicy{LeaveType: "sabbatical", Steps: []string{"hr"}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			req := models.UpdateApprovalPoliciesRequest{Policies: []models.ApprovalPolicy{tt.policy}}
+			rec := env.do(http.MethodPut, "/api/admin/approval-policies", env.admin, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec := env.do(http.MethodGet, "/api/admin/approval-policies", env.manager, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+// internal/handlers/attachments.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"leave-app/internal/approval"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// canViewAttachments reports whether user may download a leave's
+// attachments: its owner, the manager it is routed to, and anyone who can
+// decide or has decided one of its steps, themselves or through the
+// delegators whose authority they hold
+func canViewAttachments(user *models.User, delegators []models.User, leave *models.Leave) bool {
+	if leave.UserID == user.ID || canReviewAttachments(user, leave) {
+		return true
+	}
+	for i := range delegators {
+		if delegators[i].ID != leave.UserID && canReviewAttachments(&delegators[i], leave) {
+			return true
+		}
+	}
+	return false
+}
+
+// canReviewAttachments reports whether user sees a leave's attachments as
+// one of its approvers
+func canReviewAttachments(user *models.User, leave *models.Leave) bool {
+	if leave.ApproverID != nil && *leave.ApproverID == user.ID {
+		return true
+	}
+	for _, step := range leave.Approvals {
+		if approval.CanAct(user, leave, step.Role) || (step.ApproverID != nil && *step.ApproverID == user.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadAttachment handles POST /api/leaves/:id/attachments. The file is
+// sent as the multipart field "file"; its type is sniffed from the
+// contents rather than trusted from the request.
+func (h *Handler) UploadAttachment(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	leave, err := h.DB.GetLeaveByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Leave not found"})
+		return
+	}
+	if leave.UserID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+	if h.Blobs == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Attachment storage is not configured"})
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxAttachmentBytes+64<<10)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Attachment is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > constants.MaxAttachmentBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Attachment is too large"})
+		return
+	}
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the file"})
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(sniff[:n]))
+	if !slices.Contains(constants.AttachmentContentTypes, contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported attachment type " + contentType})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store attachment"})
+		return
+	}
+
+	attachment := models.Attachment{
+		ID:          uuid.New().String(),
+		LeaveID:     leave.ID,
+		FileName:    filepath.Base(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		UploadedBy:  currentUser.ID,
+		CreatedAt:   time.Now(),
+	}
+	attachment.StorageKey = attachment.ID
+	if err := h.Blobs.Put(attachment.StorageKey, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store attachment"})
+		return
+	}
+	if err := h.DB.AddAttachment(currentUser.ID, &attachment); err != nil {
+		h.Blobs.Delete(attachment.StorageKey)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Leave not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store attachment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadAttachment handles GET /api/leaves/:id/attachments/:attachmentId
+func (h *Handler) DownloadAttachment(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	leave, err := h.DB.GetLeaveByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Leave not found"})
+		return
+	}
+	delegators, err := h.DB.GetActiveDelegators(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch delegations"})
+		return
+	}
+	if !canViewAttachments(currentUser, delegators, leave) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	attachment, err := h.DB.GetAttachment(leave.ID, c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+	if h.Blobs == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Attachment storage is not configured"})
+		return
+	}
+	blob, err := h.Blobs.Open(attachment.StorageKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment"})
+		return
+	}
+	defer blob.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, blob, map[string]string{
+		"Content-Disposition":    disposition,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
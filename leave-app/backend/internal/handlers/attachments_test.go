@@ -0,0 +1,125 @@
+// internal/handlers/attachments_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pdf is enough of a PDF for content sniffing
+var pdf = []byte("%PDF-1.4\n% test certificate\n")
+
+func (e *testEnv) upload(leaveID string, as *models.User, name string, content []byte) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/leaves/"+leaveID+"/attachments", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(testUserHeader, as.Email)
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAttachments(t *testing.T) {
+	t.Run("owner uploads and approvers download", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.upload(leave.ID, env.alice, "certificate.pdf", pdf)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("upload: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var attachment models.Attachment
+		if err := json.Unmarshal(rec.Body.Bytes(), &attachment); err != nil {
+			t.Fatal(err)
+		}
+		if attachment.ContentType != "application/pdf" || attachment.Size != int64(len(pdf)) {
+			t.Errorf("attachment = %+v", attachment)
+		}
+
+		path := "/api/leaves/" + leave.ID + "/attachments/" + attachment.ID
+		for _, user := range []*models.User{env.alice, env.manager, env.admin} {
+			rec := env.do(http.MethodGet, path, user, nil)
+			if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), pdf) {
+				t.Errorf("%s: status = %d, body = %q", user.Email, rec.Code, rec.Body.String())
+			}
+		}
+		if rec := env.do(http.MethodGet, path, env.bob, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("bob: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if rec := env.upload(leave.ID, env.bob, "other.pdf", pdf); rec.Code != http.StatusForbidden {
+			t.Errorf("upload as bob: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+
+		// Holding the manager's authority, bob may download them too
+		env.delegate(env.manager, env.bob, 0, 1)
+		if rec := env.do(http.MethodGet, path, env.bob, nil); rec.Code != http.StatusOK {
+			t.Errorf("bob as delegate: status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("content type and size are validated", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		if rec := env.upload(leave.ID, env.alice, "notes.pdf", []byte("just some text")); rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("text file: status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+		}
+		large := append(append([]byte{}, pdf...), make([]byte, constants.MaxAttachmentBytes)...)
+		if rec := env.upload(leave.ID, env.alice, "large.pdf", large); rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("large file: status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+		}
+	})
+
+	t.Run("required attachment blocks approval", func(t *testing.T) {
+		env := newTestEnv(t)
+		overDays := 2.0
+		rec := env.do(http.MethodPut, "/api/admin/leave-types/sick", env.admin, models.LeaveTypeSettings{
+			Name: "Sick leave", Paid: true, CountsAgainstBalance: true, RequiresAttachment: true, AttachmentOverDays: &overDays,
+		})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update sick leave: status = %d: %s", rec.Code, rec.Body.String())
+		}
+
+		file := func(start, end string) models.Leave {
+			rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+				Type: "sick", StartDate: start, EndDate: end, Reason: "flu",
+			})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("file leave: status = %d: %s", rec.Code, rec.Body.String())
+			}
+			return decodeLeave(t, rec)
+		}
+
+		short := file("2030-03-04", "2030-03-05")
+		if rec := env.do(http.MethodPost, "/api/leaves/"+short.ID+"/approve", env.admin, nil); rec.Code != http.StatusOK {
+			t.Errorf("two days without certificate: status = %d, want %d", rec.Code, http.StatusOK)
+		}
+
+		long := file("2030-03-11", "2030-03-13")
+		if rec := env.do(http.MethodPost, "/api/leaves/"+long.ID+"/approve", env.admin, nil); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("three days without certificate: status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+		}
+		env.upload(long.ID, env.alice, "certificate.pdf", pdf)
+		if rec := env.do(http.MethodPost, "/api/leaves/"+long.ID+"/approve", env.admin, nil); rec.Code != http.StatusOK {
+			t.Errorf("with certificate: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+	})
+}
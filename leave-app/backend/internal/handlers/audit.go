@@ -0,0 +1,45 @@
+// internal/handlers/audit.go
+package handlers
+
+import (
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditEvents handles GET /api/admin/audit
+func (h *Handler) GetAuditEvents(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var filter models.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.DB.GetAuditEvents(filter)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidDateRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD dates with from on or before to"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
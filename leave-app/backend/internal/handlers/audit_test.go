@@ -0,0 +1,75 @@
+// internal/handlers/audit_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"testing"
+)
+
+func (e *testEnv) audit(query string) []models.AuditEvent {
+	e.t.Helper()
+	rec := e.do(http.MethodGet, "/api/admin/audit"+query, e.admin, nil)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("audit: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var events []models.AuditEvent
+	if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+		e.t.Fatal(err)
+	}
+	return events
+}
+
+func TestAuditTrail(t *testing.T) {
+	env := newTestEnv(t)
+	leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+	env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.admin, map[string]string{"comment": "enjoy"})
+	env.do(http.MethodPut, "/api/users/"+env.bob.ID+"/role", env.admin, models.UpdateUserRoleRequest{Role: "manager"})
+
+	events := env.audit("?entityId=" + leave.ID)
+	if len(events) != 2 {
+		t.Fatalf("leave events = %d, want 2", len(events))
+	}
+	// Newest first
+	approved, created := events[0], events[1]
+	if created.Action != string(constants.AuditLeaveCreate) || created.ActorID == nil || *created.ActorID != env.alice.ID {
+		t.Errorf("first event = %s by %v, want leave.create by alice", created.Action, created.ActorID)
+	}
+	if approved.Action != string(constants.AuditLeaveStatus) || approved.ActorID == nil || *approved.ActorID != env.admin.ID {
+		t.Errorf("second event = %s by %v, want leave.status by admin", approved.Action, approved.ActorID)
+	}
+	var before, after models.Leave
+	if err := json.Unmarshal(approved.Before, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(approved.After, &after); err != nil {
+		t.Fatal(err)
+	}
+	if before.Status != "pending" || after.Status != "approved" || after.ApproverComment == nil || *after.ApproverComment != "enjoy" {
+		t.Errorf("status change = %s -> %s (%v), want pending -> approved with comment", before.Status, after.Status, after.ApproverComment)
+	}
+
+	roles := env.audit("?action=user.role")
+	if len(roles) != 1 || roles[0].EntityID != env.bob.ID || roles[0].EntityType != "user" {
+		t.Errorf("role events = %+v, want one for bob", roles)
+	}
+	if got := env.audit("?actorId=" + env.alice.ID + "&limit=1"); len(got) != 1 {
+		t.Errorf("events by alice = %d, want 1", len(got))
+	}
+
+	t.Run("invalid date range", func(t *testing.T) {
+		rec := env.do(http.MethodGet, "/api/admin/audit?from=2030-02-01&to=2030-01-01", env.admin, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		rec := env.do(http.MethodGet, "/api/admin/audit", env.manager, nil)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+// internal/handlers/calendar.go
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/ical"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// calendarEvent renders one leave for owner's feed. Other people's leave
+// is shown without its type or reason.
+func calendarEvent(owner *models.User, leave *models.Leave, typeNames map[string]string) (ical.Event, error) {
+	summary := "On leave"
+	if leave.UserID == owner.ID {
+		if name, ok := typeNames[leave.Type]; ok {
+			summary = name
+		}
+	} else {
+		summary = leave.UserEmail + ": " + summary
+	}
+	switch constants.LeavePortion(leave.Portion) {
+	case constants.LeavePortionFirstHalf:
+		summary += " (first half)"
+	case constants.LeavePortionSecondHalf:
+		summary += " (second half)"
+	}
+
+	event := ical.Event{
+		UID:      leave.ID + "@leave-app",
+		Sequence: leave.Sequence,
+		Summary:  summary,
+	}
+	if leave.Portion == string(constants.LeavePortionHourly) && leave.StartTime != nil && leave.EndTime != nil {
+		start, err := time.Parse(workdays.DateLayout+" 15:04", leave.StartDate+" "+*leave.StartTime)
+		if err != nil {
+			return event, err
+		}
+		end, err := time.Parse(workdays.DateLayout+" 15:04", leave.StartDate+" "+*leave.EndTime)
+		if err != nil {
+			return event, err
+		}
+		event.Start, event.End = start, end
+		return event, nil
+	}
+
+	start, err := time.Parse(workdays.DateLayout, leave.StartDate)
+	if err != nil {
+		return event, err
+	}
+	end, err := time.Parse(workdays.DateLayout, leave.EndDate)
+	if err != nil {
+		return event, err
+	}
+	event.AllDay = true
+	event.Start, event.End = start, end.AddDate(0, 0, 1)
+	return event, nil
+}
+
+// calendarFeedPaths lists the feed URLs owner's token opens, relative to
+// the API host. Only admins get the company feed.
+func calendarFeedPaths(owner *models.User, token string) map[string]string {
+	scopes := []constants.CalendarScope{constants.CalendarScopeMe, constants.CalendarScopeTeam}
+	if owner.Role == string(constants.RoleAdmin) {
+		scopes = append(scopes, constants.CalendarScopeCompany)
+	}
+	paths := make(map[string]string)
+	for _, scope := range scopes {
+		paths[string(scope)] = "/api/calendar/" + string(scope) + ".ics?token=" + token
+	}
+	return paths
+}
+
+// RotateCalendarToken handles POST /api/me/calendar-token. The new token
+// replaces any earlier one and is only shown in this response.
+func (h *Handler) RotateCalendarToken(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	token, err := h.DB.RotateCalendarToken(currentUser.ID, currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token, "feeds": calendarFeedPaths(currentUser, token)})
+}
+
+// RevokeCalendarToken handles DELETE /api/me/calendar-token
+func (h *Handler) RevokeCalendarToken(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	if err := h.DB.RevokeCalendarToken(currentUser.ID, currentUser.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar token"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCalendarFeed handles GET /api/calendar/:scope.ics. Calendar clients
+// cannot send a bearer token, so the feed token in the query string
+// authenticates instead.
+func (h *Handler) GetCalendarFeed(c *gin.Context) {
+	scope, ok := strings.CutSuffix(c.Param("scope"), ".ics")
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	owner, err := h.DB.GetUserByCalendarToken(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid calendar token"})
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -constants.CalendarFeedDays).Format(workdays.DateLayout)
+	leaves, err := h.DB.GetCalendarLeaves(owner, scope, since)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrUnknownCalendarScope):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+			return
+		case errors.Is(err, db.ErrCalendarScopeForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar"})
+		return
+	}
+	types, err := h.DB.GetLeaveTypes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar"})
+		return
+	}
+	typeNames := make(map[string]string, len(types))
+	for _, t := range types {
+		typeNames[t.Code] = t.Name
+	}
+
+	cal := ical.Calendar{Name: "Leave (" + scope + ")"}
+	for i := range leaves {
+		event, err := calendarEvent(owner, &leaves[i], typeNames)
+		if err != nil {
+			log.Printf("Skipping leave %s in calendar feed: %v", leaves[i].ID, err)
+			continue
+		}
+		cal.Events = append(cal.Events, event)
+	}
+
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render calendar"})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+scope+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,137 @@
+// internal/handlers/calendar_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// calendarToken issues a feed token for user
+func (e *testEnv) calendarToken(user *models.User) string {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/me/calendar-token", user, nil)
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("calendar token: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Token string            `json:"token"`
+		Feeds map[string]string `json:"feeds"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		e.t.Fatal(err)
+	}
+	return body.Token
+}
+
+// feed fetches a calendar feed without a session, as a calendar client would
+func (e *testEnv) feed(scope, token string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/calendar/"+scope+".ics?token="+token, nil)
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCalendarFeed(t *testing.T) {
+	t.Run("renders approved leave as all-day events", func(t *testing.T) {
+		env := newTestEnv(t)
+		approved := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+		env.seedLeave(env.alice, "2030-03-11", "2030-03-11", constants.LeaveStatusPending)
+
+		rec := env.feed("me", env.calendarToken(env.alice))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+			t.Errorf("content type = %q, want text/calendar", ct)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{
+			"BEGIN:VCALENDAR\r\n",
+			"UID:" + approved.ID + "@leave-app\r\n",
+			"SEQUENCE:1\r\n",
+			"DTSTART;VALUE=DATE:20300304\r\n",
+			"DTEND;VALUE=DATE:20300309\r\n",
+			"SUMMARY:Annual leave\r\n",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("feed is missing %q:\n%s", want, body)
+			}
+		}
+		if n := strings.Count(body, "BEGIN:VEVENT"); n != 1 {
+			t.Errorf("events = %d, want 1", n)
+		}
+	})
+
+	t.Run("sequence increases when the leave changes", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+		token := env.calendarToken(env.alice)
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+
+		if body := env.feed("me", token).Body.String(); !strings.Contains(body, "SEQUENCE:2\r\n") {
+			t.Errorf("feed after cancellation request lacks SEQUENCE:2:\n%s", body)
+		}
+	})
+
+	t.Run("team and company scopes hide leave details, company is admin only", func(t *testing.T) {
+		env := newTestEnv(t)
+		for _, user := range []*models.User{env.alice, env.bob} {
+			if err := env.store.SetUserManager(env.admin.ID, user.ID, &env.manager.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		env.seedLeave(env.bob, "2030-03-04", "2030-03-04", constants.LeaveStatusApproved)
+		env.seedLeave(env.admin, "2030-03-05", "2030-03-05", constants.LeaveStatusApproved)
+		token := env.calendarToken(env.alice)
+
+		team := env.feed("team", token).Body.String()
+		if !strings.Contains(team, "SUMMARY:bob@example.com: On leave\r\n") {
+			t.Errorf("team feed lacks bob's leave:\n%s", team)
+		}
+		if strings.Contains(team, "admin@example.com") {
+			t.Errorf("team feed includes admin, who is not on the team:\n%s", team)
+		}
+		if rec := env.feed("company", token); rec.Code != http.StatusForbidden {
+			t.Errorf("company feed as user: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if n := strings.Count(env.feed("company", env.calendarToken(env.admin)).Body.String(), "BEGIN:VEVENT"); n != 2 {
+			t.Errorf("company events = %d, want 2", n)
+		}
+		if n := strings.Count(env.feed("team", env.calendarToken(env.manager)).Body.String(), "BEGIN:VEVENT"); n != 1 {
+			t.Errorf("manager's team events = %d, want 1", n)
+		}
+	})
+
+	t.Run("rotated and revoked tokens stop working", func(t *testing.T) {
+		env := newTestEnv(t)
+		old := env.calendarToken(env.alice)
+		current := env.calendarToken(env.alice)
+
+		if rec := env.feed("me", old); rec.Code != http.StatusUnauthorized {
+			t.Errorf("rotated token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+		if rec := env.feed("me", current); rec.Code != http.StatusOK {
+			t.Errorf("current token: status = %d, want %d", rec.Code, http.StatusOK)
+		}
+
+		if rec := env.do(http.MethodDelete, "/api/me/calendar-token", env.alice, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("revoke: status = %d, want %d", rec.Code, http.StatusNoContent)
+		}
+		if rec := env.feed("me", current); rec.Code != http.StatusUnauthorized {
+			t.Errorf("revoked token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("unknown scope is not found", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec := env.feed("everyone", env.calendarToken(env.alice)); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// internal/handlers/compoff.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCompOffCredits handles GET /api/me/comp-off, listing the current
+// user's claims and credits
+func (h *Handler) GetCompOffCredits(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	credits, err := h.DB.GetCompOffCredits(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comp-off credits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credits)
+}
+
+// CreateCompOffCredit handles POST /api/me/comp-off. The current user
+// claims comp-off for a weekend day or holiday they worked.
+func (h *Handler) CreateCompOffCredit(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	var req models.CreateCompOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	credit := models.CompOffCredit{UserID: currentUser.ID, WorkDate: req.WorkDate, Days: req.Days, Reason: req.Reason}
+	if credit.Days == 0 {
+		credit.Days = 1
+	}
+	if credit.Days != 0.5 && credit.Days != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be 0.5 or 1"})
+		return
+	}
+
+	if err := h.DB.CreateCompOffCredit(currentUser.ID, &credit); err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidDateRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "workDate must be a YYYY-MM-DD date"})
+		case errors.Is(err, db.ErrCompOffWorkingDay), errors.Is(err, db.ErrCompOffNotWorked):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, db.ErrDuplicateCompOff):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim comp-off"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, credit)
+}
+
+// GetPendingCompOffCredits handles GET /api/comp-off/pending
+func (h *Handler) GetPendingCompOffCredits(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	credits, err := h.DB.GetPendingCompOffCredits(user.(*models.User))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending comp-off claims"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credits)
+}
+
+// ApproveCompOffCredit handles POST /api/comp-off/:id/approve
+func (h *Handler) ApproveCompOffCredit(c *gin.Context) {
+	h.decideCompOffCredit(c, true)
+}
+
+// RejectCompOffCredit handles POST /api/comp-off/:id/reject
+func (h *Handler) RejectCompOffCredit(c *gin.Context) {
+	h.decideCompOffCredit(c, false)
+}
+
+func (h *Handler) decideCompOffCredit(c *gin.Context, approve bool) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	creditID := c.Param("id")
+
+	var req models.UpdateLeaveStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// We can ignore the error if the body is empty, comment is optional
+	}
+
+	if err := h.DB.DecideCompOffCredit(creditID, currentUser, approve, req.Comment); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comp-off claim not found"})
+		case errors.Is(err, db.ErrNotApprover):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, db.ErrCompOffNotPending):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Comp-off claim is not awaiting approval"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		}
+		return
+	}
+
+	credit, err := h.DB.GetCompOffCredit(creditID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated comp-off claim"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credit)
+}
@@ -0,0 +1,242 @@
+// internal/handlers/compoff_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// weekend returns the Saturday weeks weeks before the most recent one, and
+// the Sunday after it
+func weekend(weeks int) (string, string) {
+	day := time.Now()
+	for day.Weekday() != time.Saturday {
+		day = day.AddDate(0, 0, -1)
+	}
+	day = day.AddDate(0, 0, -7*weeks)
+	return day.Format(workdays.DateLayout), day.AddDate(0, 0, 1).Format(workdays.DateLayout)
+}
+
+// nextMonday returns the Monday after today
+func nextMonday() time.Time {
+	day := time.Now().AddDate(0, 0, 1)
+	for day.Weekday() != time.Monday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// earnCompOff has user claim days for workDate and the manager approve it
+func (e *testEnv) earnCompOff(user *models.User, workDate string, days float64) models.CompOffCredit {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/me/comp-off", user, models.CreateCompOffRequest{WorkDate: workDate, Days: days, Reason: "release"})
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("claim: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var credit models.CompOffCredit
+	if err := json.Unmarshal(rec.Body.Bytes(), &credit); err != nil {
+		e.t.Fatal(err)
+	}
+	rec = e.do(http.MethodPost, "/api/comp-off/"+credit.ID+"/approve", e.manager, nil)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("approve claim: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &credit); err != nil {
+		e.t.Fatal(err)
+	}
+	return credit
+}
+
+// compOffBalance returns user's comp-off balance and credits
+func (e *testEnv) compOffBalance(user *models.User) (models.LeaveBalance, []models.CompOffCredit) {
+	e.t.Helper()
+	balances, err := e.store.GetLeaveBalances(user.ID, time.Now().Year())
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	credits, err := e.store.GetCompOffCredits(user.ID)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return balances[string(constants.LeaveTypeCompOff)], credits
+}
+
+func TestCompOff(t *testing.T) {
+	newCompOffEnv := func(t *testing.T) *testEnv {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		return env
+	}
+
+	t.Run("the manager approves a claim and a credit is issued", func(t *testing.T) {
+		env := newCompOffEnv(t)
+		saturday, _ := weekend(1)
+		rec := env.do(http.MethodPost, "/api/me/comp-off", env.alice, models.CreateCompOffRequest{WorkDate: saturday, Reason: "release"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("claim: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var claim models.CompOffCredit
+		if err := json.Unmarshal(rec.Body.Bytes(), &claim); err != nil {
+			t.Fatal(err)
+		}
+		if claim.Status != string(constants.LeaveStatusPending) || claim.Days != 1 || claim.ExpiresOn != nil {
+			t.Errorf("claim = %+v, want a pending day without an expiry", claim)
+		}
+
+		rec = env.do(http.MethodGet, "/api/comp-off/pending", env.manager, nil)
+		var pending []models.CompOffCredit
+		if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].ID != claim.ID {
+			t.Fatalf("manager's queue = %+v, want alice's claim", pending)
+		}
+		if rec := env.do(http.MethodPost, "/api/comp-off/"+claim.ID+"/approve", env.bob, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("approve as bob: status = %d, want 403", rec.Code)
+		}
+
+		rec = env.do(http.MethodPost, "/api/comp-off/"+claim.ID+"/approve", env.manager, nil)
+		var credit models.CompOffCredit
+		if err := json.Unmarshal(rec.Body.Bytes(), &credit); err != nil {
+			t.Fatal(err)
+		}
+		worked, _ := time.Parse(workdays.DateLayout, saturday)
+		expires := worked.AddDate(0, 0, constants.CompOffExpiryDays).Format(workdays.DateLayout)
+		if credit.Status != string(constants.LeaveStatusApproved) || credit.ExpiresOn == nil || *credit.ExpiresOn != expires {
+			t.Errorf("credit = %+v, want approved and expiring on %s", credit, expires)
+		}
+		if rec := env.do(http.MethodPost, "/api/comp-off/"+claim.ID+"/reject", env.manager, nil); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("decide twice: status = %d, want 422", rec.Code)
+		}
+
+		if balance, _ := env.compOffBalance(env.alice); balance.Allowance != 1 || balance.Remaining != 1 {
+			t.Errorf("balance = %+v, want one day to spend", balance)
+		}
+	})
+
+	t.Run("claims are only for non-working days already worked", func(t *testing.T) {
+		env := newCompOffEnv(t)
+		saturday, _ := weekend(1)
+		monday, _ := time.Parse(workdays.DateLayout, saturday)
+		tests := []struct {
+			name     string
+			workDate string
+			want     int
+		}{
+			{"working day", monday.AddDate(0, 0, 2).Format(workdays.DateLayout), http.StatusUnprocessableEntity},
+			{"future weekend", nextMonday().AddDate(0, 0, 5).Format(workdays.DateLayout), http.StatusUnprocessableEntity},
+			{"not a date", "last saturday", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			rec := env.do(http.MethodPost, "/api/me/comp-off", env.alice, models.CreateCompOffRequest{WorkDate: tt.workDate, Reason: "release"})
+			if rec.Code != tt.want {
+				t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+			}
+		}
+
+		env.earnCompOff(env.alice, saturday, 1)
+		rec := env.do(http.MethodPost, "/api/me/comp-off", env.alice, models.CreateCompOffRequest{WorkDate: saturday, Reason: "again"})
+		if rec.Code != http.StatusConflict {
+			t.Errorf("duplicate claim: status = %d, want 409", rec.Code)
+		}
+	})
+
+	t.Run("compoff leave spends the oldest credits first", func(t *testing.T) {
+		env := newCompOffEnv(t)
+		older, _ := weekend(2)
+		newer, _ := weekend(1)
+		first := env.earnCompOff(env.alice, older, 1)
+		second := env.earnCompOff(env.alice, newer, 1)
+
+		start := nextMonday()
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "compoff", StartDate: start.Format(workdays.DateLayout), EndDate: start.AddDate(0, 0, 2).Format(workdays.DateLayout), Reason: "rest",
+		})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("three days from two credits: status = %d, want 422", rec.Code)
+		}
+
+		day := start.Format(workdays.DateLayout)
+		rec = env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "compoff", StartDate: day, EndDate: day, Portion: string(constants.LeavePortionFirstHalf), Reason: "rest",
+		})
+		leave := decodeLeave(t, rec)
+		if balance, _ := env.compOffBalance(env.alice); balance.Pending != 0.5 || balance.Remaining != 1.5 {
+			t.Errorf("balance while pending = %+v, want half a day held", balance)
+		}
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil); rec.Code != http.StatusOK {
+			t.Fatalf("approve leave: status = %d: %s", rec.Code, rec.Body.String())
+		}
+
+		balance, credits := env.compOffBalance(env.alice)
+		if balance.Used != 0.5 || balance.Remaining != 1.5 {
+			t.Errorf("balance = %+v, want half a day used", balance)
+		}
+		remaining := map[string]float64{}
+		for _, c := range credits {
+			remaining[c.ID] = c.Remaining
+		}
+		if remaining[first.ID] != 0.5 || remaining[second.ID] != 1 {
+			t.Errorf("remaining = %v, want the older credit drawn", remaining)
+		}
+
+		rec = env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusCancellationRequested) {
+			t.Fatalf("leave status = %q, want cancellation_requested", got.Status)
+		}
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil)
+		if balance, _ := env.compOffBalance(env.alice); balance.Used != 0 || balance.Remaining != 2 {
+			t.Errorf("balance after cancellation = %+v, want the credit restored", balance)
+		}
+	})
+
+	t.Run("credits cannot be spent after they expire", func(t *testing.T) {
+		env := newCompOffEnv(t)
+		saturday, _ := weekend(1)
+		credit := env.earnCompOff(env.alice, saturday, 1)
+		expires, _ := time.Parse(workdays.DateLayout, *credit.ExpiresOn)
+		late := expires.AddDate(0, 0, 1)
+		for !env.store.Calendar.IsWorkingDay(late) {
+			late = late.AddDate(0, 0, 1)
+		}
+		day := late.Format(workdays.DateLayout)
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{Type: "compoff", StartDate: day, EndDate: day, Reason: "rest"})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("leave after expiry: status = %d, want 422", rec.Code)
+		}
+	})
+
+	t.Run("a delegate decides claims for the manager", func(t *testing.T) {
+		env := newCompOffEnv(t)
+		env.delegate(env.manager, env.bob, 0, 7)
+		saturday, _ := weekend(1)
+		rec := env.do(http.MethodPost, "/api/me/comp-off", env.alice, models.CreateCompOffRequest{WorkDate: saturday, Days: 0.5, Reason: "deploy"})
+		var claim models.CompOffCredit
+		if err := json.Unmarshal(rec.Body.Bytes(), &claim); err != nil {
+			t.Fatal(err)
+		}
+
+		rec = env.do(http.MethodPost, "/api/comp-off/"+claim.ID+"/reject", env.bob, map[string]string{"comment": "not approved overtime"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("reject as delegate: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		if balance, _ := env.compOffBalance(env.alice); balance.Allowance != 0 {
+			t.Errorf("balance = %+v, want nothing from a rejected claim", balance)
+		}
+		rec = env.do(http.MethodGet, "/api/me/comp-off", env.alice, nil)
+		var credits []models.CompOffCredit
+		if err := json.Unmarshal(rec.Body.Bytes(), &credits); err != nil {
+			t.Fatal(err)
+		}
+		if len(credits) != 1 || credits[0].Status != string(constants.LeaveStatusRejected) || credits[0].Comment == nil {
+			t.Errorf("credits = %+v, want the rejected claim with its comment", credits)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// internal/handlers/delegations.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDelegations handles GET /api/me/delegations, listing the delegations
+// the current user gave and received
+func (h *Handler) GetDelegations(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	delegations, err := h.DB.GetDelegations(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get delegations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, delegations)
+}
+
+// CreateDelegation handles POST /api/me/delegations. The current user
+// lends their approval authority to the delegate between the dates.
+func (h *Handler) CreateDelegation(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	var delegation models.Delegation
+	if err := c.ShouldBindJSON(&delegation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	delegation.DelegatorID = currentUser.ID
+
+	if err := h.DB.CreateDelegation(currentUser.ID, &delegation); err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidDateRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "startDate and endDate must be YYYY-MM-DD dates with startDate on or before endDate"})
+		case errors.Is(err, db.ErrInvalidDelegation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only approvers can delegate, and only to another existing user"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create delegation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// DeleteDelegation handles DELETE /api/me/delegations/:id, ending one of
+// the current user's delegations
+func (h *Handler) DeleteDelegation(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	if err := h.DB.DeleteDelegation(currentUser.ID, currentUser.ID, c.Param("id")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delegation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete delegation"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
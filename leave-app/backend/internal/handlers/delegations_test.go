@@ -0,0 +1,137 @@
+// internal/handlers/delegations_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// delegate lends from's authority to to for days days starting in offset
+// days
+func (e *testEnv) delegate(from, to *models.User, offset, days int) models.Delegation {
+	e.t.Helper()
+	start := time.Now().AddDate(0, 0, offset)
+	rec := e.do(http.MethodPost, "/api/me/delegations", from, models.Delegation{
+		DelegateID: to.ID,
+		StartDate:  start.Format(workdays.DateLayout),
+		EndDate:    start.AddDate(0, 0, days-1).Format(workdays.DateLayout),
+	})
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("delegate: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var d models.Delegation
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		e.t.Fatal(err)
+	}
+	return d
+}
+
+func TestDelegation(t *testing.T) {
+	t.Run("delegates decide the delegator's queue and both are recorded", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		env.delegate(env.manager, env.bob, 0, 7)
+		sent := env.mail()
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-05", Reason: "wedding",
+		})
+		leave := decodeLeave(t, rec)
+		if msgs := sent(); len(msgs) != 2 || msgs[0].To != env.bob.Email || msgs[1].To != env.manager.Email {
+			t.Errorf("sent = %+v, want the manager and their delegate asked", msgs)
+		}
+
+		rec = env.do(http.MethodGet, "/api/approvals/pending", env.bob, nil)
+		var pending []models.Leave
+		if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].ID != leave.ID {
+			t.Fatalf("delegate's queue = %+v, want alice's leave", pending)
+		}
+
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.bob, nil); rec.Code != http.StatusOK {
+			t.Fatalf("approve as delegate: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		got, err := env.store.GetLeaveByID(leave.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step := got.Approvals[0]
+		if step.ApproverID == nil || *step.ApproverID != env.bob.ID || step.OnBehalfOfID == nil || *step.OnBehalfOfID != env.manager.ID {
+			t.Errorf("step = %+v, want decided by bob on behalf of the manager", step)
+		}
+		events, err := env.store.GetAuditEvents(models.AuditFilter{Action: string(constants.AuditLeaveDelegated)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].ActorID == nil || *events[0].ActorID != env.bob.ID || events[0].EntityID != leave.ID {
+			t.Errorf("audit = %+v, want bob's delegated decision", events)
+		}
+	})
+
+	t.Run("delegations only count during their window", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		env.delegate(env.manager, env.bob, 3, 7)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.bob, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("reject before the window: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("delegated admin authority excludes the delegate's own leave", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.delegate(env.admin, env.bob, 0, 1)
+		own := env.seedLeave(env.bob, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+		other := env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+		if rec := env.do(http.MethodPost, "/api/leaves/"+own.ID+"/approve", env.bob, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("approve own leave: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if rec := env.do(http.MethodPost, "/api/leaves/"+other.ID+"/approve", env.bob, nil); rec.Code != http.StatusOK {
+			t.Errorf("approve for the admin: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+	})
+
+	t.Run("managing delegations", func(t *testing.T) {
+		env := newTestEnv(t)
+		for _, tc := range []struct {
+			name string
+			as   *models.User
+			body models.Delegation
+		}{
+			{"plain users have nothing to delegate", env.alice, models.Delegation{DelegateID: env.bob.ID, StartDate: "2030-03-04", EndDate: "2030-03-08"}},
+			{"to themselves", env.manager, models.Delegation{DelegateID: env.manager.ID, StartDate: "2030-03-04", EndDate: "2030-03-08"}},
+			{"to nobody", env.manager, models.Delegation{DelegateID: "missing", StartDate: "2030-03-04", EndDate: "2030-03-08"}},
+			{"backwards", env.manager, models.Delegation{DelegateID: env.bob.ID, StartDate: "2030-03-08", EndDate: "2030-03-04"}},
+		} {
+			if rec := env.do(http.MethodPost, "/api/me/delegations", tc.as, tc.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, http.StatusBadRequest)
+			}
+		}
+
+		d := env.delegate(env.manager, env.bob, 0, 7)
+		rec := env.do(http.MethodGet, "/api/me/delegations", env.bob, nil)
+		var received []models.Delegation
+		if err := json.Unmarshal(rec.Body.Bytes(), &received); err != nil {
+			t.Fatal(err)
+		}
+		if len(received) != 1 || received[0].DelegatorEmail != env.manager.Email {
+			t.Errorf("bob's delegations = %+v, want the manager's", received)
+		}
+		if rec := env.do(http.MethodDelete, "/api/me/delegations/"+d.ID, env.bob, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("delete as delegate: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+		if rec := env.do(http.MethodDelete, "/api/me/delegations/"+d.ID, env.manager, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("delete as delegator: status = %d, want %d", rec.Code, http.StatusNoContent)
+		}
+	})
+}
@@ -2,9 +2,14 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"leave-app/internal/constants"
 	"leave-app/internal/db"
 	"leave-app/internal/models"
+	"leave-app/internal/notify"
+	"leave-app/internal/storage"
+	"log"
 	"net/http"
 	"time"
 
@@ -12,14 +17,142 @@ import (
 )
 
 type Handler struct {
-	DB *db.Database
+	DB     db.Store
+	Blobs  storage.Blobs    // Attachment contents
+	Notify *notify.Notifier // Nil sends no email
 }
 
-func New(db *db.Database) *Handler {
-	return &Handler{DB: db}
+func New(store db.Store) *Handler {
+	return &Handler{DB: store}
 }
 
-// GetCurrentUser handles GET /api/me
+// Register mounts the API routes on an authenticated router group
+func (h *Handler) Register(api gin.IRoutes) {
+	api.GET("/me", h.GetCurrentUser)
+	api.GET("/me/balance", h.GetBalance)
+	api.POST("/me/calendar-token", h.RotateCalendarToken)
+	api.DELETE("/me/calendar-token", h.RevokeCalendarToken)
+	api.GET("/me/notifications", h.GetNotificationPreferences)
+	api.PUT("/me/notifications", h.UpdateNotificationPreferences)
+	api.GET("/me/delegations", h.GetDelegations)
+	api.POST("/me/delegations", h.CreateDelegation)
+	api.DELETE("/me/delegations/:id", h.DeleteDelegation)
+	api.GET("/me/comp-off", h.GetCompOffCredits)
+	api.POST("/me/comp-off", h.CreateCompOffCredit)
+	api.GET("/users", h.GetUsers)
+	api.PUT("/admin/allowances", h.UpdateAllowances)
+	api.PUT("/users/:id/role", h.UpdateUserRole)
+	api.PUT("/users/:id/manager", h.UpdateUserManager)
+	api.PUT("/users/:id/groups", h.UpdateUserGroups)
+	api.PUT("/users/:id/min-staffing", h.UpdateMinStaffing)
+	api.GET("/team/leaves", h.GetTeamLeaves)
+	api.GET("/team/availability", h.GetTeamAvailability)
+	api.GET("/leaves", h.GetLeaves)
+	api.POST("/leaves", h.CreateLeave)
+	api.PUT("/leaves/:id", h.UpdateLeave)
+	api.DELETE("/leaves/:id", h.DeleteLeave)
+	api.POST("/leaves/:id/cancel", h.CancelLeave)
+	api.POST("/leaves/:id/approve", h.ApproveLeave)
+	api.POST("/leaves/:id/reject", h.RejectLeave)
+	api.POST("/leaves/:id/attachments", h.UploadAttachment)
+	api.GET("/leaves/:id/attachments/:attachmentId", h.DownloadAttachment)
+	api.GET("/approvals/pending", h.GetPendingApprovals)
+	api.GET("/comp-off/pending", h.GetPendingCompOffCredits)
+	api.POST("/comp-off/:id/approve", h.ApproveCompOffCredit)
+	api.POST("/comp-off/:id/reject", h.RejectCompOffCredit)
+	api.GET("/admin/approval-policies", h.GetApprovalPolicies)
+	api.PUT("/admin/approval-policies", h.UpdateApprovalPolicies)
+	api.GET("/admin/audit", h.GetAuditEvents)
+	api.GET("/admin/reports/leaves", h.GetLeaveReport)
+	api.GET("/admin/reports/balances", h.GetBalanceReport)
+	api.GET("/admin/analytics", h.GetLeaveAnalytics)
+	api.POST("/admin/import", h.ImportRecords)
+	api.GET("/admin/accrual-policies", h.GetAccrualPolicies)
+	api.PUT("/admin/accrual-policies", h.UpdateAccrualPolicies)
+	api.POST("/admin/accruals/run", h.RunAccruals)
+	api.GET("/admin/allowance-policies", h.GetAllowancePolicies)
+	api.POST("/admin/allowance-policies", h.CreateAllowancePolicy)
+	api.PUT("/admin/allowance-policies/:id/assignments", h.AssignAllowancePolicy)
+	api.GET("/leave-types", h.GetLeaveTypes)
+	api.POST("/admin/leave-types", h.CreateLeaveType)
+	api.PUT("/admin/leave-types/:code", h.UpdateLeaveType)
+	api.DELETE("/admin/leave-types/:code", h.DeleteLeaveType)
+	api.GET("/holidays", h.GetHolidays)
+	api.PUT("/admin/holidays/:date", h.SetHoliday)
+	api.DELETE("/admin/holidays/:date", h.DeleteHoliday)
+	api.GET("/admin/webhooks", h.GetWebhookSubscriptions)
+	api.POST("/admin/webhooks", h.CreateWebhookSubscription)
+	api.PUT("/admin/webhooks/:id", h.UpdateWebhookSubscription)
+	api.DELETE("/admin/webhooks/:id", h.DeleteWebhookSubscription)
+	api.GET("/admin/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+}
+
+// RegisterFeeds mounts the routes that authenticate with their own token
+// rather than the session, on an unauthenticated /api group
+func (h *Handler) RegisterFeeds(r gin.IRoutes) {
+	r.GET("/calendar/:scope", h.GetCalendarFeed)
+}
+
+// respondLeaveError maps errors from leave mutations to a response,
+// falling back to a 500 with message for anything unexpected.
+func respondLeaveError(c *gin.Context, err error, message string) {
+	var balanceErr *db.InsufficientBalanceError
+	var overlapErr *db.OverlapError
+	var transitionErr *db.TransitionError
+	var maxDaysErr *db.MaxConsecutiveDaysError
+	switch {
+	case errors.As(err, &balanceErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     balanceErr.Error(),
+			"type":      balanceErr.Type,
+			"requested": balanceErr.Requested,
+			"remaining": balanceErr.Remaining,
+		})
+	case errors.As(err, &overlapErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":               overlapErr.Error(),
+			"conflictingLeaveIds": overlapErr.LeaveIDs,
+		})
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": transitionErr.Error(),
+			"from":  transitionErr.From,
+			"to":    transitionErr.To,
+		})
+	case errors.As(err, &maxDaysErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":              maxDaysErr.Error(),
+			"type":               maxDaysErr.Type,
+			"maxConsecutiveDays": maxDaysErr.Max,
+		})
+	case errors.Is(err, db.ErrInvalidDateRange),
+		errors.Is(err, db.ErrUnknownLeaveType),
+		errors.Is(err, db.ErrNoWorkingDays),
+		errors.Is(err, db.ErrInvalidPortion),
+		errors.Is(err, db.ErrLeaveSpansYears):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, db.ErrAttachmentRequired):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Leave not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// notifyStatus emails whoever has to act on a leave's new status: its
+// approvers while a decision is outstanding, its owner once decided
+func (h *Handler) notifyStatus(leave *models.Leave) {
+	switch constants.LeaveStatus(leave.Status) {
+	case constants.LeaveStatusPending, constants.LeaveStatusCancellationRequested:
+		h.Notify.AwaitingDecision(*leave)
+	case constants.LeaveStatusApproved, constants.LeaveStatusRejected, constants.LeaveStatusCancelled:
+		h.Notify.Decided(*leave)
+	}
+}
+
+// GetCurrentUser handles GET /api/me. Allowances are the effective ones,
+// with the policy they come from.
 func (h *Handler) GetCurrentUser(c *gin.Context) {
 	user, exists := c.Get(constants.ContextUserKey)
 	if !exists {
@@ -27,10 +160,37 @@ func (h *Handler) GetCurrentUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.(*models.User))
+	currentUser := *user.(*models.User)
+	policy, err := h.DB.GetEffectivePolicy(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get allowance policy"})
+		return
+	}
+	currentUser.Allowances = policy.Allowances
+	currentUser.EffectivePolicy = policy
+
+	c.JSON(http.StatusOK, currentUser)
 }
 
-// GetUsers handles GET /api/users
+// GetBalance handles GET /api/me/balance
+func (h *Handler) GetBalance(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentUser := user.(*models.User)
+
+	balances, err := h.DB.GetLeaveBalances(currentUser.ID, time.Now().Year())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leave balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, balances)
+}
+
+// GetUsers handles GET /api/users, one page at a time
 func (h *Handler) GetUsers(c *gin.Context) {
 	user, exists := c.Get(constants.ContextUserKey)
 	if !exists {
@@ -44,13 +204,23 @@ func (h *Handler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	users, err := h.DB.GetAllUsers()
+	var filter models.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.DB.ListUsers(filter)
 	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, page)
 }
 
 // UpdateAllowances handles PUT /api/admin/allowances
@@ -73,7 +243,7 @@ func (h *Handler) UpdateAllowances(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.UpdateAllUserAllowances(req); err != nil {
+	if err := h.DB.UpdateAllUserAllowances(currentUser.ID, req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update allowances"})
 		return
 	}
@@ -81,7 +251,8 @@ func (h *Handler) UpdateAllowances(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// GetLeaves handles GET /api/leaves
+// GetLeaves handles GET /api/leaves. Admins see every leave, others only
+// their own, one page at a time.
 func (h *Handler) GetLeaves(c *gin.Context) {
 	user, exists := c.Get(constants.ContextUserKey)
 	if !exists {
@@ -90,21 +261,29 @@ func (h *Handler) GetLeaves(c *gin.Context) {
 	}
 	currentUser := user.(*models.User)
 
-	var leaves []models.Leave
-	var err error
-
-	if currentUser.Role == string(constants.RoleAdmin) {
-		leaves, err = h.DB.GetAllLeaves()
-	} else {
-		leaves, err = h.DB.GetLeavesByUserID(currentUser.ID)
+	var filter models.LeaveFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if currentUser.Role != string(constants.RoleAdmin) {
+		filter.UserID = currentUser.ID
 	}
 
+	page, err := h.DB.ListLeaves(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leaves"})
+		switch {
+		case errors.Is(err, db.ErrInvalidDateRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD dates with from on or before to"})
+		case errors.Is(err, db.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leaves"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, leaves)
+	c.JSON(http.StatusOK, page)
 }
 
 // CreateLeave handles POST /api/leaves
@@ -124,18 +303,28 @@ func (h *Handler) CreateLeave(c *gin.Context) {
 
 	leave := models.Leave{
 		UserID:    currentUser.ID,
+		UserEmail: currentUser.Email,
 		Type:      req.Type,
 		StartDate: req.StartDate,
 		EndDate:   req.EndDate,
+		Portion:   req.Portion,
 		Reason:    req.Reason,
 		Status:    string(constants.LeaveStatusPending),
 		CreatedAt: time.Now(),
 	}
+	if leave.Portion == "" {
+		leave.Portion = string(constants.LeavePortionFull)
+	}
+	if leave.Portion == string(constants.LeavePortionHourly) {
+		leave.StartTime = req.StartTime
+		leave.EndTime = req.EndTime
+	}
 
-	if err := h.DB.CreateLeave(&leave); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create leave"})
+	if err := h.DB.CreateLeave(currentUser.ID, &leave); err != nil {
+		respondLeaveError(c, err, "Failed to create leave")
 		return
 	}
+	h.notifyStatus(&leave)
 
 	c.JSON(http.StatusCreated, leave)
 }
@@ -162,9 +351,36 @@ func (h *Handler) UpdateLeave(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.UpdateLeaveStatus(leaveID, req.Status, req.Comment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update leave status"})
-		return
+	leave, err := h.DB.GetLeaveByID(leaveID)
+	if err != nil {
+		respondLeaveError(c, err, "Failed to fetch leave")
+		return
+	}
+
+	// Deciding a pending leave closes its whole approval chain, with the
+	// same staffing check as the approve endpoint
+	var warnings []models.StaffingWarning
+	switch constants.LeaveStatus(req.Status) {
+	case constants.LeaveStatusApproved, constants.LeaveStatusRejected:
+		if leave.Status == string(constants.LeaveStatusPending) {
+			decision := models.LeaveDecision{
+				Approve:  req.Status == string(constants.LeaveStatusApproved),
+				Comment:  req.Comment,
+				Confirm:  req.Confirm,
+				AllSteps: true,
+			}
+			if warnings, err = h.DB.DecideLeave(leaveID, currentUser, decision); err != nil {
+				respondDecisionError(c, err)
+				return
+			}
+			break
+		}
+		fallthrough
+	default:
+		if err := h.DB.UpdateLeaveStatus(currentUser.ID, leaveID, req.Status, req.Comment); err != nil {
+			respondLeaveError(c, err, "Failed to update leave status")
+			return
+		}
 	}
 
 	updatedLeave, err := h.DB.GetLeaveByID(leaveID)
@@ -172,6 +388,8 @@ func (h *Handler) UpdateLeave(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated leave"})
 		return
 	}
+	h.notifyStatus(updatedLeave)
+	updatedLeave.StaffingWarnings = warnings
 
 	c.JSON(http.StatusOK, updatedLeave)
 }
@@ -198,37 +416,51 @@ func (h *Handler) DeleteLeave(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.DeleteLeave(leaveID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete leave"})
+	if err := h.DB.DeleteLeave(currentUser.ID, leaveID); err != nil {
+		respondLeaveError(c, err, "Failed to delete leave")
 		return
 	}
+	if h.Blobs != nil {
+		for _, a := range leave.Attachments {
+			if err := h.Blobs.Delete(a.StorageKey); err != nil {
+				log.Printf("Could not delete attachment %s of leave %s: %v", a.ID, leaveID, err)
+			}
+		}
+	}
 
 	c.Status(http.StatusNoContent)
 }
 
-// ApproveLeave handles POST /api/leaves/:id/approve
-func (h *Handler) ApproveLeave(c *gin.Context) {
+// CancelLeave handles POST /api/leaves/:id/cancel. A pending leave is
+// withdrawn straight away; an approved one needs its cancellation approved.
+func (h *Handler) CancelLeave(c *gin.Context) {
 	user, exists := c.Get(constants.ContextUserKey)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
 		return
 	}
-
 	currentUser := user.(*models.User)
-	if currentUser.Role != string(constants.RoleAdmin) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+
+	leaveID := c.Param("id")
+
+	leave, err := h.DB.GetLeaveByID(leaveID)
+	if err != nil {
+		respondLeaveError(c, err, "Failed to fetch leave")
 		return
 	}
 
-	leaveID := c.Param("id")
+	if leave.UserID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
 
-	var req models.UpdateLeaveStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// We can ignore the error if the body is empty, comment is optional
+	status := string(constants.LeaveStatusCancellationRequested)
+	if leave.Status == string(constants.LeaveStatusPending) {
+		status = string(constants.LeaveStatusWithdrawn)
 	}
 
-	if err := h.DB.UpdateLeaveStatus(leaveID, string(constants.LeaveStatusApproved), req.Comment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve leave"})
+	if err := h.DB.UpdateLeaveStatus(currentUser.ID, leaveID, status, leave.ApproverComment); err != nil {
+		respondLeaveError(c, err, "Failed to cancel leave")
 		return
 	}
 
@@ -237,12 +469,24 @@ func (h *Handler) ApproveLeave(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated leave"})
 		return
 	}
+	h.notifyStatus(updatedLeave)
 
 	c.JSON(http.StatusOK, updatedLeave)
 }
 
+// ApproveLeave handles POST /api/leaves/:id/approve
+func (h *Handler) ApproveLeave(c *gin.Context) {
+	h.decideLeave(c, true)
+}
+
 // RejectLeave handles POST /api/leaves/:id/reject
 func (h *Handler) RejectLeave(c *gin.Context) {
+	h.decideLeave(c, false)
+}
+
+// decideLeave records the current user's decision on the leave's current
+// approval step
+func (h *Handler) decideLeave(c *gin.Context, approve bool) {
 	user, exists := c.Get(constants.ContextUserKey)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
@@ -250,11 +494,6 @@ func (h *Handler) RejectLeave(c *gin.Context) {
 	}
 
 	currentUser := user.(*models.User)
-	if currentUser.Role != string(constants.RoleAdmin) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
-
 	leaveID := c.Param("id")
 
 	var req models.UpdateLeaveStatusRequest
@@ -262,8 +501,19 @@ func (h *Handler) RejectLeave(c *gin.Context) {
 		// We can ignore the error if the body is empty, comment is optional
 	}
 
-	if err := h.DB.UpdateLeaveStatus(leaveID, string(constants.LeaveStatusRejected), req.Comment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject leave"})
+	leave, err := h.DB.GetLeaveByID(leaveID)
+	if err != nil {
+		respondLeaveError(c, err, "Failed to fetch leave")
+		return
+	}
+	cancellation := leave.Status == string(constants.LeaveStatusCancellationRequested)
+
+	// Approvals may go below the minimum staffing, but only once the
+	// approver has seen the warnings and confirmed
+	decision := models.LeaveDecision{Approve: approve, Comment: req.Comment, Confirm: req.Confirm}
+	warnings, err := h.DB.DecideLeave(leaveID, currentUser, decision)
+	if err != nil {
+		respondDecisionError(c, err)
 		return
 	}
 
@@ -272,10 +522,36 @@ func (h *Handler) RejectLeave(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated leave"})
 		return
 	}
+	if cancellation && !approve {
+		h.Notify.CancellationDeclined(*updatedLeave)
+	} else {
+		h.notifyStatus(updatedLeave)
+	}
+	updatedLeave.StaffingWarnings = warnings
 
 	c.JSON(http.StatusOK, updatedLeave)
 }
 
+// respondDecisionError maps the errors of DecideLeave. Staffing warnings
+// are only shown to those allowed to decide, as the store checks rights
+// first.
+func respondDecisionError(c *gin.Context, err error) {
+	var staffing *db.StaffingError
+	switch {
+	case errors.As(err, &staffing):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "Approving this leave takes the team below its minimum staffing; send confirm to approve anyway",
+			"staffingWarnings": staffing.Warnings,
+		})
+	case errors.Is(err, db.ErrNotApprover):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	case errors.Is(err, db.ErrLeaveNotPending):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Leave is not awaiting approval"})
+	default:
+		respondLeaveError(c, err, "Failed to record decision")
+	}
+}
+
 // UpdateUserRole handles PUT /api/users/:id/role
 func (h *Handler) UpdateUserRole(c *gin.Context) {
 	user, exists := c.Get(constants.ContextUserKey)
@@ -298,7 +574,11 @@ func (h *Handler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.UpdateUserRole(userID, req.Role); err != nil {
+	if err := h.DB.UpdateUserRole(currentUser.ID, userID, req.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
 		return
 	}
@@ -0,0 +1,530 @@
+// internal/handlers/handlers_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"leave-app/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testUserHeader stands in for the JWT: the test middleware loads the user
+// with this email into the context.
+const testUserHeader = "X-Test-User"
+
+type testEnv struct {
+	t       *testing.T
+	store   *db.MemoryStore
+	handler *Handler
+	router  *gin.Engine
+	admin   *models.User
+	manager *models.User
+	alice   *models.User
+	bob     *models.User
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := db.NewMemoryStore()
+	allowances := models.Allowance{Sick: 10, Annual: 20, Casual: 5}
+	env := &testEnv{
+		t:       t,
+		store:   store,
+		admin:   store.AddUser(models.User{Email: "admin@example.com", Role: string(constants.RoleAdmin), Allowances: allowances}),
+		manager: store.AddUser(models.User{Email: "manager@example.com", Role: string(constants.RoleManager), Allowances: allowances}),
+		alice:   store.AddUser(models.User{Email: "alice@example.com", Role: string(constants.RoleUser), Allowances: allowances}),
+		bob:     store.AddUser(models.User{Email: "bob@example.com", Role: string(constants.RoleUser), Allowances: allowances}),
+	}
+
+	env.router = gin.New()
+	api := env.router.Group("/api")
+	api.Use(func(c *gin.Context) {
+		user, err := store.GetUserByEmail(c.GetHeader(testUserHeader))
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(constants.ContextUserKey, user)
+		c.Next()
+	})
+	h := New(store)
+	h.Blobs = storage.Local{Dir: t.TempDir()}
+	h.Register(api)
+	h.RegisterFeeds(env.router.Group("/api"))
+	env.handler = h
+	return env
+}
+
+func (e *testEnv) do(method, path string, as *models.User, body any) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			e.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(testUserHeader, as.Email)
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// seedLeave files an annual leave for user and moves it to status
+func (e *testEnv) seedLeave(user *models.User, start, end string, status constants.LeaveStatus) *models.Leave {
+	e.t.Helper()
+	leave := &models.Leave{
+		UserID:    user.ID,
+		Type:      string(constants.LeaveTypeAnnual),
+		StartDate: start,
+		EndDate:   end,
+		Portion:   string(constants.LeavePortionFull),
+		Reason:    "holiday",
+		Status:    string(constants.LeaveStatusPending),
+	}
+	if err := e.store.CreateLeave(user.ID, leave); err != nil {
+		e.t.Fatalf("seed leave: %v", err)
+	}
+	if status != constants.LeaveStatusPending {
+		if err := e.store.UpdateLeaveStatus(e.admin.ID, leave.ID, string(status), nil); err != nil {
+			e.t.Fatalf("seed leave status: %v", err)
+		}
+	}
+	return leave
+}
+
+func (e *testEnv) annualUsed(user *models.User) float64 {
+	e.t.Helper()
+	balances, err := e.store.GetLeaveBalances(user.ID, 2030)
+	if err != nil {
+		e.t.Fatalf("balances: %v", err)
+	}
+	return balances[string(constants.LeaveTypeAnnual)].Used
+}
+
+func decodeLeave(t *testing.T, rec *httptest.ResponseRecorder) models.Leave {
+	t.Helper()
+	var leave models.Leave
+	if err := json.Unmarshal(rec.Body.Bytes(), &leave); err != nil {
+		t.Fatalf("decode leave: %v (%s)", err, rec.Body.String())
+	}
+	return leave
+}
+
+func TestApproveLeave(t *testing.T) {
+	t.Run("admin approves and balance is deducted", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.admin, map[string]string{"comment": "enjoy"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusApproved) {
+			t.Errorf("leave status = %q, want approved", got.Status)
+		}
+		if used := env.annualUsed(env.alice); used != 5 {
+			t.Errorf("annual used = %v, want 5", used)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		for _, user := range []*models.User{env.alice, env.bob} {
+			rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", user, nil)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s: status = %d, want %d", user.Email, rec.Code, http.StatusForbidden)
+			}
+		}
+		if used := env.annualUsed(env.alice); used != 0 {
+			t.Errorf("annual used = %v, want 0", used)
+		}
+	})
+
+	t.Run("unknown leave is not found", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPost, "/api/leaves/missing/approve", env.admin, nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestRejectLeave(t *testing.T) {
+	t.Run("admin rejects pending leave", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.admin, map[string]string{"comment": "busy week"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		got := decodeLeave(t, rec)
+		if got.Status != string(constants.LeaveStatusRejected) {
+			t.Errorf("leave status = %q, want rejected", got.Status)
+		}
+		if got.ApproverComment == nil || *got.ApproverComment != "busy week" {
+			t.Errorf("approver comment = %v, want %q", got.ApproverComment, "busy week")
+		}
+	})
+
+	t.Run("already decided leave cannot be rejected", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.admin, nil)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.bob, nil)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		got, err := env.store.GetLeaveByID(leave.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != string(constants.LeaveStatusPending) {
+			t.Errorf("leave status = %q, want pending", got.Status)
+		}
+	})
+}
+
+func TestUpdateLeaveStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		from constants.LeaveStatus
+		to   constants.LeaveStatus
+		want int
+	}{
+		{"pending to approved", constants.LeaveStatusPending, constants.LeaveStatusApproved, http.StatusOK},
+		{"pending to withdrawn", constants.LeaveStatusPending, constants.LeaveStatusWithdrawn, http.StatusOK},
+		{"approved to cancellation requested", constants.LeaveStatusApproved, constants.LeaveStatusCancellationRequested, http.StatusOK},
+		{"approved to rejected", constants.LeaveStatusApproved, constants.LeaveStatusRejected, http.StatusUnprocessableEntity},
+		{"approved to cancelled", constants.LeaveStatusApproved, constants.LeaveStatusCancelled, http.StatusUnprocessableEntity},
+		{"rejected to approved", constants.LeaveStatusRejected, constants.LeaveStatusApproved, http.StatusUnprocessableEntity},
+		{"unknown status", constants.LeaveStatusPending, "archived", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", tt.from)
+
+			rec := env.do(http.MethodPut, "/api/leaves/"+leave.ID, env.admin, models.UpdateLeaveStatusRequest{Status: string(tt.to)})
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCancelLeave(t *testing.T) {
+	t.Run("pending leave is withdrawn", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusWithdrawn) {
+			t.Errorf("leave status = %q, want withdrawn", got.Status)
+		}
+	})
+
+	t.Run("approved leave is cancelled once the approver agrees", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusCancellationRequested) {
+			t.Fatalf("leave status = %q, want cancellation_requested", got.Status)
+		}
+		if used := env.annualUsed(env.alice); used != 5 {
+			t.Errorf("annual used while cancellation is requested = %v, want 5", used)
+		}
+
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.bob, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("approve cancellation as bob: status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		rec = env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusCancelled) {
+			t.Errorf("leave status = %q, want cancelled", got.Status)
+		}
+		if used := env.annualUsed(env.alice); used != 0 {
+			t.Errorf("annual used after cancellation = %v, want 0", used)
+		}
+	})
+
+	t.Run("refused cancellation keeps the leave approved", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.admin, nil)
+		if got := decodeLeave(t, rec); got.Status != string(constants.LeaveStatusApproved) {
+			t.Errorf("leave status = %q, want approved", got.Status)
+		}
+		if used := env.annualUsed(env.alice); used != 5 {
+			t.Errorf("annual used = %v, want 5", used)
+		}
+	})
+
+	t.Run("final status cannot be cancelled", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusRejected)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("only the owner can cancel", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.bob, nil)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestDeleteLeave(t *testing.T) {
+	tests := []struct {
+		name   string
+		status constants.LeaveStatus
+		as     func(e *testEnv) *models.User
+		want   int
+	}{
+		{"owner deletes pending", constants.LeaveStatusPending, func(e *testEnv) *models.User { return e.alice }, http.StatusNoContent},
+		{"other user", constants.LeaveStatusPending, func(e *testEnv) *models.User { return e.bob }, http.StatusForbidden},
+		{"admin cannot delete others' leave", constants.LeaveStatusPending, func(e *testEnv) *models.User { return e.admin }, http.StatusForbidden},
+		{"owner cannot delete approved", constants.LeaveStatusApproved, func(e *testEnv) *models.User { return e.alice }, http.StatusForbidden},
+		{"owner cannot delete rejected", constants.LeaveStatusRejected, func(e *testEnv) *models.User { return e.alice }, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", tt.status)
+
+			rec := env.do(http.MethodDelete, "/api/leaves/"+leave.ID, tt.as(env), nil)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+
+			_, err := env.store.GetLeaveByID(leave.ID)
+			if deleted := err != nil; deleted != (tt.want == http.StatusNoContent) {
+				t.Errorf("leave deleted = %v, want %v", deleted, tt.want == http.StatusNoContent)
+			}
+		})
+	}
+
+	t.Run("unknown leave is not found", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodDelete, "/api/leaves/missing", env.alice, nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestCreateLeave(t *testing.T) {
+	t.Run("rejects leave beyond the remaining balance", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "casual", StartDate: "2030-03-04", EndDate: "2030-03-15", Reason: "trip",
+		})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+		}
+	})
+
+	t.Run("rejects leave spanning New Year", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-12-30", EndDate: "2031-01-03", Reason: "holidays",
+		})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+		}
+		if got := env.annualPending(env.alice); got != 0 {
+			t.Errorf("pending = %v, want nothing charged", got)
+		}
+	})
+
+	t.Run("rejects overlapping leave", func(t *testing.T) {
+		env := newTestEnv(t)
+		existing := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-08", EndDate: "2030-03-11", Reason: "long weekend",
+		})
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+		}
+		var body struct {
+			ConflictingLeaveIDs []string `json:"conflictingLeaveIds"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.ConflictingLeaveIDs) != 1 || body.ConflictingLeaveIDs[0] != existing.ID {
+			t.Errorf("conflicting ids = %v, want [%s]", body.ConflictingLeaveIDs, existing.ID)
+		}
+	})
+
+	t.Run("half days on the same date do not overlap", func(t *testing.T) {
+		env := newTestEnv(t)
+		for _, portion := range []string{"first_half", "second_half"} {
+			rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+				Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-04", Reason: "appointment", Portion: portion,
+			})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("%s: status = %d, want %d: %s", portion, rec.Code, http.StatusCreated, rec.Body.String())
+			}
+			if got := decodeLeave(t, rec); got.WorkingDays != 0.5 {
+				t.Errorf("%s: working days = %v, want 0.5", portion, got.WorkingDays)
+			}
+		}
+	})
+}
+
+func (e *testEnv) leavePage(path string, as *models.User) models.LeavePage {
+	e.t.Helper()
+	rec := e.do(http.MethodGet, path, as, nil)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("GET %s: status = %d: %s", path, rec.Code, rec.Body.String())
+	}
+	var page models.LeavePage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		e.t.Fatal(err)
+	}
+	return page
+}
+
+func TestGetLeaves(t *testing.T) {
+	t.Run("pages through leaves in sort order", func(t *testing.T) {
+		env := newTestEnv(t)
+		starts := []string{"2030-03-18", "2030-03-04", "2030-03-11", "2030-03-25", "2030-04-01"}
+		for _, start := range starts {
+			env.seedLeave(env.alice, start, start, constants.LeaveStatusPending)
+		}
+
+		want := []string{"2030-03-04", "2030-03-11", "2030-03-18", "2030-03-25", "2030-04-01"}
+		for _, sort := range []string{"startDate", "-startDate"} {
+			var got []string
+			path := "/api/leaves?sort=" + sort + "&limit=2"
+			for pages := 0; ; pages++ {
+				if pages > len(starts) {
+					t.Fatalf("%s: pagination does not end", sort)
+				}
+				page := env.leavePage(path, env.admin)
+				for _, l := range page.Items {
+					got = append(got, l.StartDate)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				path = "/api/leaves?sort=" + sort + "&limit=2&cursor=" + page.NextCursor
+			}
+			if sort == "-startDate" {
+				slices.Reverse(got)
+			}
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("%s: start dates = %v, want %v", sort, got, want)
+			}
+		}
+	})
+
+	t.Run("filters by status and date range", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusApproved)
+		env.seedLeave(env.alice, "2030-03-11", "2030-03-12", constants.LeaveStatusPending)
+		inRange := env.seedLeave(env.bob, "2030-03-07", "2030-03-11", constants.LeaveStatusPending)
+
+		page := env.leavePage("/api/leaves?status=pending&from=2030-03-09&to=2030-03-10", env.admin)
+		if len(page.Items) != 1 || page.Items[0].ID != inRange.ID {
+			t.Errorf("items = %+v, want only %s", page.Items, inRange.ID)
+		}
+	})
+
+	t.Run("non-admins only see their own leaves", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-04", constants.LeaveStatusPending)
+		env.seedLeave(env.bob, "2030-03-04", "2030-03-04", constants.LeaveStatusPending)
+
+		page := env.leavePage("/api/leaves?userId="+env.alice.ID, env.bob)
+		if len(page.Items) != 1 || page.Items[0].UserID != env.bob.ID {
+			t.Errorf("items = %+v, want bob's leave only", page.Items)
+		}
+	})
+
+	t.Run("bad parameters", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-04", constants.LeaveStatusPending)
+		env.seedLeave(env.alice, "2030-03-05", "2030-03-05", constants.LeaveStatusPending)
+		cursor := env.leavePage("/api/leaves?limit=1", env.admin).NextCursor
+
+		for _, query := range []string{
+			"cursor=garbage",
+			"sort=startDate&cursor=" + cursor, // made for the default sort
+			"sort=email",
+			"from=2030-03-10&to=2030-03-01",
+			"limit=501",
+		} {
+			if rec := env.do(http.MethodGet, "/api/leaves?"+query, env.admin, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+func TestGetUsers(t *testing.T) {
+	env := newTestEnv(t)
+
+	rec := env.do(http.MethodGet, "/api/users?role=user&limit=1", env.admin, nil)
+	var page models.UserPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != env.alice.ID || page.NextCursor == "" {
+		t.Fatalf("first page = %+v, want alice and a cursor", page)
+	}
+
+	rec = env.do(http.MethodGet, "/api/users?role=user&limit=1&cursor="+page.NextCursor, env.admin, nil)
+	page = models.UserPage{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != env.bob.ID || page.NextCursor != "" {
+		t.Errorf("second page = %+v, want bob and no cursor", page)
+	}
+
+	if rec := env.do(http.MethodGet, "/api/users", env.alice, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("as user: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,90 @@
+// internal/handlers/holidays.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetHolidays handles GET /api/holidays
+func (h *Handler) GetHolidays(c *gin.Context) {
+	holidays, err := h.DB.GetHolidays()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get holidays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holidays)
+}
+
+// respondHolidayError maps errors from holiday mutations to a response
+func respondHolidayError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrInvalidHolidayDate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, db.ErrHolidaysReadOnly):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Holiday not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// SetHoliday handles PUT /api/admin/holidays/:date, adding the holiday or
+// renaming it. New leave is costed with it at once.
+func (h *Handler) SetHoliday(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var holiday models.Holiday
+	if err := c.ShouldBindJSON(&holiday); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	holiday.Date = c.Param("date")
+
+	if err := h.DB.SetHoliday(currentUser.ID, holiday); err != nil {
+		respondHolidayError(c, err, "Failed to save holiday")
+		return
+	}
+
+	c.JSON(http.StatusOK, holiday)
+}
+
+// DeleteHoliday handles DELETE /api/admin/holidays/:date
+func (h *Handler) DeleteHoliday(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	if err := h.DB.DeleteHoliday(currentUser.ID, c.Param("date")); err != nil {
+		respondHolidayError(c, err, "Failed to delete holiday")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
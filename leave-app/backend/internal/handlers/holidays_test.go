@@ -0,0 +1,90 @@
+// internal/handlers/holidays_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"testing"
+)
+
+// fixedHolidays is a holiday source other than the store, like an ICS file
+type fixedHolidays map[string]string
+
+func (f fixedHolidays) Holidays() (map[string]string, error) { return f, nil }
+
+func TestHolidays(t *testing.T) {
+	t.Run("new holidays apply to new leave at once", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPut, "/api/admin/holidays/2030-03-05", env.admin, models.Holiday{Name: "Founders' Day"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("set: status = %d: %s", rec.Code, rec.Body.String())
+		}
+
+		// Monday to Wednesday around the holiday
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-06", constants.LeaveStatusPending)
+		if got := env.annualPending(env.alice); got != 2 {
+			t.Errorf("pending = %v, want 2", got)
+		}
+
+		if rec := env.do(http.MethodGet, "/api/holidays", env.alice, nil); rec.Code != http.StatusOK {
+			t.Errorf("list: status = %d", rec.Code)
+		}
+		if rec := env.do(http.MethodDelete, "/api/admin/holidays/2030-03-05", env.admin, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		env.seedLeave(env.bob, "2030-03-04", "2030-03-06", constants.LeaveStatusPending)
+		if got := env.annualPending(env.bob); got != 3 {
+			t.Errorf("pending after delete = %v, want 3", got)
+		}
+	})
+
+	t.Run("bad and missing dates", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec := env.do(http.MethodPut, "/api/admin/holidays/05-03-2030", env.admin, models.Holiday{Name: "x"}); rec.Code != http.StatusBadRequest {
+			t.Errorf("bad date: status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+		if rec := env.do(http.MethodDelete, "/api/admin/holidays/2030-03-05", env.admin, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("missing: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("read only when loaded from elsewhere", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.store.Calendar = workdays.New(workdays.DefaultWeekend)
+		if err := env.store.Calendar.Load(fixedHolidays{"2030-03-05": "From file"}); err != nil {
+			t.Fatal(err)
+		}
+		if rec := env.do(http.MethodPut, "/api/admin/holidays/2030-03-06", env.admin, models.Holiday{Name: "x"}); rec.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+
+		// The list shows what leave is costed with, not the store's table
+		rec := env.do(http.MethodGet, "/api/holidays", env.alice, nil)
+		var holidays []models.Holiday
+		if err := json.Unmarshal(rec.Body.Bytes(), &holidays); err != nil {
+			t.Fatalf("list: status = %d: %v", rec.Code, err)
+		}
+		if len(holidays) != 1 || holidays[0] != (models.Holiday{Date: "2030-03-05", Name: "From file"}) {
+			t.Errorf("list = %+v, want the file's holiday", holidays)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec := env.do(http.MethodPut, "/api/admin/holidays/2030-03-05", env.manager, models.Holiday{Name: "x"}); rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func (e *testEnv) annualPending(user *models.User) float64 {
+	e.t.Helper()
+	balances, err := e.store.GetLeaveBalances(user.ID, 2030)
+	if err != nil {
+		e.t.Fatalf("balances: %v", err)
+	}
+	return balances[string(constants.LeaveTypeAnnual)].Pending
+}
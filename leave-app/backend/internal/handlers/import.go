@@ -0,0 +1,84 @@
+// internal/handlers/import.go
+package handlers
+
+import (
+	"errors"
+	"io"
+	"leave-app/internal/constants"
+	"leave-app/internal/importer"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importFile opens one of the uploaded import files, or returns nil when
+// it was not sent
+func importFile(c *gin.Context, name string) (io.ReadCloser, error) {
+	header, err := c.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return header.Open()
+}
+
+// ImportRecords handles POST /api/admin/import. The multipart form carries
+// a users file, a leaves file or both; with dryRun=true the files are only
+// checked.
+func (h *Handler) ImportRecords(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var query models.ImportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxImportBytes)
+	files := make(map[string]io.Reader)
+	for _, name := range []string{"users", "leaves"} {
+		f, err := importFile(c, name)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import files are too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart form with users and leaves files"})
+			return
+		}
+		if f != nil {
+			defer f.Close()
+			files[name] = f
+		}
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload a users file, a leaves file or both"})
+		return
+	}
+
+	result, err := importer.Run(h.DB, currentUser.ID, files["users"], files["leaves"], query.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import records"})
+		return
+	}
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, result)
+}
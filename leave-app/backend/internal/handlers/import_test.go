@@ -0,0 +1,208 @@
+// internal/handlers/import_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// importFiles posts CSV files to the import endpoint as a multipart form
+func (e *testEnv) importFiles(path string, as *models.User, files map[string]string) (*httptest.ResponseRecorder, models.ImportResult) {
+	e.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, content := range files {
+		part, err := form.CreateFormFile(name, name+".csv")
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(testUserHeader, as.Email)
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+
+	var result models.ImportResult
+	if rec.Code == http.StatusOK || rec.Code == http.StatusUnprocessableEntity {
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			e.t.Fatalf("decode result: %v", err)
+		}
+	}
+	return rec, result
+}
+
+const (
+	importUsers = `email,role,manager,annual,sick,casual
+carol@example.com,user,dave@example.com,25,,
+dave@example.com,manager,,,,
+alice@example.com,,dave@example.com,,,
+`
+	importLeaves = `email,type,start_date,end_date,status,reason
+carol@example.com,annual,2030-03-04,2030-03-08,approved,summer
+carol@example.com,sick,2030-03-11,2030-03-11,rejected,
+alice@example.com,annual,2030-04-01,2030-04-02,cancelled,trip
+`
+)
+
+func TestImport(t *testing.T) {
+	t.Run("users and historical leave are imported together", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec, result := env.importFiles("/api/admin/import", env.admin, map[string]string{"users": importUsers, "leaves": importLeaves})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		if !result.Applied || result.UsersCreated != 2 || result.UsersUpdated != 1 || result.Leaves != 3 {
+			t.Errorf("result = %+v", result)
+		}
+
+		carol, err := env.store.GetUserByEmail("carol@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dave, _ := env.store.GetUserByEmail("dave@example.com")
+		if carol.ManagerID == nil || *carol.ManagerID != dave.ID || carol.Allowances.Annual != 25 || carol.Allowances.Sick != 10 {
+			t.Errorf("carol = %+v, want dave as manager and 25 annual days", carol)
+		}
+		alice, _ := env.store.GetUserByEmail(env.alice.Email)
+		if alice.Role != string(constants.RoleUser) || alice.ManagerID == nil || *alice.ManagerID != dave.ID {
+			t.Errorf("alice = %+v, want her role kept and dave as manager", alice)
+		}
+
+		balances, err := env.store.GetLeaveBalances(carol.ID, 2030)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := balances["annual"]; got.Used != 5 || got.Remaining != 20 {
+			t.Errorf("carol's annual balance = %+v, want the approved leave charged", got)
+		}
+		if got := balances["sick"]; got.Used != 0 {
+			t.Errorf("carol's sick balance = %+v, want the rejected leave free", got)
+		}
+		if used := env.annualUsed(env.alice); used != 0 {
+			t.Errorf("alice used = %v, want the cancelled leave free", used)
+		}
+
+		page := env.leavePage("/api/leaves?userId="+carol.ID+"&status=approved", env.admin)
+		if len(page.Items) != 1 {
+			t.Fatalf("carol's approved leaves = %+v, want one", page.Items)
+		}
+		leave, err := env.store.GetLeaveByID(page.Items[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(leave.Approvals) != 1 || leave.Approvals[0].Decision != string(constants.LeaveStatusApproved) || leave.Approvals[0].DecidedAt == nil {
+			t.Errorf("approvals = %+v, want the step signed off", leave.Approvals)
+		}
+	})
+
+	t.Run("comp-off leave is drawn from credits", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		saturday, _ := weekend(1)
+		env.earnCompOff(env.alice, saturday, 1)
+		monday := nextMonday()
+		day := func(d int) string { return monday.AddDate(0, 0, d).Format(workdays.DateLayout) }
+
+		leaves := "email,type,start_date,end_date,status\nalice@example.com,compoff," + day(1) + "," + day(2) + ",approved\n"
+		rec, result := env.importFiles("/api/admin/import", env.admin, map[string]string{"leaves": leaves})
+		if rec.Code != http.StatusUnprocessableEntity || len(result.Errors) != 1 || result.Errors[0].Column != "type" {
+			t.Fatalf("two days on one credit: status = %d, result = %+v", rec.Code, result)
+		}
+
+		leaves = "email,type,start_date,end_date,status\nalice@example.com,compoff," + day(1) + "," + day(1) + ",approved\n"
+		if rec, result := env.importFiles("/api/admin/import", env.admin, map[string]string{"leaves": leaves}); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, result = %+v", rec.Code, result)
+		}
+		balance, credits := env.compOffBalance(env.alice)
+		if balance.Used != 1 || balance.Remaining != 0 || len(credits) != 1 || credits[0].Remaining != 0 {
+			t.Errorf("balance = %+v, credits = %+v, want the credit spent", balance, credits)
+		}
+	})
+
+	t.Run("a dry run checks without importing", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec, result := env.importFiles("/api/admin/import?dryRun=true", env.admin, map[string]string{"users": importUsers, "leaves": importLeaves})
+		if rec.Code != http.StatusOK || !result.DryRun || result.Applied || result.UsersCreated != 2 || result.Leaves != 3 {
+			t.Fatalf("status = %d, result = %+v", rec.Code, result)
+		}
+		if _, err := env.store.GetUserByEmail("carol@example.com"); err == nil {
+			t.Error("dry run created carol")
+		}
+		if alice, _ := env.store.GetUserByEmail(env.alice.Email); alice.ManagerID != nil {
+			t.Error("dry run changed alice's manager")
+		}
+	})
+
+	t.Run("row errors are reported and nothing is applied", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.seedLeave(env.bob, "2030-03-05", "2030-03-05", constants.LeaveStatusApproved)
+		users := importUsers + "not-an-email,user,,,,\nerin@example.com,boss,,x,,\nfrank@example.com,user,bob@example.com,,,\n"
+		leaves := importLeaves + "bob@example.com,annual,2030-03-04,2030-03-06,approved,\nghost@example.com,annual,2030-03-04,2030-03-04,approved,\ncarol@example.com,annual,2030-03-09,2030-03-09,pending,\n"
+		rec, result := env.importFiles("/api/admin/import", env.admin, map[string]string{"users": users, "leaves": leaves})
+		if rec.Code != http.StatusUnprocessableEntity || result.Applied {
+			t.Fatalf("status = %d, result = %+v", rec.Code, result)
+		}
+
+		type at struct {
+			file   string
+			row    int
+			column string
+		}
+		want := []at{
+			{"users", 5, "email"},
+			{"users", 6, "role"},
+			{"users", 6, "annual"},
+			{"users", 7, "manager"}, // bob is not a manager
+			{"leaves", 5, "start_date"},
+			{"leaves", 6, "email"},
+			{"leaves", 7, "status"},
+		}
+		if len(result.Errors) != len(want) {
+			t.Fatalf("errors = %+v, want %d", result.Errors, len(want))
+		}
+		for i, w := range want {
+			if e := result.Errors[i]; e.File != w.file || e.Row != w.row || e.Column != w.column {
+				t.Errorf("error %d = %+v, want %+v", i, e, w)
+			}
+		}
+		if _, err := env.store.GetUserByEmail("carol@example.com"); err == nil {
+			t.Error("failed import created carol")
+		}
+	})
+
+	t.Run("managers may not form a cycle", func(t *testing.T) {
+		env := newTestEnv(t)
+		users := "email,role,manager\nmanager@example.com,,admin@example.com\nadmin@example.com,,manager@example.com\n"
+		rec, result := env.importFiles("/api/admin/import", env.admin, map[string]string{"users": users})
+		if rec.Code != http.StatusUnprocessableEntity || len(result.Errors) != 2 || result.Errors[0].Message != "managers form a cycle" {
+			t.Fatalf("status = %d, result = %+v", rec.Code, result)
+		}
+	})
+
+	t.Run("the upload and caller are checked", func(t *testing.T) {
+		env := newTestEnv(t)
+		if rec, _ := env.importFiles("/api/admin/import", env.manager, map[string]string{"users": importUsers}); rec.Code != http.StatusForbidden {
+			t.Errorf("as manager = %d, want 403", rec.Code)
+		}
+		if rec, _ := env.importFiles("/api/admin/import", env.admin, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("without files = %d, want 400", rec.Code)
+		}
+		rec, result := env.importFiles("/api/admin/import", env.admin, map[string]string{"leaves": "email,type,start\n"})
+		if rec.Code != http.StatusUnprocessableEntity || len(result.Errors) != 4 || result.Errors[0].Row != 1 {
+			t.Errorf("bad header: status = %d, errors = %+v", rec.Code, result.Errors)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+// internal/handlers/leavetypes.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// leaveTypeCodes returns the set of configured leave type codes
+func (h *Handler) leaveTypeCodes() (map[string]bool, error) {
+	types, err := h.DB.GetLeaveTypes()
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]bool, len(types))
+	for _, t := range types {
+		codes[t.Code] = true
+	}
+	return codes, nil
+}
+
+// GetLeaveTypes handles GET /api/leave-types
+func (h *Handler) GetLeaveTypes(c *gin.Context) {
+	types, err := h.DB.GetLeaveTypes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leave types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types)
+}
+
+// CreateLeaveType handles POST /api/admin/leave-types
+func (h *Handler) CreateLeaveType(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var leaveType models.LeaveType
+	if err := c.ShouldBindJSON(&leaveType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.CreateLeaveType(currentUser.ID, &leaveType); err != nil {
+		if errors.Is(err, db.ErrDuplicateLeaveType) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create leave type"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, leaveType)
+}
+
+// UpdateLeaveType handles PUT /api/admin/leave-types/:code
+func (h *Handler) UpdateLeaveType(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var settings models.LeaveTypeSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	leaveType, err := h.DB.UpdateLeaveType(currentUser.ID, c.Param("code"), settings)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Leave type not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update leave type"})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaveType)
+}
+
+// DeleteLeaveType handles DELETE /api/admin/leave-types/:code. Types with
+// leaves filed against them cannot be deleted.
+func (h *Handler) DeleteLeaveType(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	if err := h.DB.DeleteLeaveType(currentUser.ID, c.Param("code")); err != nil {
+		switch {
+		case errors.Is(err, db.ErrLeaveTypeInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Leave type not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete leave type"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
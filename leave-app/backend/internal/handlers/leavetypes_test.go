@@ -0,0 +1,130 @@
+// internal/handlers/leavetypes_test.go
+package handlers
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"testing"
+)
+
+func (e *testEnv) createLeaveType(leaveType models.LeaveType) {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/admin/leave-types", e.admin, leaveType)
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("create leave type: status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLeaveTypes(t *testing.T) {
+	t.Run("new type with a default allowance", func(t *testing.T) {
+		env := newTestEnv(t)
+		maxDays := 5
+		env.createLeaveType(models.LeaveType{Code: "bereavement", LeaveTypeSettings: models.LeaveTypeSettings{
+			Name: "Bereavement", Paid: true, CountsAgainstBalance: true, DefaultAllowance: 3, MaxConsecutiveDays: &maxDays,
+		}})
+
+		balances, err := env.store.GetLeaveBalances(env.alice.ID, 2030)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := balances["bereavement"].Allowance; got != 3 {
+			t.Errorf("bereavement allowance = %v, want 3", got)
+		}
+
+		// Monday to Wednesday is within both limits
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "bereavement", StartDate: "2030-03-04", EndDate: "2030-03-06", Reason: "funeral",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+	})
+
+	t.Run("max consecutive days counts calendar days", func(t *testing.T) {
+		env := newTestEnv(t)
+		maxDays := 5
+		env.createLeaveType(models.LeaveType{Code: "study", LeaveTypeSettings: models.LeaveTypeSettings{
+			Name: "Study", MaxConsecutiveDays: &maxDays,
+		}})
+
+		// Friday to Wednesday is four working days over six calendar days
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "study", StartDate: "2030-03-08", EndDate: "2030-03-13", Reason: "exams",
+		})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+		}
+	})
+
+	t.Run("types without a balance are not charged", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.createLeaveType(models.LeaveType{Code: "unpaid", LeaveTypeSettings: models.LeaveTypeSettings{Name: "Unpaid"}})
+
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "unpaid", StartDate: "2030-03-04", EndDate: "2030-03-29", Reason: "sabbatical",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		leave := decodeLeave(t, rec)
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.admin, nil); rec.Code != http.StatusOK {
+			t.Fatalf("approve: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		balances, err := env.store.GetLeaveBalances(env.alice.ID, 2030)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := balances["unpaid"]; ok {
+			t.Error("unpaid leave has a balance")
+		}
+	})
+
+	t.Run("unknown type is rejected", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "maternity", StartDate: "2030-03-04", EndDate: "2030-03-04", Reason: "baby",
+		})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("update, and delete only while unused", func(t *testing.T) {
+		env := newTestEnv(t)
+		settings := models.LeaveTypeSettings{Name: "Annual leave", Paid: true, CountsAgainstBalance: true}
+		if rec := env.do(http.MethodPut, "/api/admin/leave-types/missing", env.admin, settings); rec.Code != http.StatusNotFound {
+			t.Errorf("update missing: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+		if rec := env.do(http.MethodPost, "/api/admin/leave-types", env.admin, models.LeaveType{Code: "annual", LeaveTypeSettings: settings}); rec.Code != http.StatusConflict {
+			t.Errorf("duplicate: status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-04", constants.LeaveStatusPending)
+		if rec := env.do(http.MethodDelete, "/api/admin/leave-types/annual", env.admin, nil); rec.Code != http.StatusConflict {
+			t.Errorf("delete in use: status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+		if rec := env.do(http.MethodDelete, "/api/admin/leave-types/casual", env.admin, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("delete unused: status = %d, want %d", rec.Code, http.StatusNoContent)
+		}
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "casual", StartDate: "2030-03-05", EndDate: "2030-03-05", Reason: "errand",
+		})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("leave of deleted type: status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPost, "/api/admin/leave-types", env.manager, models.LeaveType{
+			Code: "study", LeaveTypeSettings: models.LeaveTypeSettings{Name: "Study"},
+		})
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if rec := env.do(http.MethodGet, "/api/leave-types", env.alice, nil); rec.Code != http.StatusOK {
+			t.Errorf("list as user: status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+// internal/handlers/logging.go
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedParams are query parameters that authenticate the request and so
+// must not reach the access log
+var redactedParams = []string{"token"}
+
+// Logger is gin's request logger with credentials in the query string,
+// such as calendar feed tokens, masked
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(logFormatter)
+}
+
+// logFormatter writes gin's default log line for param with its path
+// redacted
+func logFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor, methodColor, resetColor = param.StatusCodeColor(), param.MethodColor(), param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		redactPath(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+// redactPath masks the values of redactedParams in path's query string
+func redactPath(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Drop a query we cannot read rather than risk logging a credential
+		return base + "?REDACTED"
+	}
+	redacted := false
+	for _, name := range redactedParams {
+		if values, ok := query[name]; ok {
+			for i := range values {
+				values[i] = "REDACTED"
+			}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
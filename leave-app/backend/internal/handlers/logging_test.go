@@ -0,0 +1,35 @@
+// internal/handlers/logging_test.go
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{path: "/api/leaves", want: "/api/leaves"},
+		{path: "/api/leaves?status=pending", want: "/api/leaves?status=pending"},
+		{path: "/api/calendar/me.ics?token=s3cret", want: "/api/calendar/me.ics?token=REDACTED"},
+		{path: "/api/calendar/me.ics?token=a&token=b&x=1", want: "/api/calendar/me.ics?token=REDACTED&token=REDACTED&x=1"},
+		{path: "/api/calendar/me.ics?token=%zz", want: "/api/calendar/me.ics?REDACTED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := redactPath(tt.path); got != tt.want {
+				t.Errorf("redactPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFormatter(t *testing.T) {
+	line := logFormatter(gin.LogFormatterParams{Method: "GET", StatusCode: 200, Path: "/api/calendar/team.ics?token=s3cret"})
+	if strings.Contains(line, "s3cret") || !strings.Contains(line, "/api/calendar/team.ics?token=REDACTED") {
+		t.Errorf("log line = %q, want the token masked", line)
+	}
+}
@@ -0,0 +1,120 @@
+// internal/handlers/notifications_test.go
+package handlers
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/notify"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// outbox is a Mailer that keeps what it is given
+type outbox struct {
+	mu   sync.Mutex
+	sent []notify.Message
+}
+
+func (o *outbox) Send(msg notify.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+// mail turns on notifications and returns a function that waits for them
+// and returns what was sent so far, sorted by recipient
+func (e *testEnv) mail() func() []notify.Message {
+	box := &outbox{}
+	e.handler.Notify = &notify.Notifier{Mailer: box, Store: e.store}
+	return func() []notify.Message {
+		e.handler.Notify.Wait()
+		box.mu.Lock()
+		defer box.mu.Unlock()
+		sent := append([]notify.Message{}, box.sent...)
+		box.sent = nil
+		sort.Slice(sent, func(i, j int) bool { return sent[i].To < sent[j].To })
+		return sent
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	t.Run("approvers hear of new requests and requesters of decisions", func(t *testing.T) {
+		env := newTestEnv(t)
+		sent := env.mail()
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-05", Reason: "wedding <3",
+		})
+		leave := decodeLeave(t, rec)
+		msgs := sent()
+		if len(msgs) != 1 || msgs[0].To != env.manager.Email {
+			t.Fatalf("sent = %+v, want one mail to the manager", msgs)
+		}
+		if !strings.Contains(msgs[0].HTML, "wedding &lt;3") || !strings.Contains(msgs[0].HTML, "Annual leave") {
+			t.Errorf("request mail does not show the escaped reason and type:\n%s", msgs[0].HTML)
+		}
+
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.manager, map[string]string{"comment": "team offsite"})
+		msgs = sent()
+		if len(msgs) != 1 || msgs[0].To != env.alice.Email || !strings.Contains(msgs[0].Subject, "rejected") {
+			t.Fatalf("sent = %+v, want a rejection mail to alice", msgs)
+		}
+		if !strings.Contains(msgs[0].HTML, "team offsite") {
+			t.Errorf("decision mail lacks the approver's comment:\n%s", msgs[0].HTML)
+		}
+	})
+
+	t.Run("refused cancellation is not reported as an approval", func(t *testing.T) {
+		env := newTestEnv(t)
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusApproved)
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/cancel", env.alice, nil)
+
+		sent := env.mail()
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/reject", env.admin, map[string]string{"comment": "too close to launch"})
+		msgs := sent()
+		if len(msgs) != 1 || msgs[0].To != env.alice.Email || !strings.Contains(msgs[0].Subject, "cancellation request was declined") {
+			t.Fatalf("sent = %+v, want a declined cancellation mail to alice", msgs)
+		}
+		if strings.Contains(msgs[0].HTML, "has been approved") || !strings.Contains(msgs[0].HTML, "too close to launch") {
+			t.Errorf("declined cancellation mail:\n%s", msgs[0].HTML)
+		}
+	})
+
+	t.Run("leave without a manager goes to admins", func(t *testing.T) {
+		env := newTestEnv(t)
+		sent := env.mail()
+		env.do(http.MethodPost, "/api/leaves", env.bob, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-04", Reason: "errand",
+		})
+		if msgs := sent(); len(msgs) != 1 || msgs[0].To != env.admin.Email {
+			t.Errorf("sent = %+v, want one mail to the admin", msgs)
+		}
+	})
+
+	t.Run("opted-out users get no email", func(t *testing.T) {
+		env := newTestEnv(t)
+		sent := env.mail()
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+
+		rec := env.do(http.MethodPut, "/api/me/notifications", env.alice, models.NotificationPreferences{Email: false})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("opt out: status = %d: %s", rec.Code, rec.Body.String())
+		}
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.admin, nil)
+		if msgs := sent(); len(msgs) != 0 {
+			t.Errorf("sent = %+v, want nothing", msgs)
+		}
+
+		rec = env.do(http.MethodGet, "/api/me/notifications", env.alice, nil)
+		if !strings.Contains(rec.Body.String(), `"email":false`) {
+			t.Errorf("preferences = %s, want email off", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,136 @@
+// internal/handlers/reminders_test.go
+package handlers
+
+import (
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/scheduler"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var chaseConfig = scheduler.Config{ReminderAfter: 48 * time.Hour, EscalateAfter: 120 * time.Hour}
+
+// chase runs the reminder scheduler at now plus after
+func (e *testEnv) chase(config scheduler.Config, after time.Duration) {
+	e.t.Helper()
+	s := scheduler.Scheduler{Store: e.store, Notify: e.handler.Notify, Config: config, Holder: "test"}
+	if _, err := s.RunOnce(time.Now().Add(after)); err != nil {
+		e.t.Fatalf("chase: %v", err)
+	}
+}
+
+func TestReminders(t *testing.T) {
+	t.Run("approvers are reminded once per step", func(t *testing.T) {
+		env := newTestEnv(t)
+		sent := env.mail()
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-05", Reason: "wedding",
+		})
+		sent()
+
+		env.chase(chaseConfig, 47*time.Hour)
+		if msgs := sent(); len(msgs) != 0 {
+			t.Fatalf("sent before the reminder is due = %+v", msgs)
+		}
+		env.chase(chaseConfig, 49*time.Hour)
+		msgs := sent()
+		if len(msgs) != 1 || msgs[0].To != env.manager.Email || !strings.HasPrefix(msgs[0].Subject, "Reminder:") {
+			t.Fatalf("sent = %+v, want one reminder to the manager", msgs)
+		}
+		env.chase(chaseConfig, 50*time.Hour)
+		if msgs := sent(); len(msgs) != 0 {
+			t.Errorf("reminded again: %+v", msgs)
+		}
+	})
+
+	t.Run("stale leave climbs the manager chain and then goes to admins", func(t *testing.T) {
+		env := newTestEnv(t)
+		sent := env.mail()
+		director := env.store.AddUser(models.User{Email: "director@example.com", Role: string(constants.RoleManager)})
+		for user, manager := range map[string]string{env.alice.ID: env.manager.ID, env.manager.ID: director.ID} {
+			if err := env.store.SetUserManager(env.admin.ID, user, &manager); err != nil {
+				t.Fatal(err)
+			}
+		}
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+		sent()
+
+		env.chase(chaseConfig, 121*time.Hour)
+		msgs := sent()
+		if len(msgs) != 1 || msgs[0].To != director.Email || !strings.HasPrefix(msgs[0].Subject, "Escalated:") {
+			t.Fatalf("sent = %+v, want one escalation to the director", msgs)
+		}
+		if got, _ := env.store.GetLeaveByID(leave.ID); got.ApproverID == nil || *got.ApproverID != director.ID {
+			t.Errorf("approver = %v, want the director", got.ApproverID)
+		}
+
+		// The clock restarted, so the director gets the full wait too
+		env.chase(chaseConfig, 200*time.Hour)
+		if msgs := sent(); len(msgs) != 1 || !strings.HasPrefix(msgs[0].Subject, "Reminder:") {
+			t.Fatalf("sent = %+v, want a reminder to the director", msgs)
+		}
+		env.chase(chaseConfig, 242*time.Hour)
+		if msgs := sent(); len(msgs) != 1 || msgs[0].To != env.admin.Email {
+			t.Fatalf("sent = %+v, want the escalation to reach the admin", msgs)
+		}
+		env.chase(chaseConfig, 400*time.Hour)
+		if msgs := sent(); len(msgs) != 1 || !strings.HasPrefix(msgs[0].Subject, "Reminder:") {
+			t.Errorf("sent = %+v, want only a reminder once admins have it", msgs)
+		}
+	})
+
+	t.Run("sick leave can be approved automatically", func(t *testing.T) {
+		env := newTestEnv(t)
+		sent := env.mail()
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "sick", StartDate: "2030-03-04", EndDate: "2030-03-04", Reason: "flu",
+		})
+		leave := decodeLeave(t, rec)
+		sent()
+
+		config := chaseConfig
+		config.SickAutoApproveAfter = 24 * time.Hour
+		env.chase(config, 25*time.Hour)
+		got, err := env.store.GetLeaveByID(leave.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != string(constants.LeaveStatusApproved) || got.ApproverComment == nil || *got.ApproverComment != scheduler.AutoApproveComment {
+			t.Errorf("leave = %+v, want it approved automatically", got)
+		}
+		for _, step := range got.Approvals {
+			if step.Decision != string(constants.LeaveStatusApproved) {
+				t.Errorf("step %d = %s, want approved", step.Step, step.Decision)
+			}
+		}
+		if msgs := sent(); len(msgs) != 1 || msgs[0].To != env.alice.Email || !strings.Contains(msgs[0].Subject, "approved") {
+			t.Errorf("sent = %+v, want the approval mail to alice", msgs)
+		}
+	})
+
+	t.Run("one replica holds the lease at a time", func(t *testing.T) {
+		env := newTestEnv(t)
+		now := time.Now()
+		first := scheduler.Scheduler{Store: env.store, Config: chaseConfig, Holder: "first"}
+		second := scheduler.Scheduler{Store: env.store, Config: chaseConfig, Holder: "second"}
+
+		if ran, err := first.RunOnce(now); err != nil || !ran {
+			t.Fatalf("first run = %v, %v; want it to take the lease", ran, err)
+		}
+		if ran, _ := second.RunOnce(now.Add(time.Minute)); ran {
+			t.Error("second replica ran while the lease was held")
+		}
+		if ran, _ := first.RunOnce(now.Add(constants.SchedulerIntervalMinutes * time.Minute)); !ran {
+			t.Error("holder could not renew its lease")
+		}
+		if ran, _ := second.RunOnce(now.Add(time.Hour)); !ran {
+			t.Error("second replica could not take over an expired lease")
+		}
+	})
+}
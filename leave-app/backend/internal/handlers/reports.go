@@ -0,0 +1,145 @@
+// internal/handlers/reports.go
+package handlers
+
+import (
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"leave-app/internal/report"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	leaveReportHeader   = []any{"Leave ID", "Employee", "Type", "Status", "Start date", "End date", "Portion", "Working days", "Approver", "Requested at"}
+	balanceReportHeader = []any{"Employee", "Type", "Allowance", "Carried forward", "Used", "Pending", "Remaining"}
+)
+
+// reportFormat picks csv or xlsx from the format parameter, then the
+// Accept header
+func reportFormat(c *gin.Context, format string) string {
+	if format != "" {
+		return format
+	}
+	if strings.Contains(c.GetHeader("Accept"), report.ContentTypeXLSX) {
+		return "xlsx"
+	}
+	return "csv"
+}
+
+// reportStream writes a report to the response. The response starts with
+// the first row, so an error found before then can still be sent as JSON.
+type reportStream struct {
+	c      *gin.Context
+	name   string
+	format string
+	header []any
+	w      report.Writer
+}
+
+func (s *reportStream) start() error {
+	s.c.Header("Content-Type", report.ContentType(s.format))
+	s.c.Header("Content-Disposition", `attachment; filename="`+s.name+`.`+s.format+`"`)
+	s.c.Status(http.StatusOK)
+	w, err := report.New(s.c.Writer, s.format)
+	if err != nil {
+		return err
+	}
+	s.w = w
+	return w.WriteRow(s.header...)
+}
+
+func (s *reportStream) row(cells ...any) error {
+	if s.w == nil {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	return s.w.WriteRow(cells...)
+}
+
+// finish ends the report, writing just the header when it has no rows.
+// Once rows have gone out, err can only be logged.
+func (s *reportStream) finish(err error) {
+	switch {
+	case err != nil && s.w == nil:
+		if errors.Is(err, db.ErrInvalidDateRange) {
+			s.c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD dates with from on or before to"})
+			return
+		}
+		log.Printf("Failed to build %s report: %v", s.name, err)
+		s.c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	case err != nil:
+		log.Printf("Failed to stream %s report: %v", s.name, err)
+		return
+	case s.w == nil:
+		err = s.start()
+	}
+	if err == nil {
+		err = s.w.Close()
+	}
+	if err != nil {
+		log.Printf("Failed to finish %s report: %v", s.name, err)
+	}
+}
+
+// GetLeaveReport handles GET /api/admin/reports/leaves
+func (h *Handler) GetLeaveReport(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var filter models.LeaveReportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s := &reportStream{c: c, name: "leaves", format: reportFormat(c, filter.Format), header: leaveReportHeader}
+	err := h.DB.StreamLeaveReport(filter, func(r models.LeaveReportRow) error {
+		l := &r.Leave
+		return s.row(l.ID, l.UserEmail, l.Type, l.Status, l.StartDate, l.EndDate, l.Portion, l.WorkingDays, r.Approver, l.CreatedAt.UTC().Format(time.RFC3339))
+	})
+	s.finish(err)
+}
+
+// GetBalanceReport handles GET /api/admin/reports/balances
+func (h *Handler) GetBalanceReport(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var filter models.BalanceReportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s := &reportStream{c: c, name: "balances", format: reportFormat(c, filter.Format), header: balanceReportHeader}
+	err := h.DB.StreamBalanceReport(filter, func(r models.BalanceReportRow) error {
+		return s.row(r.Email, r.Type, r.Allowance, r.CarriedForward, r.Used, r.Pending, r.Remaining)
+	})
+	s.finish(err)
+}
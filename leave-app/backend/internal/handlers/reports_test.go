@@ -0,0 +1,174 @@
+// internal/handlers/reports_test.go
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/report"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// readCSV parses a CSV report
+func readCSV(t *testing.T, rec *httptest.ResponseRecorder) [][]string {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	return rows
+}
+
+// readSheet returns the sheet XML of an XLSX report
+func readSheet(t *testing.T, body []byte) string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	f, err := z.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("open sheet: %v", err)
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(sheet)
+}
+
+func TestReports(t *testing.T) {
+	setup := func(t *testing.T) (*testEnv, *models.Leave) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+		rec := env.do(http.MethodPost, "/api/leaves", env.alice, models.CreateLeaveRequest{
+			Type: "annual", StartDate: "2030-03-04", EndDate: "2030-03-08", Reason: "wedding",
+		})
+		leave := decodeLeave(t, rec)
+		if rec := env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.manager, nil); rec.Code != http.StatusOK {
+			t.Fatalf("approve: %d %s", rec.Code, rec.Body.String())
+		}
+		env.seedLeave(env.bob, "2030-04-01", "2030-04-01", constants.LeaveStatusPending)
+		return env, &leave
+	}
+
+	t.Run("leaves are exported as CSV with working days, approver and status", func(t *testing.T) {
+		env, leave := setup(t)
+		rec := env.do(http.MethodGet, "/api/admin/reports/leaves?from=2030-03-01&to=2030-04-30", env.admin, nil)
+		if ct := rec.Header().Get("Content-Type"); ct != report.ContentTypeCSV {
+			t.Errorf("content type = %q", ct)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="leaves.csv"`) {
+			t.Errorf("content disposition = %q", cd)
+		}
+		rows := readCSV(t, rec)
+		if len(rows) != 3 || rows[0][0] != "Leave ID" {
+			t.Fatalf("rows = %v, want the header and two leaves", rows)
+		}
+		want := []string{leave.ID, env.alice.Email, "annual", "approved", "2030-03-04", "2030-03-08", "full", "5", env.manager.Email}
+		for i, cell := range want {
+			if rows[1][i] != cell {
+				t.Errorf("column %s = %q, want %q", rows[0][i], rows[1][i], cell)
+			}
+		}
+		if rows[2][1] != env.bob.Email || rows[2][3] != "pending" {
+			t.Errorf("second row = %v, want bob's pending leave", rows[2])
+		}
+	})
+
+	t.Run("filters narrow the export", func(t *testing.T) {
+		env, _ := setup(t)
+		rows := readCSV(t, env.do(http.MethodGet, "/api/admin/reports/leaves?status=pending", env.admin, nil))
+		if len(rows) != 2 || rows[1][1] != env.bob.Email {
+			t.Errorf("status filter rows = %v", rows)
+		}
+		rows = readCSV(t, env.do(http.MethodGet, "/api/admin/reports/leaves?to=2030-03-31&type=annual", env.admin, nil))
+		if len(rows) != 2 || rows[1][1] != env.alice.Email {
+			t.Errorf("date filter rows = %v", rows)
+		}
+		rows = readCSV(t, env.do(http.MethodGet, "/api/admin/reports/leaves?type=sick", env.admin, nil))
+		if len(rows) != 1 {
+			t.Errorf("empty report rows = %v, want just the header", rows)
+		}
+	})
+
+	t.Run("leaves are exported as XLSX on request", func(t *testing.T) {
+		env, leave := setup(t)
+		rec := env.do(http.MethodGet, "/api/admin/reports/leaves?format=xlsx", env.admin, nil)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != report.ContentTypeXLSX {
+			t.Fatalf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		sheet := readSheet(t, rec.Body.Bytes())
+		for _, want := range []string{leave.ID, env.manager.Email, "<v>5</v>", "Working days"} {
+			if !strings.Contains(sheet, want) {
+				t.Errorf("sheet is missing %q", want)
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/reports/balances?type=annual", nil)
+		req.Header.Set(testUserHeader, env.admin.Email)
+		req.Header.Set("Accept", report.ContentTypeXLSX)
+		rec = httptest.NewRecorder()
+		env.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != report.ContentTypeXLSX {
+			t.Fatalf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if sheet := readSheet(t, rec.Body.Bytes()); !strings.Contains(sheet, env.alice.Email) {
+			t.Errorf("balance sheet is missing alice: %s", sheet)
+		}
+	})
+
+	t.Run("balances are exported per user and type", func(t *testing.T) {
+		env, _ := setup(t)
+		rows := readCSV(t, env.do(http.MethodGet, "/api/admin/reports/balances?year=2030&type=annual", env.admin, nil))
+		if len(rows) != 5 || rows[0][0] != "Employee" {
+			t.Fatalf("rows = %v, want the header and one row per user", rows)
+		}
+		for _, row := range rows[1:] {
+			if row[0] == env.alice.Email && (row[1] != "annual" || row[2] != "20" || row[4] != "5" || row[6] != "15") {
+				t.Errorf("alice's row = %v", row)
+			}
+		}
+	})
+
+	t.Run("formula-like text is neutralised in CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := report.NewCSV(&buf)
+		if err := w.WriteRow("=1+1", "-3", -3, "plain"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != "'=1+1,'-3,-3,plain\n" {
+			t.Errorf("csv = %q", got)
+		}
+	})
+
+	t.Run("only admins export and ranges are checked", func(t *testing.T) {
+		env := newTestEnv(t)
+		for _, path := range []string{"/api/admin/reports/leaves", "/api/admin/reports/balances"} {
+			if rec := env.do(http.MethodGet, path, env.manager, nil); rec.Code != http.StatusForbidden {
+				t.Errorf("%s as manager = %d, want 403", path, rec.Code)
+			}
+		}
+		if rec := env.do(http.MethodGet, "/api/admin/reports/leaves?from=2030-03-10&to=2030-03-01", env.admin, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("reversed range = %d, want 400", rec.Code)
+		}
+		if rec := env.do(http.MethodGet, "/api/admin/reports/leaves?format=pdf", env.admin, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown format = %d, want 400", rec.Code)
+		}
+	})
+}
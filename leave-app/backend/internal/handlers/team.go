@@ -0,0 +1,161 @@
+// internal/handlers/team.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTeamLeaves handles GET /api/team/leaves
+func (h *Handler) GetTeamLeaves(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleManager) && currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	leaves, err := h.DB.GetTeamLeaves(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get team leaves"})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaves)
+}
+
+// GetTeamAvailability handles GET /api/team/availability
+func (h *Handler) GetTeamAvailability(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleManager) && currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var filter models.AvailabilityFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	managerID := currentUser.ID
+	if filter.ManagerID != "" && filter.ManagerID != currentUser.ID {
+		if currentUser.Role != string(constants.RoleAdmin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		managerID = filter.ManagerID
+	}
+	if filter.From == "" {
+		filter.From = time.Now().Format(workdays.DateLayout)
+	}
+	if from, err := time.Parse(workdays.DateLayout, filter.From); err == nil && filter.To == "" {
+		filter.To = from.AddDate(0, 0, 6).Format(workdays.DateLayout)
+	}
+
+	availability, err := h.DB.GetTeamAvailability(managerID, filter.From, filter.To)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidDateRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD dates with from on or before to, at most " + strconv.Itoa(constants.MaxAvailabilityDays) + " days apart"})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manager not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get team availability"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// UpdateMinStaffing handles PUT /api/users/:id/min-staffing
+func (h *Handler) UpdateMinStaffing(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	var req models.UpdateMinStaffingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.SetMinStaffing(currentUser.ID, c.Param("id"), *req.MinStaffing); err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidManager):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only managers and admins have a team"})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update minimum staffing"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Minimum staffing updated successfully"})
+}
+
+// UpdateUserManager handles PUT /api/users/:id/manager
+func (h *Handler) UpdateUserManager(c *gin.Context) {
+	user, exists := c.Get(constants.ContextUserKey)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	currentUser := user.(*models.User)
+	if currentUser.Role != string(constants.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	userID := c.Param("id")
+
+	var req models.UpdateManagerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.SetUserManager(currentUser.ID, userID, req.ManagerID); err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidManager):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Manager must be another manager or admin outside the user's reporting line"})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update manager"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User manager updated successfully"})
+}
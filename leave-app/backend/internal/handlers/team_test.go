@@ -0,0 +1,174 @@
+// internal/handlers/team_test.go
+package handlers
+
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"net/http"
+	"testing"
+)
+
+func TestManagerApprovals(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.store.SetUserManager(env.admin.ID, env.alice.ID, &env.manager.ID); err != nil {
+		t.Fatal(err)
+	}
+	report := env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+	other := env.seedLeave(env.bob, "2030-03-04", "2030-03-05", constants.LeaveStatusPending)
+
+	if report.ApproverID == nil || *report.ApproverID != env.manager.ID {
+		t.Fatalf("report leave approver = %v, want manager", report.ApproverID)
+	}
+
+	rec := env.do(http.MethodGet, "/api/team/leaves", env.manager, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("team leaves: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var team []models.Leave
+	if err := json.Unmarshal(rec.Body.Bytes(), &team); err != nil {
+		t.Fatal(err)
+	}
+	if len(team) != 1 || team[0].ID != report.ID {
+		t.Errorf("team leaves = %v, want only %s", team, report.ID)
+	}
+
+	if rec := env.do(http.MethodPost, "/api/leaves/"+other.ID+"/approve", env.manager, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("approve other team's leave: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := env.do(http.MethodPost, "/api/leaves/"+report.ID+"/approve", env.manager, nil); rec.Code != http.StatusOK {
+		t.Errorf("approve report's leave: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	// Admins keep the override on every leave
+	if rec := env.do(http.MethodPost, "/api/leaves/"+other.ID+"/reject", env.admin, nil); rec.Code != http.StatusOK {
+		t.Errorf("admin reject: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := env.do(http.MethodGet, "/api/team/leaves", env.alice, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("team leaves as user: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestUpdateUserManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    func(e *testEnv) *models.User
+		manager func(e *testEnv) *string
+		want    int
+	}{
+		{"assign manager", func(e *testEnv) *models.User { return e.alice }, func(e *testEnv) *string { return &e.manager.ID }, http.StatusOK},
+		{"clear manager", func(e *testEnv) *models.User { return e.alice }, func(e *testEnv) *string { return nil }, http.StatusOK},
+		{"plain user cannot manage", func(e *testEnv) *models.User { return e.alice }, func(e *testEnv) *string { return &e.bob.ID }, http.StatusBadRequest},
+		{"self", func(e *testEnv) *models.User { return e.manager }, func(e *testEnv) *string { return &e.manager.ID }, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			rec := env.do(http.MethodPut, "/api/users/"+tt.user(env).ID+"/manager", env.admin, models.UpdateManagerRequest{ManagerID: tt.manager(env)})
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("reporting cycle", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.store.SetUserManager(env.admin.ID, env.manager.ID, &env.admin.ID); err != nil {
+			t.Fatal(err)
+		}
+		rec := env.do(http.MethodPut, "/api/users/"+env.admin.ID+"/manager", env.admin, models.UpdateManagerRequest{ManagerID: &env.manager.ID})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("non-admin is forbidden", func(t *testing.T) {
+		env := newTestEnv(t)
+		rec := env.do(http.MethodPut, "/api/users/"+env.alice.ID+"/manager", env.manager, models.UpdateManagerRequest{ManagerID: &env.manager.ID})
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestTeamAvailability(t *testing.T) {
+	env := newTestEnv(t)
+	for _, user := range []*models.User{env.alice, env.bob} {
+		if err := env.store.SetUserManager(env.admin.ID, user.ID, &env.manager.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	two := 2
+	if rec := env.do(http.MethodPut, "/api/users/"+env.manager.ID+"/min-staffing", env.admin, models.UpdateMinStaffingRequest{MinStaffing: &two}); rec.Code != http.StatusOK {
+		t.Fatalf("set minimum: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	env.seedLeave(env.alice, "2030-03-04", "2030-03-05", constants.LeaveStatusApproved)
+	pending := env.seedLeave(env.bob, "2030-03-05", "2030-03-05", constants.LeaveStatusPending)
+
+	rec := env.do(http.MethodGet, "/api/team/availability?from=2030-03-04&to=2030-03-10", env.manager, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("availability: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var availability models.TeamAvailability
+	if err := json.Unmarshal(rec.Body.Bytes(), &availability); err != nil {
+		t.Fatal(err)
+	}
+	if len(availability.Members) != 2 || len(availability.Days) != 7 {
+		t.Fatalf("availability = %+v, want 2 members over 7 days", availability)
+	}
+	tuesday := availability.Days[1]
+	if len(tuesday.Out) != 2 || tuesday.Available != 1 || !tuesday.BelowMinimum {
+		t.Errorf("tuesday = %+v, want both out and only alice's approved leave counted", tuesday)
+	}
+	if wednesday := availability.Days[2]; len(wednesday.Out) != 0 || wednesday.Available != 2 || wednesday.BelowMinimum {
+		t.Errorf("wednesday = %+v, want everyone in", wednesday)
+	}
+	if saturday := availability.Days[5]; saturday.Working {
+		t.Errorf("saturday = %+v, want a non-working day", saturday)
+	}
+
+	rec = env.do(http.MethodPost, "/api/leaves/"+pending.ID+"/approve", env.manager, nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("approve without confirm: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	var refused struct {
+		StaffingWarnings []models.StaffingWarning `json:"staffingWarnings"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &refused); err != nil {
+		t.Fatal(err)
+	}
+	if w := refused.StaffingWarnings; len(w) != 1 || w[0].Date != "2030-03-05" || w[0].Available != 0 || w[0].MinStaffing != 2 {
+		t.Errorf("warnings = %+v, want tuesday with nobody in", w)
+	}
+	if got, _ := env.store.GetLeaveByID(pending.ID); got.Status != string(constants.LeaveStatusPending) {
+		t.Errorf("leave status after refused approval = %s, want pending", got.Status)
+	}
+
+	rec = env.do(http.MethodPost, "/api/leaves/"+pending.ID+"/approve", env.manager, map[string]any{"confirm": true})
+	leave := decodeLeave(t, rec)
+	if leave.Status != string(constants.LeaveStatusApproved) {
+		t.Fatalf("approve with confirm: status = %s, want approved despite the warning", leave.Status)
+	}
+	if w := leave.StaffingWarnings; len(w) != 1 || w[0].Date != "2030-03-05" {
+		t.Errorf("warnings = %+v, want tuesday", w)
+	}
+
+	for _, tc := range []struct {
+		path string
+		as   *models.User
+		want int
+	}{
+		{"/api/team/availability", env.alice, http.StatusForbidden},
+		{"/api/team/availability?managerId=" + env.admin.ID, env.manager, http.StatusForbidden},
+		{"/api/team/availability?managerId=" + env.manager.ID + "&from=2030-03-04&to=2030-03-04", env.admin, http.StatusOK},
+		{"/api/team/availability?from=2030-03-04&to=2030-12-31", env.manager, http.StatusBadRequest},
+		{"/api/team/availability?from=2030-03-04&to=2030-03-01", env.manager, http.StatusBadRequest},
+	} {
+		if rec := env.do(http.MethodGet, tc.path, tc.as, nil); rec.Code != tc.want {
+			t.Errorf("%s as %s: status = %d, want %d", tc.path, tc.as.Email, rec.Code, tc.want)
+		}
+	}
+	if rec := env.do(http.MethodPut, "/api/users/"+env.alice.ID+"/min-staffing", env.admin, models.UpdateMinStaffingRequest{MinStaffing: &two}); rec.Code != http.StatusBadRequest {
+		t.Errorf("minimum for a plain user: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,193 @@
+// internal/handlers/webhooks_test.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/webhook"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is a webhook endpoint that records requests and answers with
+// status
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, string) {
+	r := &receiver{status: status}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(srv.Close)
+	return r, srv.URL
+}
+
+func (e *testEnv) createWebhook(url string, events ...string) models.WebhookSubscription {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/api/admin/webhooks", e.admin, models.WebhookSubscription{URL: url, Events: events})
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("create webhook: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var sub models.WebhookSubscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &sub); err != nil {
+		e.t.Fatal(err)
+	}
+	return sub
+}
+
+func (e *testEnv) deliveries(sub models.WebhookSubscription) []models.WebhookDelivery {
+	e.t.Helper()
+	rec := e.do(http.MethodGet, "/api/admin/webhooks/"+sub.ID+"/deliveries", e.admin, nil)
+	if rec.Code != http.StatusOK {
+		e.t.Fatalf("deliveries: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var deliveries []models.WebhookDelivery
+	if err := json.Unmarshal(rec.Body.Bytes(), &deliveries); err != nil {
+		e.t.Fatal(err)
+	}
+	return deliveries
+}
+
+func (e *testEnv) dispatch(at time.Time) int {
+	e.t.Helper()
+	d := webhook.Dispatcher{Outbox: e.store}
+	n, err := d.RunOnce(context.Background(), at)
+	if err != nil {
+		e.t.Fatalf("dispatch: %v", err)
+	}
+	return n
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	env := newTestEnv(t)
+
+	sub := env.createWebhook("https://example.com/hook", "leave.approved")
+	if len(sub.Secret) < 16 {
+		t.Errorf("generated secret = %q, want one", sub.Secret)
+	}
+
+	rec := env.do(http.MethodGet, "/api/admin/webhooks", env.admin, nil)
+	var subs []models.WebhookSubscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &subs); err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].Secret != "" {
+		t.Errorf("list = %+v, want one subscription without its secret", subs)
+	}
+
+	for _, body := range []models.WebhookSubscription{
+		{URL: "https://example.com/hook", Events: []string{"leave.exploded"}},
+		{URL: "not a url", Events: []string{"leave.approved"}},
+		{URL: "https://example.com/hook"},
+	} {
+		if rec := env.do(http.MethodPost, "/api/admin/webhooks", env.admin, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%+v: status = %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if rec := env.do(http.MethodGet, "/api/admin/webhooks", env.alice, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("as user: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := env.do(http.MethodDelete, "/api/admin/webhooks/"+sub.ID, env.admin, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := env.do(http.MethodGet, "/api/admin/webhooks/"+sub.ID+"/deliveries", env.admin, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("deliveries of deleted webhook: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	t.Run("subscribed events are signed and logged", func(t *testing.T) {
+		env := newTestEnv(t)
+		recv, url := newReceiver(t, http.StatusOK)
+		sub := env.createWebhook(url, "leave.approved")
+
+		leave := env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+		env.do(http.MethodPost, "/api/leaves/"+leave.ID+"/approve", env.admin, nil)
+
+		if n := env.dispatch(time.Now()); n != 1 {
+			t.Fatalf("dispatched = %d, want 1 (creation is not subscribed)", n)
+		}
+		req, body := recv.requests[0], recv.bodies[0]
+		if got := req.Header.Get(webhook.HeaderEvent); got != "leave.approved" {
+			t.Errorf("event header = %q, want leave.approved", got)
+		}
+		timestamp, _ := strconv.ParseInt(req.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if got, want := req.Header.Get(webhook.HeaderSignature), webhook.Sign(sub.Secret, timestamp, body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		var payload models.WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Leave.ID != leave.ID || payload.Leave.Status != string(constants.LeaveStatusApproved) || payload.Leave.UserEmail != env.alice.Email {
+			t.Errorf("payload leave = %+v, want alice's approved leave", payload.Leave)
+		}
+
+		deliveries := env.deliveries(sub)
+		if len(deliveries) != 1 || deliveries[0].Status != string(constants.WebhookStatusSucceeded) || deliveries[0].DeliveredAt == nil {
+			t.Errorf("deliveries = %+v, want one succeeded", deliveries)
+		}
+	})
+
+	t.Run("failures are retried with backoff", func(t *testing.T) {
+		env := newTestEnv(t)
+		recv, url := newReceiver(t, http.StatusInternalServerError)
+		sub := env.createWebhook(url, "leave.created")
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+
+		now := time.Now()
+		env.dispatch(now)
+		deliveries := env.deliveries(sub)
+		if len(deliveries) != 1 || deliveries[0].Attempts != 1 || deliveries[0].Status != string(constants.WebhookStatusPending) {
+			t.Fatalf("deliveries = %+v, want one pending after one attempt", deliveries)
+		}
+		if code := deliveries[0].LastStatusCode; code == nil || *code != http.StatusInternalServerError {
+			t.Errorf("last status code = %v, want 500", code)
+		}
+
+		if n := env.dispatch(now.Add(10 * time.Second)); n != 0 {
+			t.Errorf("dispatched before backoff elapsed = %d, want 0", n)
+		}
+		recv.mu.Lock()
+		recv.status = http.StatusNoContent
+		recv.mu.Unlock()
+		if n := env.dispatch(now.Add(constants.WebhookRetryBaseSeconds*time.Second + time.Second)); n != 1 {
+			t.Errorf("dispatched after backoff = %d, want 1", n)
+		}
+		if d := env.deliveries(sub)[0]; d.Status != string(constants.WebhookStatusSucceeded) || d.Attempts != 2 {
+			t.Errorf("delivery = %+v, want succeeded on the second attempt", d)
+		}
+	})
+
+	t.Run("disabled subscriptions receive nothing", func(t *testing.T) {
+		env := newTestEnv(t)
+		_, url := newReceiver(t, http.StatusOK)
+		sub := env.createWebhook(url, "leave.created", "leave.deleted")
+		rec := env.do(http.MethodPut, "/api/admin/webhooks/"+sub.ID, env.admin, models.WebhookSubscription{URL: url, Events: sub.Events, Disabled: true})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("disable: status = %d: %s", rec.Code, rec.Body.String())
+		}
+
+		env.seedLeave(env.alice, "2030-03-04", "2030-03-08", constants.LeaveStatusPending)
+		if n := env.dispatch(time.Now()); n != 0 {
+			t.Errorf("dispatched = %d, want 0", n)
+		}
+	})
+}
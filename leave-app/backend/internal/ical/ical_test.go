@@ -0,0 +1,100 @@
+// internal/ical/ical_test.go
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "On leave", want: "On leave"},
+		{in: `C:\path`, want: `C:\\path`},
+		{in: "a;b,c", want: `a\;b\,c`},
+		{in: "one\ntwo\r\nthree", want: `one\ntwo\nthree`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "short", in: "SUMMARY:Leave", want: "SUMMARY:Leave\r\n"},
+		{name: "exactly 75 octets", in: strings.Repeat("a", 75), want: strings.Repeat("a", 75) + "\r\n"},
+		{
+			name: "continued with a space",
+			in:   strings.Repeat("a", 80),
+			want: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 5) + "\r\n",
+		},
+		{
+			name: "multibyte runes are not split",
+			in:   strings.Repeat("a", 74) + "é",
+			want: strings.Repeat("a", 74) + "\r\n é\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeFolded(w, tt.in)
+			w.Flush()
+			if got := buf.String(); got != tt.want {
+				t.Errorf("folded = %q, want %q", got, tt.want)
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+				if len(line) > 75 {
+					t.Errorf("line of %d octets: %q", len(line), line)
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarWrite(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	cal := Calendar{Name: "Leave (me)", Events: []Event{
+		// Leave on the 4th to the 6th ends on the 7th, exclusive
+		{UID: "a@leave-app", Sequence: 2, Summary: "Annual, unpaid", Start: day("2030-03-04"), End: day("2030-03-07"), AllDay: true},
+		{UID: "b@leave-app", Summary: "Hourly", Start: day("2030-03-08").Add(9 * time.Hour), End: day("2030-03-08").Add(11 * time.Hour)},
+	}}
+
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Date(2030, 3, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Leave (me)\r\n",
+		"UID:a@leave-app\r\nSEQUENCE:2\r\nDTSTAMP:20300301T120000Z\r\n",
+		"DTSTART;VALUE=DATE:20300304\r\nDTEND;VALUE=DATE:20300307\r\n",
+		`SUMMARY:Annual\, unpaid` + "\r\n",
+		"DTSTART:20300308T090000\r\nDTEND:20300308T110000\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "BEGIN:VEVENT") != 2 || strings.Count(got, "END:VEVENT") != 2 {
+		t.Errorf("want two events:\n%s", got)
+	}
+}
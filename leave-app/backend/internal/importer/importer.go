@@ -0,0 +1,296 @@
+// internal/importer/importer.go
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"leave-app/internal/workdays"
+	"net/mail"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns of the users file; only email is required
+var userColumns = []string{"email", "role", "manager", "annual", "sick", "casual"}
+
+// Columns of the leaves file; portion and reason may be left out
+var leaveColumns = []string{"email", "type", "start_date", "end_date", "portion", "status", "reason"}
+
+var requiredLeaveColumns = []string{"email", "type", "start_date", "end_date", "status"}
+
+// Statuses imported leave may have; history has no open requests
+var leaveStatuses = []string{
+	string(constants.LeaveStatusApproved),
+	string(constants.LeaveStatusRejected),
+	string(constants.LeaveStatusWithdrawn),
+	string(constants.LeaveStatusCancelled),
+}
+
+var roles = []string{
+	string(constants.RoleUser),
+	string(constants.RoleManager),
+	string(constants.RoleHR),
+	string(constants.RoleAdmin),
+}
+
+// Portions imported leave may have; hourly leave needs times the file
+// does not carry
+var portions = []string{
+	string(constants.LeavePortionFull),
+	string(constants.LeavePortionFirstHalf),
+	string(constants.LeavePortionSecondHalf),
+}
+
+// Store is the part of db.Store the importer uses
+type Store interface {
+	ImportRecords(actorID string, batch *models.ImportBatch, dryRun bool) (*models.ImportResult, error)
+}
+
+// Run parses a users file and a leaves file, either of which may be nil,
+// and imports them for actorID. Rows that fail to parse are reported
+// alongside the store's own row errors, and stop anything being applied.
+func Run(store Store, actorID string, users, leaves io.Reader, dryRun bool) (*models.ImportResult, error) {
+	batch := &models.ImportBatch{}
+	var errs []models.ImportRowError
+	if users != nil {
+		batch.Users, errs = ParseUsers(users)
+	}
+	if leaves != nil {
+		var leaveErrs []models.ImportRowError
+		batch.Leaves, leaveErrs = ParseLeaves(leaves)
+		errs = append(errs, leaveErrs...)
+	}
+
+	result, err := store.ImportRecords(actorID, batch, dryRun || len(errs) > 0)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = dryRun
+	result.Errors = append(errs, result.Errors...)
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		a, b := result.Errors[i], result.Errors[j]
+		if a.File != b.File {
+			return a.File == "users"
+		}
+		return a.Row < b.Row
+	})
+	return result, nil
+}
+
+// table reads a CSV file with a header row into records keyed by column.
+// Rows are numbered by the line they start on, so the header is row 1.
+type table struct {
+	file    string
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newTable(file string, r io.Reader, known, required []string) (*table, []models.ImportRowError) {
+	t := &table{file: file, reader: csv.NewReader(r), columns: make(map[string]int)}
+	t.reader.FieldsPerRecord = -1
+	t.reader.TrimLeadingSpace = true
+
+	header, err := t.reader.Read()
+	t.row = 1
+	if errors.Is(err, io.EOF) {
+		return nil, []models.ImportRowError{t.error("", "file is empty")}
+	}
+	if err != nil {
+		return nil, []models.ImportRowError{t.error("", err.Error())}
+	}
+	var errs []models.ImportRowError
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(known, name) {
+			errs = append(errs, t.error(name, "unknown column"))
+			continue
+		}
+		if _, ok := t.columns[name]; ok {
+			errs = append(errs, t.error(name, "duplicate column"))
+			continue
+		}
+		t.columns[name] = i
+	}
+	for _, name := range required {
+		if _, ok := t.columns[name]; !ok {
+			errs = append(errs, t.error(name, "missing column"))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return t, nil
+}
+
+func (t *table) error(column, message string) models.ImportRowError {
+	return models.ImportRowError{File: t.file, Row: t.row, Column: column, Message: message}
+}
+
+// next reads the next record, reporting false at the end of the file
+func (t *table) next() (map[string]string, *models.ImportRowError, bool) {
+	record, err := t.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, false
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			t.row, err = parseErr.StartLine, parseErr.Err
+		}
+		rowErr := t.error("", err.Error())
+		return nil, &rowErr, true
+	}
+	t.row, _ = t.reader.FieldPos(0)
+	fields := make(map[string]string, len(t.columns))
+	for name, i := range t.columns {
+		if i < len(record) {
+			fields[name] = strings.TrimSpace(record[i])
+		}
+	}
+	return fields, nil, true
+}
+
+// checkEmail reports whether s is a bare email address
+func checkEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// ParseUsers reads a users file: email, role, manager (an email) and the
+// annual, sick and casual allowances
+func ParseUsers(r io.Reader) ([]models.ImportUser, []models.ImportRowError) {
+	t, errs := newTable("users", r, userColumns, []string{"email"})
+	if t == nil {
+		return nil, errs
+	}
+
+	users := make([]models.ImportUser, 0)
+	seen := make(map[string]int)
+	for {
+		fields, rowErr, ok := t.next()
+		if !ok {
+			break
+		}
+		if rowErr != nil {
+			errs = append(errs, *rowErr)
+			continue
+		}
+
+		u := models.ImportUser{Row: t.row, Email: fields["email"], Role: fields["role"], ManagerEmail: fields["manager"]}
+		valid := true
+		fail := func(column, message string) {
+			errs = append(errs, t.error(column, message))
+			valid = false
+		}
+		switch first, dup := seen[u.Email]; {
+		case !checkEmail(u.Email):
+			fail("email", "not an email address")
+		case dup:
+			fail("email", fmt.Sprintf("already on row %d", first))
+		default:
+			seen[u.Email] = t.row
+		}
+		if u.Role != "" && !slices.Contains(roles, u.Role) {
+			fail("role", "must be one of "+strings.Join(roles, ", "))
+		}
+		if u.ManagerEmail != "" && !checkEmail(u.ManagerEmail) {
+			fail("manager", "not an email address")
+		}
+		for _, a := range []struct {
+			column string
+			dest   **int
+		}{
+			{"annual", &u.Annual},
+			{"sick", &u.Sick},
+			{"casual", &u.Casual},
+		} {
+			if fields[a.column] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(fields[a.column])
+			if err != nil || n < 0 {
+				fail(a.column, "must be a whole number of days")
+				continue
+			}
+			*a.dest = &n
+		}
+		if valid {
+			users = append(users, u)
+		}
+	}
+	return users, errs
+}
+
+// ParseLeaves reads a leaves file: the employee's email, type, YYYY-MM-DD
+// start and end dates, portion, final status and reason
+func ParseLeaves(r io.Reader) ([]models.ImportLeave, []models.ImportRowError) {
+	t, errs := newTable("leaves", r, leaveColumns, requiredLeaveColumns)
+	if t == nil {
+		return nil, errs
+	}
+
+	leaves := make([]models.ImportLeave, 0)
+	for {
+		fields, rowErr, ok := t.next()
+		if !ok {
+			break
+		}
+		if rowErr != nil {
+			errs = append(errs, *rowErr)
+			continue
+		}
+
+		l := models.ImportLeave{
+			Row:       t.row,
+			Email:     fields["email"],
+			Type:      fields["type"],
+			StartDate: fields["start_date"],
+			EndDate:   fields["end_date"],
+			Portion:   fields["portion"],
+			Status:    fields["status"],
+			Reason:    fields["reason"],
+		}
+		if l.Portion == "" {
+			l.Portion = string(constants.LeavePortionFull)
+		}
+		valid := true
+		fail := func(column, message string) {
+			errs = append(errs, t.error(column, message))
+			valid = false
+		}
+		if !checkEmail(l.Email) {
+			fail("email", "not an email address")
+		}
+		if l.Type == "" {
+			fail("type", "required")
+		}
+		start, err := time.Parse(workdays.DateLayout, l.StartDate)
+		if err != nil {
+			fail("start_date", "must be a YYYY-MM-DD date")
+		}
+		end, err := time.Parse(workdays.DateLayout, l.EndDate)
+		if err != nil {
+			fail("end_date", "must be a YYYY-MM-DD date")
+		} else if end.Before(start) {
+			fail("end_date", "must not be before start_date")
+		}
+		if !slices.Contains(portions, l.Portion) {
+			fail("portion", "must be one of "+strings.Join(portions, ", "))
+		}
+		if !slices.Contains(leaveStatuses, l.Status) {
+			fail("status", "must be one of "+strings.Join(leaveStatuses, ", "))
+		}
+		if valid {
+			leaves = append(leaves, l)
+		}
+	}
+	return leaves, errs
+}
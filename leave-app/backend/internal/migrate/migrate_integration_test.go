@@ -0,0 +1,90 @@
+//go:build integration
+
+// internal/migrate/migrate_integration_test.go
+package migrate
+
+import (
+	"leave-app/internal/testdb"
+	"leave-app/migrations"
+	"testing"
+)
+
+func appliedCount(t *testing.T, r *Runner) int {
+	t.Helper()
+	statuses, err := r.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := 0
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			applied++
+		}
+	}
+	return applied
+}
+
+func TestRunnerUpDown(t *testing.T) {
+	conn := testdb.Open(t)
+	r, err := New(conn, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := r.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(r.migrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(r.migrations))
+	}
+	if again, err := r.Up(); err != nil || len(again) != 0 {
+		t.Errorf("second Up applied %d, err %v; want none", len(again), err)
+	}
+
+	// Every down file must undo its up file for the next Up to succeed
+	reverted, err := r.Down(len(r.migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(r.migrations) {
+		t.Errorf("reverted %d migrations, want %d", len(reverted), len(r.migrations))
+	}
+	if got := appliedCount(t, r); got != 0 {
+		t.Errorf("%d migrations still applied", got)
+	}
+	if _, err := r.Up(); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+}
+
+func TestRunnerLegacyBaseline(t *testing.T) {
+	conn := testdb.Open(t)
+	r, err := New(conn, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old boot-time Migrate() ran the initial schema without recording it
+	for _, stmt := range SplitStatements(r.migrations[0].Up) {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	applied, err := r.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(r.migrations)-1 {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(r.migrations)-1)
+	}
+	for _, m := range applied {
+		if m.Version == legacyVersion {
+			t.Errorf("legacy migration %03d_%s ran again", m.Version, m.Name)
+		}
+	}
+	if got := appliedCount(t, r); got != len(r.migrations) {
+		t.Errorf("%d migrations recorded, want %d", got, len(r.migrations))
+	}
+}
@@ -0,0 +1,169 @@
+// internal/migrate/migrate_test.go
+package migrate
+
+import (
+	"leave-app/migrations"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"only whitespace and semicolons", " ;\n; ;", nil},
+		{"single without semicolon", "SELECT 1", []string{"SELECT 1"}},
+		{"several", "SELECT 1;\nSELECT 2;\n", []string{"SELECT 1", "SELECT 2"}},
+		{"line comments", "-- header; not a statement\nSELECT 1; # trailing;\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"block comment", "/* one; two */ SELECT 1;/* three */", []string{"SELECT 1"}},
+		{"semicolon in single quotes", "INSERT INTO t VALUES ('a;b');", []string{"INSERT INTO t VALUES ('a;b')"}},
+		{"semicolon in double quotes", `SELECT "a;b";`, []string{`SELECT "a;b"`}},
+		{"semicolon in backticks", "SELECT 1 AS `a;b`;", []string{"SELECT 1 AS `a;b`"}},
+		{"escaped quote", `SELECT 'it\'s; fine'; SELECT 2`, []string{`SELECT 'it\'s; fine'`, "SELECT 2"}},
+		{"doubled quote", "SELECT 'it''s; fine'; SELECT 2", []string{"SELECT 'it''s; fine'", "SELECT 2"}},
+		{"comment markers in strings", "SELECT '-- no', '# no', '/* no */';", []string{"SELECT '-- no', '# no', '/* no */'"}},
+		{"quote in comment", "-- don't\nSELECT 1;", []string{"SELECT 1"}},
+		{"multi-line statement", "CREATE TABLE t (\n    id INT -- key\n);", []string{"CREATE TABLE t (\n    id INT \n)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "pairs up and down by version, in order",
+			files: fstest.MapFS{
+				"002_second.up.sql":   file("up 2"),
+				"001_first.down.sql":  file("down 1"),
+				"001_first.up.sql":    file("up 1"),
+				"010_tenth.up.sql":    file("up 10"),
+				"010_tenth.down.sql":  file("down 10"),
+				"README.md":           file("ignored"),
+				"003_bad-name.up.sql": file("ignored"),
+			},
+			want: []Migration{
+				{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+				{Version: 2, Name: "second", Up: "up 2"},
+				{Version: 10, Name: "tenth", Up: "up 10", Down: "down 10"},
+			},
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"001_first.down.sql": file("down 1")},
+			wantErr: "has no up file",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"001_first.up.sql":   file("up 1"),
+				"001_other.down.sql": file("down 1"),
+			},
+			wantErr: "conflicting names",
+		},
+		{
+			name:  "empty",
+			files: fstest.MapFS{},
+			want:  []Migration{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The embedded migrations must load, be numbered without gaps and all be
+// reversible
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("migration %03d_%s: want version %d", m.Version, m.Name, i+1)
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %03d_%s has no down file", m.Version, m.Name)
+		}
+		if len(SplitStatements(m.Up)) == 0 {
+			t.Errorf("migration %03d_%s has no statements", m.Version, m.Name)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"001_first.up.sql":   "up",
+		"001_first.down.sql": "down",
+		"007_seventh.up.sql": "up",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up, down, err := Create(dir, "Add Leave Notes!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "008_add_leave_notes.up.sql"); up != want {
+		t.Errorf("up = %s, want %s", up, want)
+	}
+	if want := filepath.Join(dir, "008_add_leave_notes.down.sql"); down != want {
+		t.Errorf("down = %s, want %s", down, want)
+	}
+
+	loaded, err := Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := loaded[len(loaded)-1]
+	if last.Version != 8 || last.Name != "add_leave_notes" {
+		t.Errorf("last migration = %03d_%s, want 008_add_leave_notes", last.Version, last.Name)
+	}
+
+	if _, _, err := Create(dir, " -- "); err == nil {
+		t.Error("Create with an empty name succeeded")
+	}
+	if _, _, err := Create(t.TempDir(), "first"); err != nil {
+		t.Errorf("Create in an empty directory: %v", err)
+	}
+}
@@ -1,7 +1,11 @@
 // internal/models/models.go
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"leave-app/internal/constants"
+	"time"
+)
 
 type Allowance struct {
 	Sick   int `json:"sick"`
@@ -9,25 +13,529 @@ type Allowance struct {
 	Casual int `json:"casual"`
 }
 
+// For returns the allowance for a leave type
+func (a Allowance) For(leaveType string) (int, bool) {
+	switch constants.LeaveType(leaveType) {
+	case constants.LeaveTypeSick:
+		return a.Sick, true
+	case constants.LeaveTypeAnnual:
+		return a.Annual, true
+	case constants.LeaveTypeCasual:
+		return a.Casual, true
+	}
+	return 0, false
+}
+
+// A leave type from leave_types. Sick, annual and casual take their
+// allowance from the user's effective policy; other types that count
+// against a balance use DefaultAllowance.
+type LeaveType struct {
+	Code string `json:"code" binding:"required,max=32,lowercase"`
+	LeaveTypeSettings
+}
+
+// The editable part of a leave type, also the body of
+// PUT /api/admin/leave-types/:code
+type LeaveTypeSettings struct {
+	Name                 string   `json:"name" binding:"required,max=255"`
+	Paid                 bool     `json:"paid"`
+	RequiresAttachment   bool     `json:"requiresAttachment"`                                     // Checked before the leave is approved
+	AttachmentOverDays   *float64 `json:"attachmentOverDays,omitempty" binding:"omitempty,min=0"` // Only longer leaves need one; nil means all
+	MaxConsecutiveDays   *int     `json:"maxConsecutiveDays,omitempty" binding:"omitempty,min=1"` // Calendar days; nil means no limit
+	CountsAgainstBalance bool     `json:"countsAgainstBalance"`
+	DefaultAllowance     int      `json:"defaultAllowance" binding:"min=0"` // Days per year
+}
+
+// NeedsAttachment reports whether a leave of this type lasting days must
+// have an attachment before it is approved
+func (t LeaveType) NeedsAttachment(days float64) bool {
+	return t.RequiresAttachment && (t.AttachmentOverDays == nil || days > *t.AttachmentOverDays)
+}
+
+// AllowanceFrom returns the yearly allowance of this type given a user's
+// effective allowances
+func (t LeaveType) AllowanceFrom(a Allowance) int {
+	if days, ok := a.For(t.Code); ok {
+		return days
+	}
+	return t.DefaultAllowance
+}
+
+// For GET /api/me/balance, keyed by leave type. For accruing types the
+// allowance is what has been credited so far, including carry-forward.
+type LeaveBalance struct {
+	Allowance      float64 `json:"allowance"`
+	CarriedForward float64 `json:"carriedForward,omitempty"`
+	Used           float64 `json:"used"`
+	Pending        float64 `json:"pending"`
+	Remaining      float64 `json:"remaining"`
+}
+
 type User struct {
-	ID          string    `json:"id"`
-	Email       string    `json:"email"`
-	Role        string    `json:"role"`
-	Allowances  Allowance `json:"allowances"`
-	CreatedAt   time.Time `json:"-"` // Exclude from JSON responses
+	ID         string    `json:"id"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	ManagerID  *string   `json:"managerId,omitempty"`
+	Allowances Allowance `json:"allowances"`
+	CreatedAt  time.Time `json:"-"` // Exclude from JSON responses
+
+	EffectivePolicy *EffectivePolicy `json:"effectivePolicy,omitempty"` // Only on GET /api/me
+}
+
+// A public holiday excluded from working-day counts, also the body of
+// PUT /api/admin/holidays/:date, which takes the date from the path
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// Named allowances assigned to users directly or through their groups
+type AllowancePolicy struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name" binding:"required,max=255"`
+	Priority   int       `json:"priority"` // Highest wins among a user's groups
+	Allowances Allowance `json:"allowances"`
+	Users      []string  `json:"users"`  // IDs of users assigned directly
+	Groups     []string  `json:"groups"` // Group names assigned
+}
+
+// The allowances that apply to a user and where they come from
+type EffectivePolicy struct {
+	Source     string    `json:"source"` // user, group or default
+	PolicyID   string    `json:"policyId,omitempty"`
+	PolicyName string    `json:"policyName,omitempty"`
+	Group      string    `json:"group,omitempty"` // Group the policy was assigned through
+	Allowances Allowance `json:"allowances"`
 }
 
 type Leave struct {
-	ID              string    `json:"id"`
-	UserID          string    `json:"userId"`
-	UserEmail       string    `json:"userEmail,omitempty"` // Used for GET requests
-	Type            string    `json:"type"`
-	StartDate       string    `json:"startDate"`
-	EndDate         string    `json:"endDate"`
-	Reason          string    `json:"reason"`
-	Status          string    `json:"status"`
-	ApproverComment *string   `json:"approverComment,omitempty"`
-	CreatedAt       time.Time `json:"createdAt"`
+	ID              string     `json:"id"`
+	UserID          string     `json:"userId"`
+	UserEmail       string     `json:"userEmail,omitempty"` // Used for GET requests
+	Type            string     `json:"type"`
+	StartDate       string     `json:"startDate"`
+	EndDate         string     `json:"endDate"`
+	Portion         string     `json:"portion"`
+	StartTime       *string    `json:"startTime,omitempty"` // Hourly leave only, HH:MM
+	EndTime         *string    `json:"endTime,omitempty"`
+	Reason          string     `json:"reason"`
+	Status          string     `json:"status"`
+	ApproverID      *string    `json:"approverId,omitempty"`    // Manager the leave is routed to; admins can always decide
+	CurrentStep     *string    `json:"currentStep,omitempty"`   // Role that must decide next while pending
+	AwaitingSince   *time.Time `json:"awaitingSince,omitempty"` // When the current step started waiting
+	RemindedAt      *time.Time `json:"-"`                       // Approvers reminded of the current step
+	EscalatedAt     *time.Time `json:"-"`                       // Escalated to admins
+	WorkingDays     float64    `json:"workingDays"`
+	ApproverComment *string    `json:"approverComment,omitempty"`
+	Sequence        int        `json:"-"` // Revision number, bumped on every status change
+	CreatedAt       time.Time  `json:"createdAt"`
+
+	Approvals        []LeaveApproval   `json:"approvals,omitempty"`        // Only on single-leave responses
+	Attachments      []Attachment      `json:"attachments,omitempty"`      // Only on single-leave responses
+	StaffingWarnings []StaffingWarning `json:"staffingWarnings,omitempty"` // Only on confirmed approval responses
+}
+
+// A file uploaded to a leave, e.g. a medical certificate
+type Attachment struct {
+	ID          string    `json:"id"`
+	LeaveID     string    `json:"leaveId"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	UploadedBy  string    `json:"uploadedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// A user's notification settings, also the body of
+// PUT /api/me/notifications
+type NotificationPreferences struct {
+	Email bool `json:"email"` // Leave request and decision emails
+}
+
+// An endpoint notified of leave lifecycle events. The secret is only
+// returned when the subscription is created.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url" binding:"required,http_url,max=2048"`
+	Secret    string    `json:"secret,omitempty" binding:"omitempty,min=16,max=255"` // Generated when empty
+	Events    []string  `json:"events" binding:"required,min=1,dive,oneof=leave.created leave.approved leave.rejected leave.cancelled leave.deleted"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// One event queued for a subscription, with the outcome of its latest
+// attempt
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscriptionId"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"` // While pending
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      *string         `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+
+	URL    string `json:"-"` // Filled in for the dispatcher
+	Secret string `json:"-"`
+}
+
+// The body of a webhook request
+type WebhookPayload struct {
+	ID        string    `json:"id"` // Delivery ID; the same on every retry
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Leave     Leave     `json:"leave"`
+}
+
+// One step of a leave's approval chain
+type LeaveApproval struct {
+	Step         int        `json:"step"`
+	Role         string     `json:"role"`
+	Decision     string     `json:"decision"`
+	ApproverID   *string    `json:"approverId,omitempty"`
+	OnBehalfOfID *string    `json:"onBehalfOfId,omitempty"` // Delegator, when a delegate decided
+	Comment      *string    `json:"comment,omitempty"`
+	DecidedAt    *time.Time `json:"decidedAt,omitempty"`
+}
+
+// An approver's authority lent to a delegate from StartDate to EndDate,
+// inclusive. The delegate may decide whatever the delegator could.
+type Delegation struct {
+	ID             string    `json:"id"`
+	DelegatorID    string    `json:"delegatorId"`
+	DelegatorEmail string    `json:"delegatorEmail,omitempty"`
+	DelegateID     string    `json:"delegateId" binding:"required"`
+	DelegateEmail  string    `json:"delegateEmail,omitempty"`
+	StartDate      string    `json:"startDate" binding:"required"` // YYYY-MM-DD
+	EndDate        string    `json:"endDate" binding:"required"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// Days off earned by working WorkDate, a weekend day or holiday. Once
+// approved the credit can be spent on compoff leave until ExpiresOn.
+type CompOffCredit struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"userId"`
+	UserEmail  string     `json:"userEmail"`
+	WorkDate   string     `json:"workDate"` // YYYY-MM-DD
+	Days       float64    `json:"days"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`               // pending, approved or rejected
+	ApproverID *string    `json:"approverId,omitempty"` // Manager the claim is routed to
+	DecidedBy  *string    `json:"decidedBy,omitempty"`
+	DecidedAt  *time.Time `json:"decidedAt,omitempty"`
+	Comment    *string    `json:"comment,omitempty"`
+	ExpiresOn  *string    `json:"expiresOn,omitempty"` // YYYY-MM-DD, set on approval
+	Remaining  float64    `json:"remaining"`           // Days not yet spent, whether expired or not
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Ordered approval steps for leaves of a type longer than OverDays
+type ApprovalPolicy struct {
+	ID        string   `json:"id"`
+	LeaveType string   `json:"leaveType" binding:"required"`
+	OverDays  float64  `json:"overDays" binding:"min=0"`
+	Steps     []string `json:"steps" binding:"required,min=1,dive,oneof=manager hr admin"`
+}
+
+// How a leave type accrues. The user's allowance for the type is the yearly
+// entitlement; types without a policy get it in full every year.
+type AccrualPolicy struct {
+	LeaveType       string  `json:"leaveType" binding:"required"`
+	Frequency       string  `json:"frequency" binding:"required,oneof=monthly yearly"`
+	CarryForwardCap float64 `json:"carryForwardCap" binding:"min=0"`
+}
+
+// Totals of one accrual run
+type AccrualRun struct {
+	AsOf           string  `json:"asOf"`
+	Accrued        float64 `json:"accrued"`
+	CarriedForward float64 `json:"carriedForward"`
+	Expired        float64 `json:"expired"`
+}
+
+// Who in a manager's team, their direct reports, is out on each day of a
+// range. Pending leave is shown but only approved leave counts against
+// staffing.
+type TeamAvailability struct {
+	ManagerID   string            `json:"managerId"`
+	From        string            `json:"from"`
+	To          string            `json:"to"`
+	MinStaffing int               `json:"minStaffing"` // 0 when the team has no minimum
+	Members     []TeamMember      `json:"members"`
+	Days        []AvailabilityDay `json:"days"`
+}
+
+type TeamMember struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// One day of TeamAvailability. Available is the number of members
+// working, less half a day for half-day leave and the hours of hourly
+// leave.
+type AvailabilityDay struct {
+	Date         string              `json:"date"`
+	Working      bool                `json:"working"` // False on weekends and holidays, which list no leave
+	Available    float64             `json:"available"`
+	BelowMinimum bool                `json:"belowMinimum"`
+	Out          []AvailabilityEntry `json:"out"`
+}
+
+// A member's leave on one day
+type AvailabilityEntry struct {
+	UserID  string `json:"userId"`
+	Email   string `json:"email"`
+	LeaveID string `json:"leaveId"`
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Portion string `json:"portion"`
+}
+
+// A working day on which approving a leave leaves its team below the
+// minimum staffing
+type StaffingWarning struct {
+	Date        string  `json:"date"`
+	Available   float64 `json:"available"`
+	MinStaffing int     `json:"minStaffing"`
+}
+
+// One row of the audit trail. Before and After are the entity as JSON and
+// are absent for creations and deletions respectively.
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	ActorID    *string         `json:"actorId,omitempty"` // Empty for system changes
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   string          `json:"entityId,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// Query parameters of GET /api/admin/audit; empty fields match everything
+type AuditFilter struct {
+	ActorID    string `form:"actorId"`
+	Action     string `form:"action"`
+	EntityType string `form:"entityType"`
+	EntityID   string `form:"entityId"`
+	From       string `form:"from"` // YYYY-MM-DD, inclusive
+	To         string `form:"to"`   // YYYY-MM-DD, inclusive
+	Limit      int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// Query parameters of GET /api/team/availability. From defaults to today
+// and To to a week after From. Only admins may ask for another manager's
+// team.
+type AvailabilityFilter struct {
+	From      string `form:"from"` // YYYY-MM-DD, inclusive
+	To        string `form:"to"`   // YYYY-MM-DD, inclusive
+	ManagerID string `form:"managerId"`
+}
+
+// Query parameters of GET /api/admin/reports/leaves. Format is csv or
+// xlsx; without it the Accept header decides, defaulting to CSV.
+type LeaveReportFilter struct {
+	From   string `form:"from"` // YYYY-MM-DD; leaves ending on or after
+	To     string `form:"to"`   // YYYY-MM-DD; leaves starting on or before
+	Type   string `form:"type"`
+	Status string `form:"status"`
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx"`
+}
+
+// Query parameters of GET /api/admin/reports/balances; Year defaults to
+// the current year
+type BalanceReportFilter struct {
+	Year   int    `form:"year" binding:"omitempty,min=2000,max=2100"`
+	Type   string `form:"type"`
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx"`
+}
+
+// One row of the leave report. Approver is whoever decided the last step,
+// or the manager the leave is routed to while it waits.
+type LeaveReportRow struct {
+	Leave    Leave
+	Approver *string
+}
+
+// One row of the balance report
+type BalanceReportRow struct {
+	UserID string
+	Email  string
+	Type   string
+	LeaveBalance
+}
+
+// Query parameters of GET /api/admin/analytics. To defaults to today and
+// From to a year before To.
+type AnalyticsFilter struct {
+	From string `form:"from"` // YYYY-MM-DD, inclusive
+	To   string `form:"to"`   // YYYY-MM-DD, inclusive
+}
+
+// Absence trends from From to To. Days count approved leave on the working
+// days in the range; turnaround and rejection rates cover the leave filed
+// in it. Bradford factors instead cover the rolling window from
+// BradfordFrom to BradfordTo, the last constants.BradfordWeeks weeks.
+// Results are cached until leave changes, so GeneratedAt may be earlier
+// than the request.
+type LeaveAnalytics struct {
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	Months       []MonthlyLeaveDays   `json:"months"`
+	Teams        []TeamLeaveDays      `json:"teams"`
+	Turnaround   []ApprovalTurnaround `json:"turnaround"`
+	Rejections   []RejectionRate      `json:"rejections"`
+	Bradford     []BradfordScore      `json:"bradford"`
+	BradfordFrom string               `json:"bradfordFrom"`
+	BradfordTo   string               `json:"bradfordTo"`
+	GeneratedAt  time.Time            `json:"generatedAt"`
+}
+
+// Leave days taken in one month of LeaveAnalytics, by leave type
+type MonthlyLeaveDays struct {
+	Month string             `json:"month"` // YYYY-MM
+	Days  map[string]float64 `json:"days"`
+	Total float64            `json:"total"`
+}
+
+// Leave days taken by a manager's direct reports, by leave type. Users
+// without a manager form a team with no ManagerID. Members are counted as
+// the team is now.
+type TeamLeaveDays struct {
+	ManagerID    *string            `json:"managerId"`
+	ManagerEmail string             `json:"managerEmail,omitempty"`
+	Members      int                `json:"members"`
+	Days         map[string]float64 `json:"days"`
+	Total        float64            `json:"total"`
+}
+
+// How long decided leave of one type waited, from filing to its last
+// approval step being decided
+type ApprovalTurnaround struct {
+	Type         string  `json:"type"`
+	Decided      int     `json:"decided"`
+	AverageHours float64 `json:"averageHours"`
+	MaxHours     float64 `json:"maxHours"`
+}
+
+// The share of decided leave of one type that was rejected. Cancelled
+// leave counts as approved.
+type RejectionRate struct {
+	Type     string  `json:"type"`
+	Decided  int     `json:"decided"`
+	Rejected int     `json:"rejected"`
+	Rate     float64 `json:"rate"`
+}
+
+// An employee's Bradford factor for sick leave: spells squared times days.
+// Leaves only separated by non-working days are one spell.
+type BradfordScore struct {
+	UserID    string  `json:"userId"`
+	UserEmail string  `json:"userEmail"`
+	Spells    int     `json:"spells"`
+	Days      float64 `json:"days"`
+	Score     float64 `json:"score"`
+}
+
+// A row of a users import. Empty cells keep an existing user's value and
+// give a new user the default.
+type ImportUser struct {
+	Row          int
+	Email        string
+	Role         string
+	ManagerEmail string
+	Annual       *int
+	Sick         *int
+	Casual       *int
+}
+
+// A row of a leaves import: leave decided before the import, recorded
+// with its final status
+type ImportLeave struct {
+	Row       int
+	Email     string
+	Type      string
+	StartDate string
+	EndDate   string
+	Portion   string
+	Status    string
+	Reason    string
+}
+
+// The parsed rows of an import, applied together or not at all
+type ImportBatch struct {
+	Users  []ImportUser
+	Leaves []ImportLeave
+}
+
+// A problem with one row of an imported file. Row 1 is the header.
+type ImportRowError struct {
+	File    string `json:"file"` // users or leaves
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Result of POST /api/admin/import. Nothing is applied on a dry run or
+// when any row has an error.
+type ImportResult struct {
+	DryRun       bool             `json:"dryRun"`
+	Applied      bool             `json:"applied"`
+	UsersCreated int              `json:"usersCreated"`
+	UsersUpdated int              `json:"usersUpdated"`
+	Leaves       int              `json:"leaves"`
+	Errors       []ImportRowError `json:"errors"`
+}
+
+// Query parameters of POST /api/admin/import
+type ImportQuery struct {
+	DryRun bool `form:"dryRun"`
+}
+
+// Query parameters of GET /api/admin/webhooks/:id/deliveries
+type WebhookDeliveryFilter struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// Query parameters of GET /api/leaves. Non-admins only see their own
+// leaves whatever UserID says.
+type LeaveFilter struct {
+	Status string `form:"status"`
+	Type   string `form:"type"`
+	UserID string `form:"userId"`
+	From   string `form:"from"`                                                                     // YYYY-MM-DD; leaves ending on or after
+	To     string `form:"to"`                                                                       // YYYY-MM-DD; leaves starting on or before
+	Sort   string `form:"sort" binding:"omitempty,oneof=createdAt -createdAt startDate -startDate"` // Default -createdAt
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// Query parameters of GET /api/users
+type UserFilter struct {
+	Role   string `form:"role"`
+	Email  string `form:"email"`                                                            // Substring match
+	Sort   string `form:"sort" binding:"omitempty,oneof=email -email createdAt -createdAt"` // Default email
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// One page of GET /api/leaves; pass NextCursor back as cursor for the
+// next one. It is empty on the last page.
+type LeavePage struct {
+	Items      []Leave `json:"items"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// One page of GET /api/users
+type UserPage struct {
+	Items      []User `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // For POST /api/leaves
@@ -36,6 +544,10 @@ type CreateLeaveRequest struct {
 	StartDate string `json:"startDate" binding:"required"`
 	EndDate   string `json:"endDate" binding:"required"`
 	Reason    string `json:"reason" binding:"required"`
+	// Optional: full (default), first_half, second_half or hourly
+	Portion   string  `json:"portion" binding:"omitempty,oneof=full first_half second_half hourly"`
+	StartTime *string `json:"startTime,omitempty"` // Required for hourly leave, HH:MM
+	EndTime   *string `json:"endTime,omitempty"`
 }
 
 // For PUT /api/admin/allowances
@@ -45,13 +557,69 @@ type UpdateAllowancesRequest struct {
 	Casual int `json:"casual"`
 }
 
-// For PUT /api/leaves/:id/approve or /reject
+// For PUT /api/leaves/:id and the comment of POST /api/leaves/:id/approve
+// or /reject. Status must be reachable from the leave's current status.
 type UpdateLeaveStatusRequest struct {
 	Status  string  `json:"status"`
 	Comment *string `json:"comment,omitempty"`
+	Confirm bool    `json:"confirm,omitempty"` // Approve despite staffing warnings
+}
+
+// A decision on a leave's approval chain
+type LeaveDecision struct {
+	Approve bool
+	Comment *string
+	Confirm bool // Approve despite staffing warnings
+	// Decide every open step at once rather than the current one; only
+	// admins may, and only while the leave is pending
+	AllSteps bool
+}
+
+// For POST /api/me/comp-off; Days defaults to a full day
+type CreateCompOffRequest struct {
+	WorkDate string  `json:"workDate" binding:"required"`          // YYYY-MM-DD
+	Days     float64 `json:"days" binding:"omitempty,min=0,max=1"` // Half or whole day
+	Reason   string  `json:"reason" binding:"required,max=1000"`
+}
+
+// For PUT /api/admin/approval-policies; replaces every policy
+type UpdateApprovalPoliciesRequest struct {
+	Policies []ApprovalPolicy `json:"policies" binding:"dive"`
+}
+
+// For PUT /api/admin/accrual-policies; replaces every policy
+type UpdateAccrualPoliciesRequest struct {
+	Policies []AccrualPolicy `json:"policies" binding:"dive"`
+}
+
+// For POST /api/admin/accruals/run; AsOf defaults to today
+type RunAccrualsRequest struct {
+	AsOf string `json:"asOf"` // YYYY-MM-DD
+}
+
+// For PUT /api/admin/allowance-policies/:id/assignments; replaces the
+// policy's assignments and takes the users and groups off any other policy
+type AssignAllowancePolicyRequest struct {
+	Users  []string `json:"users"`
+	Groups []string `json:"groups" binding:"dive,required,max=64"`
+}
+
+// For PUT /api/users/:id/groups
+type UpdateUserGroupsRequest struct {
+	Groups []string `json:"groups" binding:"dive,required,max=64"`
 }
 
 // For PUT /api/users/:id/role
 type UpdateUserRoleRequest struct {
-	Role string `json:"role" binding:"required"`
+	Role string `json:"role" binding:"required,oneof=user manager hr admin"`
+}
+
+// For PUT /api/users/:id/min-staffing; 0 removes the team's minimum
+type UpdateMinStaffingRequest struct {
+	MinStaffing *int `json:"minStaffing" binding:"required,min=0"`
+}
+
+// For PUT /api/users/:id/manager; a null managerId clears the manager
+type UpdateManagerRequest struct {
+	ManagerID *string `json:"managerId"`
 }
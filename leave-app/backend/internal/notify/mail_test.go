@@ -0,0 +1,74 @@
+// internal/notify/mail_test.go
+package notify
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpStandIn accepts one message on a local port and returns what the
+// client sent after DATA
+func smtpStandIn(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 stand-in ready")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 stand-in")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				body, _ := tp.ReadDotLines()
+				data <- strings.Join(body, "\n")
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, data := smtpStandIn(t)
+	m := &SMTPMailer{Addr: addr, From: "leave@example.com"}
+
+	err := m.Send(Message{To: "alice@example.com", Subject: "Your leave request was approved\r\nBcc: eve@example.com", HTML: "<p>Enjoy</p>"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	got := <-data
+	headers, body, _ := strings.Cut(got, "\n\n")
+	if !strings.Contains(headers, "To: alice@example.com") || !strings.Contains(headers, "Content-Type: text/html; charset=utf-8") {
+		t.Errorf("headers = %q", headers)
+	}
+	if strings.Contains(headers, "\nBcc:") {
+		t.Errorf("subject injected a header: %q", headers)
+	}
+	if strings.TrimSpace(body) != "<p>Enjoy</p>" {
+		t.Errorf("body = %q, want the HTML", body)
+	}
+}
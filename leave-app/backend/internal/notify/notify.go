@@ -0,0 +1,180 @@
+// internal/notify/notify.go
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"log"
+	"sync"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Each email pairs the shared layout with its own "content" template
+var templates = map[string]*template.Template{
+	"awaiting_decision":     parseTemplate("awaiting_decision"),
+	"decided":               parseTemplate("decided"),
+	"cancellation_declined": parseTemplate("cancellation_declined"),
+}
+
+func parseTemplate(name string) *template.Template {
+	return template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+}
+
+// Store is the part of db.Store the notifier reads
+type Store interface {
+	GetStepApprovers(leave *models.Leave) ([]models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetNotificationPreferences(userID string) (*models.NotificationPreferences, error)
+	GetLeaveTypes() ([]models.LeaveType, error)
+}
+
+// OutcomeCancellationDeclined is the outcome of a refused cancellation. The
+// leave goes back to approved, so its status alone would read as approved.
+const OutcomeCancellationDeclined = "cancellation declined"
+
+// templateData is what every template is rendered with
+type templateData struct {
+	Recipient models.User
+	Leave     models.Leave
+	TypeName  string
+	Outcome   string // approved, rejected, cancelled or cancellation declined; decision emails only
+	Note      string // Why approvers are asked again, on reminders and escalations
+	Link      string
+}
+
+// Notifier emails people about leave they need to act on or that was
+// decided. Mail is sent in the background so a slow server never holds
+// up a request; failures are logged. A nil Notifier sends nothing.
+type Notifier struct {
+	Mailer Mailer
+	Store  Store
+	AppURL string // Linked from every email when set
+
+	wg sync.WaitGroup
+}
+
+// Wait blocks until mail already handed to the notifier has been sent
+func (n *Notifier) Wait() {
+	if n != nil {
+		n.wg.Wait()
+	}
+}
+
+func (n *Notifier) background(what string, leave models.Leave, send func() error) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		if err := send(); err != nil {
+			log.Printf("Could not send %s email for leave %s: %v", what, leave.ID, err)
+		}
+	}()
+}
+
+// AwaitingDecision tells the approvers of a pending leave, or of a
+// requested cancellation, that it needs their decision
+func (n *Notifier) AwaitingDecision(leave models.Leave) {
+	n.askApprovers("approval request", leave, "", "")
+}
+
+// Reminder tells the approvers of a leave that it is still waiting on them
+func (n *Notifier) Reminder(leave models.Leave) {
+	note := "This request is still waiting for your decision."
+	if leave.AwaitingSince != nil {
+		note = "This request has been waiting for your decision since " + leave.AwaitingSince.Format("2 Jan 2006 15:04 MST") + "."
+	}
+	n.askApprovers("reminder", leave, "Reminder: ", note)
+}
+
+// Escalated tells the approvers a leave was escalated to that it needs
+// their decision
+func (n *Notifier) Escalated(leave models.Leave) {
+	n.askApprovers("escalation", leave, "Escalated: ", "This request was escalated to you because it waited too long for a decision.")
+}
+
+// askApprovers sends the awaiting_decision email to the approvers of the
+// leave's current step
+func (n *Notifier) askApprovers(what string, leave models.Leave, subjectPrefix, note string) {
+	if n == nil {
+		return
+	}
+	n.background(what, leave, func() error {
+		approvers, err := n.Store.GetStepApprovers(&leave)
+		if err != nil {
+			return err
+		}
+		subject := subjectPrefix + "Leave request from " + leave.UserEmail
+		if leave.Status == string(constants.LeaveStatusCancellationRequested) {
+			subject = subjectPrefix + "Leave cancellation request from " + leave.UserEmail
+		}
+		for _, approver := range approvers {
+			if err := n.send("awaiting_decision", subject, templateData{Recipient: approver, Leave: leave, Note: note}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Decided tells the requester the outcome of their leave, with the
+// approver's comment
+func (n *Notifier) Decided(leave models.Leave) {
+	if n == nil {
+		return
+	}
+	n.background("decision", leave, func() error {
+		requester, err := n.Store.GetUserByEmail(leave.UserEmail)
+		if err != nil {
+			return err
+		}
+		return n.send("decided", "Your leave request was "+leave.Status, templateData{Recipient: *requester, Leave: leave, Outcome: leave.Status})
+	})
+}
+
+// CancellationDeclined tells the requester their request to cancel approved
+// leave was refused, so the leave still stands
+func (n *Notifier) CancellationDeclined(leave models.Leave) {
+	if n == nil {
+		return
+	}
+	n.background("cancellation declined", leave, func() error {
+		requester, err := n.Store.GetUserByEmail(leave.UserEmail)
+		if err != nil {
+			return err
+		}
+		return n.send("cancellation_declined", "Your leave cancellation request was declined", templateData{Recipient: *requester, Leave: leave, Outcome: OutcomeCancellationDeclined})
+	})
+}
+
+// send renders one email and mails it unless the recipient opted out
+func (n *Notifier) send(name, subject string, data templateData) error {
+	prefs, err := n.Store.GetNotificationPreferences(data.Recipient.ID)
+	if err != nil {
+		return err
+	}
+	if !prefs.Email {
+		return nil
+	}
+
+	data.TypeName = data.Leave.Type
+	types, err := n.Store.GetLeaveTypes()
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		if t.Code == data.Leave.Type {
+			data.TypeName = t.Name
+		}
+	}
+	data.Link = n.AppURL
+
+	var body bytes.Buffer
+	if err := templates[name].ExecuteTemplate(&body, "layout", data); err != nil {
+		return err
+	}
+	return n.Mailer.Send(Message{To: data.Recipient.Email, Subject: subject, HTML: body.String()})
+}
@@ -0,0 +1,151 @@
+// internal/scheduler/scheduler.go
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"leave-app/internal/constants"
+	"leave-app/internal/db"
+	"leave-app/internal/models"
+	"leave-app/internal/notify"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// AutoApproveComment is left on sick leave the scheduler approves
+const AutoApproveComment = "Approved automatically after waiting for a decision"
+
+// Store is the part of db.Store the scheduler uses
+type Store interface {
+	AcquireLease(name, holder string, now time.Time, ttl time.Duration) (bool, error)
+	GetAwaitingLeaves() ([]models.Leave, error)
+	GetLeaveByID(leaveID string) (*models.Leave, error)
+	MarkLeaveReminded(leaveID string, at time.Time) (bool, error)
+	EscalateLeave(leaveID string, at time.Time) error
+	AutoApproveLeave(leaveID string, comment string) error
+}
+
+// Config sets how long leave may wait before it is chased. A zero
+// duration turns that step off.
+type Config struct {
+	ReminderAfter        time.Duration
+	EscalateAfter        time.Duration
+	SickAutoApproveAfter time.Duration // Measured from the request
+}
+
+// ParseHours parses a whole number of hours from the environment, using
+// fallback when value is empty
+func ParseHours(value string, fallback int) (time.Duration, error) {
+	if value == "" {
+		return time.Duration(fallback) * time.Hour, nil
+	}
+	hours, err := strconv.Atoi(value)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("%q is not a number of hours", value)
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// DefaultHolder names this process in the lease
+func DefaultHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// Scheduler reminds approvers of leave waiting on them, escalates leave
+// that keeps waiting and optionally approves stale sick leave. Every
+// replica may run one; only the holder of the lease does any work.
+type Scheduler struct {
+	Store  Store
+	Notify *notify.Notifier
+	Config Config
+	Holder string
+}
+
+// RunOnce chases the leave waiting at now, reporting false when another
+// replica holds the lease. Leave that cannot be chased is logged and
+// skipped.
+func (s *Scheduler) RunOnce(now time.Time) (bool, error) {
+	held, err := s.Store.AcquireLease(constants.SchedulerLeaseName, s.Holder, now, constants.SchedulerLeaseMinutes*time.Minute)
+	if err != nil || !held {
+		return false, err
+	}
+	leaves, err := s.Store.GetAwaitingLeaves()
+	if err != nil {
+		return true, err
+	}
+	for _, leave := range leaves {
+		if err := s.chase(leave, now); err != nil && !errors.Is(err, db.ErrLeaveNotPending) {
+			log.Printf("Could not chase leave %s: %v", leave.ID, err)
+		}
+	}
+	return true, nil
+}
+
+// Run chases waiting leave until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(constants.SchedulerIntervalMinutes * time.Minute)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunOnce(time.Now()); err != nil {
+			log.Printf("Reminder run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// chase takes the first step due for a leave: auto-approval, escalation or
+// a reminder
+func (s *Scheduler) chase(leave models.Leave, now time.Time) error {
+	since := leave.CreatedAt
+	if leave.AwaitingSince != nil {
+		since = *leave.AwaitingSince
+	}
+	waited := now.Sub(since)
+
+	switch {
+	case s.autoApproves(&leave, now):
+		if err := s.Store.AutoApproveLeave(leave.ID, AutoApproveComment); err != nil {
+			return err
+		}
+		return s.notify(leave.ID, s.Notify.Decided)
+	case s.Config.EscalateAfter > 0 && leave.EscalatedAt == nil && waited >= s.Config.EscalateAfter:
+		if err := s.Store.EscalateLeave(leave.ID, now); err != nil {
+			return err
+		}
+		return s.notify(leave.ID, s.Notify.Escalated)
+	case s.Config.ReminderAfter > 0 && leave.RemindedAt == nil && waited >= s.Config.ReminderAfter:
+		marked, err := s.Store.MarkLeaveReminded(leave.ID, now)
+		if err != nil {
+			return err
+		}
+		if marked {
+			s.Notify.Reminder(leave)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) autoApproves(leave *models.Leave, now time.Time) bool {
+	return s.Config.SickAutoApproveAfter > 0 &&
+		leave.Type == string(constants.LeaveTypeSick) &&
+		leave.Status == string(constants.LeaveStatusPending) &&
+		now.Sub(leave.CreatedAt) >= s.Config.SickAutoApproveAfter
+}
+
+// notify sends the updated leave to one of the notifier's emails
+func (s *Scheduler) notify(leaveID string, send func(models.Leave)) error {
+	leave, err := s.Store.GetLeaveByID(leaveID)
+	if err != nil {
+		return err
+	}
+	send(*leave)
+	return nil
+}
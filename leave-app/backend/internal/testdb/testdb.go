@@ -0,0 +1,78 @@
+// internal/testdb/testdb.go
+
+// Package testdb opens the scratch MySQL database that tests built with
+// the integration tag run against:
+//
+//	LEAVE_APP_TEST_DSN='user:pass@tcp(localhost:3306)/leave_test?parseTime=true' \
+//	    go test -tags integration ./...
+//
+// Every table in that database is dropped, so never point it at real data.
+package testdb
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// DSNEnv names the variable holding the test database's DSN
+const DSNEnv = "LEAVE_APP_TEST_DSN"
+
+// Open connects to the test database and drops every table in it, so each
+// test starts from an empty schema. The test is skipped when DSNEnv is
+// unset.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", DSNEnv)
+	}
+
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	// One connection keeps FOREIGN_KEY_CHECKS below on the session that
+	// drops the tables
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	if err := dropTables(conn); err != nil {
+		t.Fatalf("reset test database: %v", err)
+	}
+	conn.SetMaxOpenConns(0)
+	return conn
+}
+
+func dropTables(conn *sql.DB) error {
+	rows, err := conn.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	for _, name := range tables {
+		if _, err := conn.Exec("DROP TABLE `" + name + "`"); err != nil {
+			return err
+		}
+	}
+	_, err = conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+	return err
+}
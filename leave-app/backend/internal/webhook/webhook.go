@@ -0,0 +1,117 @@
+// internal/webhook/webhook.go
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"leave-app/internal/constants"
+	"leave-app/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Outbox is the part of db.Store the dispatcher uses
+type Outbox interface {
+	ClaimWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(deliveryID string, statusCode int, errMsg string, at time.Time) error
+}
+
+// Sign returns the X-Webhook-Signature value for a body sent at timestamp:
+// "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// subscription secret. Including the timestamp lets receivers reject
+// replayed requests.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher sends queued deliveries. Several dispatchers may drain the
+// same outbox; claims keep them from sending a delivery twice at once.
+type Dispatcher struct {
+	Outbox Outbox
+	Client *http.Client
+}
+
+// RunOnce sends the deliveries due at now and records the outcomes,
+// returning how many were attempted
+func (d *Dispatcher) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := d.Outbox.ClaimWebhookDeliveries(now, constants.WebhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range deliveries {
+		statusCode, errMsg := d.send(ctx, &deliveries[i])
+		if err := d.Outbox.RecordWebhookAttempt(deliveries[i].ID, statusCode, errMsg, time.Now()); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Run polls the outbox until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(constants.WebhookIntervalSeconds * time.Second)
+	defer ticker.Stop()
+	for {
+		if _, err := d.RunOnce(ctx, time.Now()); err != nil {
+			log.Printf("Webhook dispatch failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// send posts one delivery, returning the response status (0 if there was
+// none) and an error message for anything but a 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, string) {
+	ctx, cancel := context.WithTimeout(ctx, constants.WebhookTimeoutSeconds*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "leave-app-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, resp.Status
+	}
+	return resp.StatusCode, ""
+}
@@ -0,0 +1,151 @@
+// internal/workdays/calendar.go
+package workdays
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+// DefaultWeekend is used when no weekend days are configured
+var DefaultWeekend = []time.Weekday{time.Saturday, time.Sunday}
+
+// HolidaySource supplies public holidays keyed by date (YYYY-MM-DD) with their names
+type HolidaySource interface {
+	Holidays() (map[string]string, error)
+}
+
+// Calendar decides which days are working days
+type Calendar struct {
+	weekend  map[time.Weekday]bool
+	mu       sync.RWMutex
+	holidays map[string]string
+	source   HolidaySource
+}
+
+// New creates a calendar with the given weekend days and no holidays
+func New(weekend []time.Weekday) *Calendar {
+	c := &Calendar{
+		weekend:  make(map[time.Weekday]bool),
+		holidays: make(map[string]string),
+	}
+	for _, d := range weekend {
+		c.weekend[d] = true
+	}
+	return c
+}
+
+// FromEnv builds the calendar the environment configures: weekend days
+// from WEEKEND_DAYS and holidays from the iCalendar file named by
+// HOLIDAYS_ICS_FILE, or from fallback when that is unset
+func FromEnv(fallback HolidaySource) (*Calendar, error) {
+	weekend, err := ParseWeekend(os.Getenv("WEEKEND_DAYS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEEKEND_DAYS: %w", err)
+	}
+	source := fallback
+	if path := os.Getenv("HOLIDAYS_ICS_FILE"); path != "" {
+		source = ICSFile{Path: path}
+	}
+	c := New(weekend)
+	if err := c.Load(source); err != nil {
+		return nil, fmt.Errorf("could not load holidays: %w", err)
+	}
+	return c, nil
+}
+
+// Load replaces the calendar's holidays with those from source, which
+// later reloads read again
+func (c *Calendar) Load(source HolidaySource) error {
+	holidays, err := source.Holidays()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.holidays = holidays
+	c.source = source
+	c.mu.Unlock()
+	return nil
+}
+
+// Reload reads the holidays again from the source last loaded, if any.
+// On error the calendar keeps the holidays it had.
+func (c *Calendar) Reload() error {
+	source := c.Source()
+	if source == nil {
+		return nil
+	}
+	return c.Load(source)
+}
+
+// Source returns the source the holidays were last loaded from, or nil
+func (c *Calendar) Source() HolidaySource {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.source
+}
+
+// Holidays returns a copy of the holidays last loaded, keyed by date
+func (c *Calendar) Holidays() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.holidays)
+}
+
+// Holiday returns the name of the holiday on day, if any
+func (c *Calendar) Holiday(day time.Time) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name, ok := c.holidays[day.Format(DateLayout)]
+	return name, ok
+}
+
+// IsWorkingDay reports whether day is neither a weekend day nor a holiday
+func (c *Calendar) IsWorkingDay(day time.Time) bool {
+	if c.weekend[day.Weekday()] {
+		return false
+	}
+	_, holiday := c.Holiday(day)
+	return !holiday
+}
+
+// WorkingDays counts the working days from start to end, inclusive
+func (c *Calendar) WorkingDays(start, end time.Time) int {
+	count := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(day) {
+			count++
+		}
+	}
+	return count
+}
+
+// ParseWeekend parses a comma separated list of weekday names such as
+// "saturday,sunday". An empty string yields DefaultWeekend.
+func ParseWeekend(s string) ([]time.Weekday, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultWeekend, nil
+	}
+
+	var days []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.ToLower(d.String()) == name || strings.ToLower(d.String()[:3]) == name {
+				days = append(days, d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown weekday %q", part)
+		}
+	}
+	return days, nil
+}
@@ -0,0 +1,116 @@
+// internal/workdays/calendar_test.go
+package workdays
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(DateLayout, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// fixedHolidays is a HolidaySource that returns its own contents
+type fixedHolidays map[string]string
+
+func (f fixedHolidays) Holidays() (map[string]string, error) { return maps.Clone(f), nil }
+
+func TestParseWeekend(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []time.Weekday
+		wantErr bool
+	}{
+		{in: "", want: DefaultWeekend},
+		{in: "  ", want: DefaultWeekend},
+		{in: "saturday,sunday", want: []time.Weekday{time.Saturday, time.Sunday}},
+		{in: "Fri, Sat", want: []time.Weekday{time.Friday, time.Saturday}},
+		{in: "FRIDAY", want: []time.Weekday{time.Friday}},
+		{in: "friday,someday", wantErr: true},
+		{in: "sa", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseWeekend(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("weekend = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkingDays(t *testing.T) {
+	holidays := fixedHolidays{"2030-03-05": "Founders' Day", "2030-03-09": "Saturday holiday"}
+	tests := []struct {
+		name       string
+		weekend    []time.Weekday
+		start, end string
+		want       int
+	}{
+		{name: "one weekday", weekend: DefaultWeekend, start: "2030-03-04", end: "2030-03-04", want: 1},
+		{name: "weekend day", weekend: DefaultWeekend, start: "2030-03-02", end: "2030-03-02", want: 0},
+		{name: "holiday", weekend: DefaultWeekend, start: "2030-03-05", end: "2030-03-05", want: 0},
+		{name: "week with a holiday", weekend: DefaultWeekend, start: "2030-03-04", end: "2030-03-10", want: 4},
+		{name: "across a weekend", weekend: DefaultWeekend, start: "2030-03-07", end: "2030-03-12", want: 4},
+		{name: "friday weekend", weekend: []time.Weekday{time.Friday, time.Saturday}, start: "2030-03-04", end: "2030-03-10", want: 4},
+		{name: "end before start", weekend: DefaultWeekend, start: "2030-03-06", end: "2030-03-04", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.weekend)
+			if err := c.Load(holidays); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.WorkingDays(date(t, tt.start), date(t, tt.end)); got != tt.want {
+				t.Errorf("working days = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	c := New(DefaultWeekend)
+	if err := c.Reload(); err != nil {
+		t.Fatalf("reload without a source: %v", err)
+	}
+
+	path := writeICS(t, "BEGIN:VEVENT\nSUMMARY:Old\nDTSTART;VALUE=DATE:20300304\nEND:VEVENT\n")
+	if err := c.Load(ICSFile{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := c.Holiday(date(t, "2030-03-04")); !ok || name != "Old" {
+		t.Fatalf("holiday = %q, %v, want Old", name, ok)
+	}
+
+	if err := os.WriteFile(path, []byte("BEGIN:VEVENT\nSUMMARY:New\nDTSTART;VALUE=DATE:20300305\nEND:VEVENT\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"2030-03-05": "New"}; !maps.Equal(c.Holidays(), want) {
+		t.Errorf("after reload = %v, want %v", c.Holidays(), want)
+	}
+
+	// A failed reload keeps the holidays already loaded
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err == nil {
+		t.Error("reload of a removed file: want an error")
+	}
+	if c.IsWorkingDay(date(t, "2030-03-05")) {
+		t.Error("2030-03-05 is a working day after a failed reload, want the holiday kept")
+	}
+}
@@ -0,0 +1,88 @@
+// internal/workdays/ics.go
+package workdays
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+)
+
+// ICSFile reads holidays from the all-day events of an iCalendar file
+type ICSFile struct {
+	Path string
+}
+
+// Holidays implements HolidaySource
+func (f ICSFile) Holidays() (map[string]string, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Unfold continuation lines (RFC 5545 section 3.1) before parsing
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	holidays := make(map[string]string)
+	var inEvent bool
+	var summary string
+	var start, end time.Time
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Drop parameters such as DTSTART;VALUE=DATE
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch name {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, summary, start, end = true, "", time.Time{}, time.Time{}
+			}
+		case "SUMMARY":
+			summary = value
+		case "DTSTART":
+			start, _ = parseICSDate(value)
+		case "DTEND":
+			end, _ = parseICSDate(value)
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			// DTEND is exclusive for all-day events; a missing one means a single day
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays[day.Format(DateLayout)] = summary
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// parseICSDate reads the date part of a DATE or DATE-TIME value
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) > 8 {
+		value = value[:8]
+	}
+	return time.Parse("20060102", value)
+}
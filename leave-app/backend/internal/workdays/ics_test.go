@@ -0,0 +1,78 @@
+// internal/workdays/ics_test.go
+package workdays
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeICS writes the given lines as an iCalendar file and returns its path
+func writeICS(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "holidays.ics")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestICSFileHolidays(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name: "single all-day event",
+			content: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:New Year\r\n" +
+				"DTSTART;VALUE=DATE:20300101\r\nDTEND;VALUE=DATE:20300102\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: map[string]string{"2030-01-01": "New Year"},
+		},
+		{
+			name:    "missing DTEND means one day",
+			content: "BEGIN:VEVENT\nSUMMARY:Founders' Day\nDTSTART;VALUE=DATE:20300305\nEND:VEVENT\n",
+			want:    map[string]string{"2030-03-05": "Founders' Day"},
+		},
+		{
+			name: "multi-day DTEND is exclusive",
+			content: "BEGIN:VEVENT\nSUMMARY:Festival\nDTSTART;VALUE=DATE:20301230\n" +
+				"DTEND;VALUE=DATE:20310102\nEND:VEVENT\n",
+			want: map[string]string{"2030-12-30": "Festival", "2030-12-31": "Festival", "2031-01-01": "Festival"},
+		},
+		{
+			name:    "folded lines are unfolded",
+			content: "BEGIN:VEVENT\r\nSUMMARY:Day of the\r\n  Long Name\r\nDTSTART;VALUE=DATE:2030\r\n\t0601\r\nEND:VEVENT\r\n",
+			want:    map[string]string{"2030-06-01": "Day of the Long Name"},
+		},
+		{
+			name:    "date-time values keep their date",
+			content: "BEGIN:VEVENT\nSUMMARY:Half day\nDTSTART:20300704T090000Z\nDTEND:20300704T130000Z\nEND:VEVENT\n",
+			want:    map[string]string{"2030-07-04": "Half day"},
+		},
+		{
+			name: "events without a start are skipped",
+			content: "BEGIN:VEVENT\nSUMMARY:Nothing\nEND:VEVENT\nBEGIN:VEVENT\nSUMMARY:Labour Day\n" +
+				"DTSTART;VALUE=DATE:20300501\nEND:VEVENT\n",
+			want: map[string]string{"2030-05-01": "Labour Day"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ICSFile{Path: writeICS(t, tt.content)}.Holidays()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("holidays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := (ICSFile{Path: filepath.Join(t.TempDir(), "none.ics")}).Holidays(); err == nil {
+			t.Error("want an error for a missing file")
+		}
+	})
+}
@@ -0,0 +1,4 @@
+-- migrations/001_initial.down.sql
+
+DROP TABLE IF EXISTS leaves;
+DROP TABLE IF EXISTS users;
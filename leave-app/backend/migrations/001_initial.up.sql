@@ -1,4 +1,4 @@
--- migrations/001_initial.sql
+-- migrations/001_initial.up.sql
 
 CREATE TABLE IF NOT EXISTS users (
     id VARCHAR(255) PRIMARY KEY,
@@ -0,0 +1,3 @@
+-- migrations/002_leave_balance_ledger.down.sql
+
+DROP TABLE IF EXISTS leave_balance_ledger;
@@ -0,0 +1,17 @@
+-- migrations/002_leave_balance_ledger.up.sql
+
+-- Append-only ledger of balance movements. Approving a leave writes a
+-- positive entry (days used); rejecting or deleting an approved leave
+-- writes the matching negative entry. Used days for a type and year are
+-- the sum of its entries.
+CREATE TABLE IF NOT EXISTS leave_balance_ledger (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    leave_id VARCHAR(255),
+    leave_type VARCHAR(32) NOT NULL,
+    year INT NOT NULL,
+    days DECIMAL(6,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_ledger_user_year (user_id, year),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- migrations/003_holidays.down.sql
+
+DROP TABLE IF EXISTS holidays;
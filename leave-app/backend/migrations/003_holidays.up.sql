@@ -0,0 +1,7 @@
+-- migrations/003_holidays.up.sql
+
+-- Public holidays excluded from working-day counts when no ICS file is configured
+CREATE TABLE IF NOT EXISTS holidays (
+    date DATE PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
@@ -0,0 +1,6 @@
+-- migrations/004_leave_portion.down.sql
+
+ALTER TABLE leaves
+    DROP COLUMN end_time,
+    DROP COLUMN start_time,
+    DROP COLUMN portion;
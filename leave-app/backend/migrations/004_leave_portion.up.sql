@@ -0,0 +1,7 @@
+-- migrations/004_leave_portion.up.sql
+
+-- Half-day and hourly leave
+ALTER TABLE leaves
+    ADD COLUMN portion VARCHAR(16) NOT NULL DEFAULT 'full' AFTER end_date,
+    ADD COLUMN start_time TIME NULL AFTER portion,
+    ADD COLUMN end_time TIME NULL AFTER start_time;
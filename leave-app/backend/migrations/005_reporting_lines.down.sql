@@ -0,0 +1,12 @@
+-- migrations/005_reporting_lines.down.sql
+
+ALTER TABLE leaves
+    DROP FOREIGN KEY fk_leaves_approver,
+    DROP COLUMN approver_id;
+
+UPDATE users SET role = 'user' WHERE role = 'manager';
+
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_manager,
+    DROP COLUMN manager_id,
+    MODIFY role ENUM('user', 'admin') NOT NULL DEFAULT 'user';
@@ -0,0 +1,12 @@
+-- migrations/005_reporting_lines.up.sql
+
+-- Each user may report to a manager, who approves the leave routed to them
+ALTER TABLE users
+    MODIFY role ENUM('user', 'manager', 'admin') NOT NULL DEFAULT 'user',
+    ADD COLUMN manager_id VARCHAR(255) NULL AFTER role,
+    ADD CONSTRAINT fk_users_manager FOREIGN KEY (manager_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- The approver a leave was routed to when it was filed; NULL means admins only
+ALTER TABLE leaves
+    ADD COLUMN approver_id VARCHAR(255) NULL AFTER status,
+    ADD CONSTRAINT fk_leaves_approver FOREIGN KEY (approver_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,11 @@
+-- migrations/006_approval_chains.down.sql
+
+ALTER TABLE leaves DROP COLUMN current_step;
+
+DROP TABLE IF EXISTS leave_approvals;
+DROP TABLE IF EXISTS approval_policies;
+
+UPDATE users SET role = 'user' WHERE role = 'hr';
+
+ALTER TABLE users
+    MODIFY role ENUM('user', 'manager', 'admin') NOT NULL DEFAULT 'user';
@@ -0,0 +1,41 @@
+-- migrations/006_approval_chains.up.sql
+
+ALTER TABLE users
+    MODIFY role ENUM('user', 'manager', 'hr', 'admin') NOT NULL DEFAULT 'user';
+
+-- Ordered approval steps per leave type. A policy applies to leaves longer
+-- than over_days; the one with the highest matching threshold wins.
+CREATE TABLE IF NOT EXISTS approval_policies (
+    id VARCHAR(255) PRIMARY KEY,
+    leave_type VARCHAR(32) NOT NULL,
+    over_days DECIMAL(6,2) NOT NULL DEFAULT 0,
+    steps VARCHAR(255) NOT NULL, -- comma separated roles, e.g. 'manager,hr'
+    UNIQUE KEY uq_approval_policy (leave_type, over_days)
+);
+
+-- Steps snapshotted onto each leave when it is filed, with their decisions
+CREATE TABLE IF NOT EXISTS leave_approvals (
+    leave_id VARCHAR(255) NOT NULL,
+    step INT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    decision ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+    approver_id VARCHAR(255) NULL,
+    comment TEXT,
+    decided_at TIMESTAMP NULL,
+    PRIMARY KEY (leave_id, step),
+    FOREIGN KEY (leave_id) REFERENCES leaves(id) ON DELETE CASCADE,
+    FOREIGN KEY (approver_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Role that must decide the leave next; NULL once the leave is decided
+ALTER TABLE leaves
+    ADD COLUMN current_step VARCHAR(16) NULL AFTER approver_id;
+
+INSERT IGNORE INTO approval_policies (id, leave_type, over_days, steps)
+VALUES ('casual-over-3', 'casual', 3, 'manager,hr');
+
+-- Leaves already waiting keep the single manager sign-off they were filed under
+INSERT INTO leave_approvals (leave_id, step, role)
+SELECT id, 0, 'manager' FROM leaves WHERE status = 'pending';
+
+UPDATE leaves SET current_step = 'manager' WHERE status = 'pending';
@@ -0,0 +1,9 @@
+-- migrations/007_leave_status_transitions.down.sql
+
+-- Cancellations still waiting keep the approval they had; finished
+-- withdrawals and cancellations fold into rejected
+UPDATE leaves SET status = 'approved' WHERE status = 'cancellation_requested';
+UPDATE leaves SET status = 'rejected' WHERE status IN ('withdrawn', 'cancelled');
+
+ALTER TABLE leaves
+    MODIFY status ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending';
@@ -0,0 +1,4 @@
+-- migrations/007_leave_status_transitions.up.sql
+
+ALTER TABLE leaves
+    MODIFY status ENUM('pending', 'approved', 'rejected', 'withdrawn', 'cancellation_requested', 'cancelled') NOT NULL DEFAULT 'pending';
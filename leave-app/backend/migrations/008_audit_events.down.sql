@@ -0,0 +1,3 @@
+-- migrations/008_audit_events.down.sql
+
+DROP TABLE IF EXISTS audit_events;
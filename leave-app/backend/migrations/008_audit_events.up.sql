@@ -0,0 +1,17 @@
+-- migrations/008_audit_events.up.sql
+
+-- Append-only record of every change made through internal/db. Rows are
+-- never updated or deleted; before and after hold the entity as JSON.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id VARCHAR(255) NULL, -- no foreign key so events outlive their actor
+    action VARCHAR(64) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL DEFAULT '',
+    before_json JSON NULL,
+    after_json JSON NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_audit_entity (entity_type, entity_id),
+    INDEX idx_audit_actor (actor_id),
+    INDEX idx_audit_created (created_at)
+);
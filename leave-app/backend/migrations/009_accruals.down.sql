@@ -0,0 +1,4 @@
+-- migrations/009_accruals.down.sql
+
+DROP TABLE IF EXISTS leave_accruals;
+DROP TABLE IF EXISTS accrual_policies;
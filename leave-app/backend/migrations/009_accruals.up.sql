@@ -0,0 +1,25 @@
+-- migrations/009_accruals.up.sql
+
+-- Leave types listed here accrue their yearly allowance monthly or at the
+-- start of the year; unlisted types keep a fixed allowance every year.
+CREATE TABLE IF NOT EXISTS accrual_policies (
+    leave_type VARCHAR(32) PRIMARY KEY,
+    frequency ENUM('monthly', 'yearly') NOT NULL,
+    carry_forward_cap DECIMAL(6,2) NOT NULL DEFAULT 0
+);
+
+-- Days credited to a user per leave year. Carry-forward credits the new
+-- year and the matching expiry empties the old one. The unique key makes
+-- re-running the accrual job a no-op.
+CREATE TABLE IF NOT EXISTS leave_accruals (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    leave_type VARCHAR(32) NOT NULL,
+    year INT NOT NULL,
+    kind ENUM('accrual', 'carry_forward', 'expiry') NOT NULL,
+    period TINYINT NOT NULL DEFAULT 0,
+    days DECIMAL(6,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_leave_accrual (user_id, leave_type, year, kind, period),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- migrations/010_allowance_policies.down.sql
+
+DROP TABLE IF EXISTS allowance_policy_assignments;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS allowance_policies;
@@ -0,0 +1,32 @@
+-- migrations/010_allowance_policies.up.sql
+
+-- Named allowance sets, e.g. for contractors or senior staff
+CREATE TABLE IF NOT EXISTS allowance_policies (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    priority INT NOT NULL DEFAULT 0,
+    sick_allowance INT NOT NULL DEFAULT 0,
+    annual_allowance INT NOT NULL DEFAULT 0,
+    casual_allowance INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    user_id VARCHAR(255) NOT NULL,
+    group_name VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, group_name),
+    INDEX idx_user_groups_group (group_name),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Each user or group has at most one policy. A user's own policy wins over
+-- their groups'; among groups the policy with the highest priority wins.
+-- Users with neither keep the allowances on their users row.
+CREATE TABLE IF NOT EXISTS allowance_policy_assignments (
+    subject_type ENUM('user', 'group') NOT NULL,
+    subject_id VARCHAR(255) NOT NULL, -- user id or group name
+    policy_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (subject_type, subject_id),
+    INDEX idx_policy_assignments_policy (policy_id),
+    FOREIGN KEY (policy_id) REFERENCES allowance_policies(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+-- migrations/011_leave_types.down.sql
+
+-- Leaves of types added since cannot be represented by the ENUM
+DELETE FROM leaves WHERE type NOT IN ('sick', 'annual', 'casual');
+
+ALTER TABLE leaves
+    DROP FOREIGN KEY fk_leaves_type,
+    DROP INDEX fk_leaves_type,
+    MODIFY type ENUM('sick', 'annual', 'casual') NOT NULL;
+
+DROP TABLE IF EXISTS leave_types;
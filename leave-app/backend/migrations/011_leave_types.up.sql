@@ -0,0 +1,24 @@
+-- migrations/011_leave_types.up.sql
+
+-- Leave types used to be a fixed ENUM on leaves.type. Sick, annual and
+-- casual keep their allowance columns; other types that count against a
+-- balance give every user default_allowance days a year.
+CREATE TABLE IF NOT EXISTS leave_types (
+    code VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    paid BOOLEAN NOT NULL DEFAULT TRUE,
+    requires_attachment BOOLEAN NOT NULL DEFAULT FALSE,
+    max_consecutive_days INT NULL,
+    counts_against_balance BOOLEAN NOT NULL DEFAULT TRUE,
+    default_allowance INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT IGNORE INTO leave_types (code, name) VALUES
+    ('sick', 'Sick leave'),
+    ('annual', 'Annual leave'),
+    ('casual', 'Casual leave');
+
+ALTER TABLE leaves
+    MODIFY type VARCHAR(32) NOT NULL,
+    ADD CONSTRAINT fk_leaves_type FOREIGN KEY (type) REFERENCES leave_types(code);
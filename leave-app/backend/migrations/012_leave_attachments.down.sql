@@ -0,0 +1,5 @@
+-- migrations/012_leave_attachments.down.sql
+
+ALTER TABLE leave_types DROP COLUMN attachment_over_days;
+
+DROP TABLE IF EXISTS leave_attachments;
@@ -0,0 +1,20 @@
+-- migrations/012_leave_attachments.up.sql
+
+-- File contents live in blob storage under storage_key
+CREATE TABLE IF NOT EXISTS leave_attachments (
+    id VARCHAR(255) PRIMARY KEY,
+    leave_id VARCHAR(255) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    uploaded_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_leave_attachments_leave (leave_id),
+    FOREIGN KEY (leave_id) REFERENCES leaves(id) ON DELETE CASCADE
+);
+
+-- With requires_attachment set, only leaves longer than this many days
+-- need one; NULL means every leave of the type does
+ALTER TABLE leave_types
+    ADD COLUMN attachment_over_days DECIMAL(5,2) NULL AFTER requires_attachment;
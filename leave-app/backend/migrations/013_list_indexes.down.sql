@@ -0,0 +1,12 @@
+-- migrations/013_list_indexes.down.sql
+
+-- MySQL may have dropped the implicit index behind the user_id foreign
+-- key in favour of the new ones, so give it a plain one back first
+CREATE INDEX idx_leaves_user ON leaves (user_id);
+
+DROP INDEX idx_users_created ON users;
+DROP INDEX idx_leaves_user_start ON leaves;
+DROP INDEX idx_leaves_start ON leaves;
+DROP INDEX idx_leaves_status_created ON leaves;
+DROP INDEX idx_leaves_user_created ON leaves;
+DROP INDEX idx_leaves_created ON leaves;
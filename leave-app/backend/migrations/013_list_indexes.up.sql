@@ -0,0 +1,12 @@
+-- migrations/013_list_indexes.up.sql
+
+-- Keyset pagination on GET /api/leaves orders by (created_at, id) or
+-- (start_date, id); the date-range filter compares start_date and end_date
+CREATE INDEX idx_leaves_created ON leaves (created_at, id);
+CREATE INDEX idx_leaves_user_created ON leaves (user_id, created_at, id);
+CREATE INDEX idx_leaves_status_created ON leaves (status, created_at, id);
+CREATE INDEX idx_leaves_start ON leaves (start_date, end_date, id);
+CREATE INDEX idx_leaves_user_start ON leaves (user_id, start_date, end_date);
+
+-- GET /api/users orders by email (already unique) or (created_at, id)
+CREATE INDEX idx_users_created ON users (created_at, id);
@@ -0,0 +1,5 @@
+-- migrations/014_calendar_feeds.down.sql
+
+DROP TABLE IF EXISTS calendar_tokens;
+
+ALTER TABLE leaves DROP COLUMN sequence;
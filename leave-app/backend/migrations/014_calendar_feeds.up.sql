@@ -0,0 +1,13 @@
+-- migrations/014_calendar_feeds.up.sql
+
+-- Bumped on every status change; the iCalendar feed uses it as SEQUENCE
+ALTER TABLE leaves ADD COLUMN sequence INT NOT NULL DEFAULT 0;
+
+-- Calendar clients can't send a bearer token, so feeds authenticate with
+-- a per-user token in the URL. Only its SHA-256 is stored.
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    user_id VARCHAR(255) PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
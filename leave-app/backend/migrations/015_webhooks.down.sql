@@ -0,0 +1,5 @@
+-- migrations/015_webhooks.down.sql
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscription_events;
+DROP TABLE IF EXISTS webhook_subscriptions;
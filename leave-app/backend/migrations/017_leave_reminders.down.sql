@@ -0,0 +1,8 @@
+-- migrations/017_leave_reminders.down.sql
+
+DROP TABLE IF EXISTS job_leases;
+
+ALTER TABLE leaves
+    DROP COLUMN escalated_at,
+    DROP COLUMN reminded_at,
+    DROP COLUMN awaiting_since;
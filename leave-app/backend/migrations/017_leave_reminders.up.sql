@@ -0,0 +1,18 @@
+-- migrations/017_leave_reminders.up.sql
+
+-- When the leave started waiting on its current step, and whether the
+-- scheduler has reminded its approvers or escalated it since then
+ALTER TABLE leaves
+    ADD COLUMN awaiting_since TIMESTAMP(3) NULL AFTER current_step,
+    ADD COLUMN reminded_at TIMESTAMP(3) NULL AFTER awaiting_since,
+    ADD COLUMN escalated_at TIMESTAMP(3) NULL AFTER reminded_at;
+
+UPDATE leaves SET awaiting_since = created_at WHERE current_step IS NOT NULL;
+
+-- Background jobs take a lease before running so only one replica runs
+-- them at a time; an expired lease can be taken over
+CREATE TABLE IF NOT EXISTS job_leases (
+    name VARCHAR(64) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP(3) NOT NULL
+);
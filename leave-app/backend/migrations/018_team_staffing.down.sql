@@ -0,0 +1,3 @@
+-- migrations/018_team_staffing.down.sql
+
+DROP TABLE IF EXISTS team_staffing;
@@ -0,0 +1,9 @@
+-- migrations/018_team_staffing.up.sql
+
+-- Minimum number of a manager's direct reports who must be working on any
+-- working day; approvals that go below it come back with warnings
+CREATE TABLE IF NOT EXISTS team_staffing (
+    manager_id VARCHAR(255) PRIMARY KEY,
+    min_staffing INT NOT NULL,
+    FOREIGN KEY (manager_id) REFERENCES users(id) ON DELETE CASCADE
+);
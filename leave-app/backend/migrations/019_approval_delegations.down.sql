@@ -0,0 +1,7 @@
+-- migrations/019_approval_delegations.down.sql
+
+ALTER TABLE leave_approvals
+    DROP FOREIGN KEY fk_leave_approvals_on_behalf_of,
+    DROP COLUMN on_behalf_of;
+
+DROP TABLE IF EXISTS approval_delegations;
@@ -0,0 +1,19 @@
+-- migrations/019_approval_delegations.up.sql
+
+-- Approvers lend their authority to a delegate between two dates, inclusive
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id VARCHAR(255) PRIMARY KEY,
+    delegator_id VARCHAR(255) NOT NULL,
+    delegate_id VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_delegations_delegate (delegate_id, start_date, end_date),
+    FOREIGN KEY (delegator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (delegate_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Whose authority a step was decided with, when a delegate decided it
+ALTER TABLE leave_approvals
+    ADD COLUMN on_behalf_of VARCHAR(255) NULL AFTER approver_id,
+    ADD CONSTRAINT fk_leave_approvals_on_behalf_of FOREIGN KEY (on_behalf_of) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+-- migrations/020_comp_off.down.sql
+
+DROP TABLE IF EXISTS comp_off_usage;
+DROP TABLE IF EXISTS comp_off_credits;
+
+DELETE FROM leave_types WHERE code = 'compoff' AND NOT EXISTS (SELECT 1 FROM leaves WHERE type = 'compoff');
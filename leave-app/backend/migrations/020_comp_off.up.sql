@@ -0,0 +1,40 @@
+-- migrations/020_comp_off.up.sql
+
+-- Days off earned by working on a weekend or holiday. The employee claims
+-- the date worked, the manager the claim was routed to decides it, and an
+-- approved credit can be spent on compoff leave until it expires.
+CREATE TABLE IF NOT EXISTS comp_off_credits (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    work_date DATE NOT NULL,
+    days DECIMAL(6,2) NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    approver_id VARCHAR(255) NULL,
+    decided_by VARCHAR(255) NULL,
+    decided_at TIMESTAMP NULL,
+    comment TEXT NULL,
+    expires_on DATE NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_comp_off_credits_user (user_id, status, work_date),
+    INDEX idx_comp_off_credits_approver (approver_id, status),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (approver_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- The credits an approved compoff leave was drawn from, oldest first.
+-- Rows go when the leave stops being charged, returning the days.
+CREATE TABLE IF NOT EXISTS comp_off_usage (
+    credit_id VARCHAR(255) NOT NULL,
+    leave_id VARCHAR(255) NOT NULL,
+    days DECIMAL(6,2) NOT NULL,
+    PRIMARY KEY (credit_id, leave_id),
+    INDEX idx_comp_off_usage_leave (leave_id),
+    FOREIGN KEY (credit_id) REFERENCES comp_off_credits(id) ON DELETE CASCADE,
+    FOREIGN KEY (leave_id) REFERENCES leaves(id) ON DELETE CASCADE
+);
+
+-- Comp-off has no yearly allowance; its balance is the unspent credits
+INSERT IGNORE INTO leave_types (code, name, paid, counts_against_balance, default_allowance) VALUES
+    ('compoff', 'Comp-off', TRUE, TRUE, 0);
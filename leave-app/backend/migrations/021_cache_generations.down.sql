@@ -0,0 +1,3 @@
+-- migrations/021_cache_generations.down.sql
+
+DROP TABLE IF EXISTS cache_generations;
@@ -0,0 +1,11 @@
+-- migrations/021_cache_generations.up.sql
+
+-- A counter per cache, bumped by every transaction that changes what the
+-- cache holds. Instances compare it with the generation their copy was
+-- computed under, so a change made on any instance drops every copy.
+CREATE TABLE IF NOT EXISTS cache_generations (
+    name VARCHAR(64) PRIMARY KEY,
+    generation BIGINT UNSIGNED NOT NULL DEFAULT 0
+);
+
+INSERT IGNORE INTO cache_generations (name) VALUES ('analytics');
@@ -0,0 +1,10 @@
+// migrations/migrations.go
+package migrations
+
+import "embed"
+
+// FS holds the versioned SQL migrations, named NNN_name.up.sql and
+// NNN_name.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
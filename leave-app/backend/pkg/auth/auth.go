@@ -3,6 +3,8 @@ package auth
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"leave-app/internal/constants"
 	"leave-app/internal/db"
 	"log"
@@ -17,11 +19,12 @@ import (
 )
 
 type Authenticator struct {
-	DB   *db.Database
-	jwks jwk.Set
+	DB     db.Store
+	jwks   jwk.Set
+	cancel context.CancelFunc
 }
 
-func New(db *db.Database) (*Authenticator, error) {
+func New(store db.Store) (*Authenticator, error) {
 	jwksURL := os.Getenv("JWKS_URL")
 	if jwksURL == "" {
 		log.Fatal("JWKS_URL environment variable not set")
@@ -36,8 +39,9 @@ func New(db *db.Database) (*Authenticator, error) {
 	}
 
 	auth := &Authenticator{
-		DB:   db,
-		jwks: set,
+		DB:     store,
+		jwks:   set,
+		cancel: cancel,
 	}
 
 	// Refresh JWKS in the background
@@ -46,6 +50,11 @@ func New(db *db.Database) (*Authenticator, error) {
 	return auth, nil
 }
 
+// Close stops the background JWKS refresh
+func (a *Authenticator) Close() {
+	a.cancel()
+}
+
 func (a *Authenticator) refreshJwks(ctx context.Context, jwksURL string) {
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
@@ -101,7 +110,7 @@ func (a *Authenticator) AuthMiddleware() gin.HandlerFunc {
 		user, err := a.DB.GetUserByEmail(emailStr)
 		if err != nil {
 			// If user not found, create a new user
-			if err.Error() == "sql: no rows in result set" {
+			if errors.Is(err, sql.ErrNoRows) {
 				user, err = a.DB.CreateUser(emailStr)
 				if err != nil {
 					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
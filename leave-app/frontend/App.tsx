@@ -19,14 +19,14 @@ type Tab = 'leaves' | 'approvals' | 'reports' | 'admin';
 function App() {
   const { user, token, isAdmin, loading, updateUser } = useAuth();
   
-  const { leaves, rawLeaves, balances, filters, actions, loading: leavesLoading, refresh } = useLeaves({ 
+  const { leaves, pendingLeaves, balances, filters, actions, loading: leavesLoading, refresh, hasMore, loadMore, hasMorePending, loadMorePending } = useLeaves({ 
     token, 
     isAdmin, 
     user
   });
 
 
-  const { users, updateGlobalAllowances, updateUserRole } = useUsers({ 
+  const { users, search: userSearch, setSearch: setUserSearch, hasMore: hasMoreUsers, loadMore: loadMoreUsers, loading: usersLoading, updateGlobalAllowances, updateUserRole } = useUsers({ 
     token, 
     isAdmin,
     onGlobalAllowancesUpdate: (allowances) => {
@@ -85,8 +85,11 @@ function App() {
       case 'leaves':
         return (
           <MyLeaves 
-            leaves={leaves.filter(l => l.userId === user.id)} 
+            leaves={leaves} 
             balances={balances}
+            hasMore={hasMore}
+            loading={leavesLoading}
+            onLoadMore={loadMore}
             onDelete={actions.deleteLeave} 
             onRequestNew={() => setIsAdding(true)}
             filters={filters}
@@ -95,7 +98,10 @@ function App() {
       case 'approvals':
         return (
           <Approvals 
-            leaves={rawLeaves || leaves} 
+            leaves={pendingLeaves} 
+            hasMore={hasMorePending}
+            loading={leavesLoading}
+            onLoadMore={loadMorePending}
             onApprove={actions.approveLeave} 
             onReject={actions.rejectLeave} 
           />
@@ -103,7 +109,7 @@ function App() {
       case 'reports':
         return (
           <Reports 
-            allLeaves={rawLeaves || leaves} 
+            token={token} 
             isAdmin={isAdmin}
             currentUser={user}
             users={users}
@@ -113,6 +119,11 @@ function App() {
         return (
           <Admin 
             users={users}
+            search={userSearch}
+            onSearchChange={setUserSearch}
+            hasMore={hasMoreUsers}
+            loading={usersLoading}
+            onLoadMore={loadMoreUsers}
             currentUser={user}
             updateUserRole={(userId, role) => updateUserRole(userId, role)
               .then(() => { toast.success(`User role updated to ${role}`); })
@@ -222,7 +233,7 @@ function App() {
               >
                 <CheckSquare size={24} className="mb-1" />
                 <span className="text-[10px] font-medium">Verify</span>
-                {leaves.some(l => l.status === 'pending') && (
+                {pendingLeaves.length > 0 && (
                   <span className="absolute top-0 right-3 w-2.5 h-2.5 bg-red-500 rounded-full border-2 border-white"></span>
                 )}
               </button>
@@ -1,11 +1,20 @@
-import { Leave, UserInfo, Allowances } from "../types";
+import {
+  Leave,
+  UserInfo,
+  Allowances,
+  Page,
+  LeaveQuery,
+  UserQuery,
+  LeaveBalance,
+} from "../types";
 
 // Read the API base URL from environment (Vite provides import.meta.env for client code).
 // Use a sensible fallback for local dev when using the Vite dev proxy.
 const API_BASE = (import.meta as any).env?.VITE_API_BASE || "/api";
 
-class ApiError extends Error {
-  constructor(public status: number, message: string) {
+// data is the parsed error body, for endpoints that say more than a message
+export class ApiError extends Error {
+  constructor(public status: number, message: string, public data?: any) {
     super(message);
     this.name = "ApiError";
   }
@@ -34,13 +43,14 @@ async function request<T>(
 
   if (!response.ok) {
     let errorMessage = "An unexpected error occurred";
+    let errorData: any;
     try {
-      const errorData = await response.json();
+      errorData = await response.json();
       errorMessage = errorData.error || errorData.message || errorMessage;
     } catch {
       errorMessage = response.statusText;
     }
-    throw new ApiError(response.status, errorMessage);
+    throw new ApiError(response.status, errorMessage, errorData);
   }
 
   // Some endpoints might return 204 No Content. Avoid parsing empty body as JSON.
@@ -55,6 +65,18 @@ async function request<T>(
   return JSON.parse(text) as T;
 }
 
+// Build a query string from the parameters that are set
+function toQuery(params: object): string {
+  const search = new URLSearchParams();
+  Object.entries(params).forEach(([key, value]) => {
+    if (value !== undefined && value !== null && value !== "") {
+      search.set(key, String(value));
+    }
+  });
+  const query = search.toString();
+  return query ? `?${query}` : "";
+}
+
 export const api = {
   getMe: async (token: string): Promise<UserInfo> => {
     // log response from debugging
@@ -62,8 +84,18 @@ export const api = {
     return response;
   },
 
-  getUsers: async (token: string): Promise<UserInfo[]> => {
-    return request<UserInfo[]>("/users", token);
+  getBalance: async (
+    token: string
+  ): Promise<Record<string, LeaveBalance>> => {
+    return request<Record<string, LeaveBalance>>("/me/balance", token);
+  },
+
+  // One page of users; pass nextCursor back as cursor for the next
+  getUsers: async (
+    token: string,
+    query: UserQuery = {}
+  ): Promise<Page<UserInfo>> => {
+    return request<Page<UserInfo>>(`/users${toQuery(query)}`, token);
   },
 
   updateGlobalAllowances: async (
@@ -87,8 +119,12 @@ export const api = {
     });
   },
 
-  getLeaves: async (token: string): Promise<Leave[]> => {
-    return request<Leave[]>("/leaves", token);
+  // One page of leaves; pass nextCursor back as cursor for the next
+  getLeaves: async (
+    token: string,
+    query: LeaveQuery = {}
+  ): Promise<Page<Leave>> => {
+    return request<Page<Leave>>(`/leaves${toQuery(query)}`, token);
   },
 
   createLeave: async (
@@ -118,14 +154,17 @@ export const api = {
     });
   },
 
+  // Answers 409 with staffingWarnings unless confirm is set when approving
+  // would take the team below its minimum staffing
   approveLeave: async (
     token: string,
     id: string,
-    comment?: string
+    comment?: string,
+    confirm?: boolean
   ): Promise<Leave> => {
     return request<Leave>(`/leaves/${id}/approve`, token, {
       method: "POST",
-      body: JSON.stringify({ comment }),
+      body: JSON.stringify({ comment, confirm }),
     });
   },
 
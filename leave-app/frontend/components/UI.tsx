@@ -36,6 +36,16 @@ export const Button: React.FC<ButtonProps> = ({
   );
 };
 
+// Fetches the next page of a paged list; hidden once there are no more
+export const LoadMore: React.FC<{ hasMore: boolean; loading?: boolean; onClick: () => void }> = ({ hasMore, loading, onClick }) => {
+  if (!hasMore) return null;
+  return (
+    <Button variant="secondary" size="sm" className="w-full mt-4" isLoading={loading} disabled={loading} onClick={onClick}>
+      Load more
+    </Button>
+  );
+};
+
 export const Card: React.FC<{ children: React.ReactNode; className?: string }> = ({ children, className }) => (
   <div className={cn("bg-white rounded-2xl border border-slate-100 shadow-sm p-4", className)}>
     {children}
@@ -1,7 +1,15 @@
 import { useState, useEffect, useMemo, useCallback } from "react";
-import { Leave, LeaveType, LeaveStatus, UserInfo } from "../types";
-import { api } from "../api/client";
-import { formatDuration } from "../utils/formatters";
+import {
+  Leave,
+  LeaveType,
+  LeaveStatus,
+  LeaveQuery,
+  LeaveBalance,
+  StaffingWarning,
+  UserInfo,
+} from "../types";
+import { api, ApiError } from "../api/client";
+import { usePaged } from "./usePaged";
 
 interface UseLeavesProps {
   token: string | null;
@@ -9,116 +17,105 @@ interface UseLeavesProps {
   user: UserInfo | null;
 }
 
+const LEAVE_TYPES: LeaveType[] = ["sick", "annual", "casual"];
+
 export const useLeaves = ({ token, isAdmin, user }: UseLeavesProps) => {
-  const [rawLeaves, setRawLeaves] = useState<Leave[]>([]);
-  const [loading, setLoading] = useState(false);
-  const [refreshKey, setRefreshKey] = useState(0);
+  const [serverBalances, setServerBalances] = useState<
+    Record<string, LeaveBalance>
+  >({});
 
-  // Filters
+  // Filters. All but the search text are applied by the server.
   const [search, setSearch] = useState("");
   const [statusFilter, setStatusFilter] = useState<LeaveStatus | "all">("all");
   const [typeFilter, setTypeFilter] = useState<LeaveType | "all">("all");
   const [startDate, setStartDate] = useState("");
   const [endDate, setEndDate] = useState("");
 
-  const fetchLeaves = useCallback(async () => {
+  const myQuery: LeaveQuery | null = user
+    ? {
+        userId: user.id,
+        status: statusFilter === "all" ? undefined : statusFilter,
+        type: typeFilter === "all" ? undefined : typeFilter,
+        from: startDate || undefined,
+        to: endDate || undefined,
+      }
+    : null;
+  const mine = usePaged(api.getLeaves, token, myQuery);
+
+  // Leave waiting for a decision, oldest first, for the approvals tab
+  const pendingQuery: LeaveQuery | null = isAdmin
+    ? { status: "pending", sort: "createdAt" }
+    : null;
+  const pending = usePaged(api.getLeaves, token, pendingQuery);
+
+  const fetchBalances = useCallback(async () => {
     if (!token) return;
-    setLoading(true);
     try {
-      const data = await api.getLeaves(token);
-      setRawLeaves(data);
+      setServerBalances(await api.getBalance(token));
     } catch (e) {
       console.error(e);
-    } finally {
-      setLoading(false);
     }
   }, [token]);
 
   useEffect(() => {
-    fetchLeaves();
-  }, [fetchLeaves, refreshKey]);
+    fetchBalances();
+  }, [fetchBalances]);
 
-  const refresh = () => setRefreshKey((k) => k + 1);
+  const refresh = () => {
+    mine.refresh();
+    pending.refresh();
+    fetchBalances();
+  };
 
   const balances = useMemo(() => {
-    if (!user) return { sick: 0, annual: 0, casual: 0 };
-
-    const myActiveLeaves = rawLeaves.filter(
-      (l) =>
-        l.userId === user.id &&
-        (l.status === "approved" || l.status === "pending")
-    );
-
+    const remaining = { sick: 0, annual: 0, casual: 0 };
+    const total = { sick: 0, annual: 0, casual: 0 };
     const used = { sick: 0, annual: 0, casual: 0 };
-
-    myActiveLeaves.forEach((l) => {
-      const days = formatDuration(l.startDate, l.endDate);
-      if (used[l.type] !== undefined) {
-        used[l.type] += days;
-      }
+    LEAVE_TYPES.forEach((type) => {
+      const balance = serverBalances[type];
+      if (!balance) return;
+      remaining[type] = Math.max(0, balance.remaining);
+      total[type] = balance.allowance;
+      used[type] = balance.used + balance.pending;
     });
-
-    return {
-      sick: Math.max(0, user.allowances.sick - used.sick),
-      annual: Math.max(0, user.allowances.annual - used.annual),
-      casual: Math.max(0, user.allowances.casual - used.casual),
-      total: user.allowances,
-      used: used,
-    };
-  }, [rawLeaves, user]);
+    return { ...remaining, total, used };
+  }, [serverBalances]);
 
   const leaves = useMemo(() => {
-    let data = rawLeaves;
-
-    if (!isAdmin && user) {
-      data = data.filter((l) => l.userId === user.id);
-    }
-
-    if (search) {
-      const lower = search.toLowerCase();
-      data = data.filter((l) => l.reason.toLowerCase().includes(lower));
-    }
+    if (!search) return mine.items;
+    const lower = search.toLowerCase();
+    return mine.items.filter((l) => l.reason.toLowerCase().includes(lower));
+  }, [mine.items, search]);
 
-    if (statusFilter !== "all") {
-      data = data.filter((l) => l.status === statusFilter);
-    }
-
-    if (typeFilter !== "all") {
-      data = data.filter((l) => l.type === typeFilter);
-    }
-
-    if (startDate) {
-      data = data.filter((l) => l.startDate >= startDate);
-    }
-    if (endDate) {
-      data = data.filter((l) => l.startDate <= endDate);
-    }
-
-    return data.sort(
-      (a, b) =>
-        new Date(b.createdAt).getTime() - new Date(a.createdAt).getTime()
-    );
-  }, [
-    rawLeaves,
-    isAdmin,
-    user,
-    search,
-    statusFilter,
-    typeFilter,
-    startDate,
-    endDate,
-  ]);
+  const pendingLeaves = useMemo(
+    () => pending.items.filter((l) => l.userId !== user?.id),
+    [pending.items, user]
+  );
 
   const deleteLeave = async (id: string) => {
     if (!token) return;
-    setRawLeaves((prev) => prev.filter((l) => l.id !== id));
+    mine.setItems((prev: Leave[]) => prev.filter((l) => l.id !== id));
     await api.deleteLeave(token, id);
     refresh();
   };
 
   const approveLeave = async (id: string, comment?: string) => {
     if (!token) return;
-    await api.approveLeave(token, id, comment);
+    try {
+      await api.approveLeave(token, id, comment);
+    } catch (e) {
+      const warnings: StaffingWarning[] | undefined =
+        e instanceof ApiError && e.status === 409
+          ? e.data?.staffingWarnings
+          : undefined;
+      if (!warnings) throw e;
+      const days = warnings
+        .map((w) => `${w.date}: ${w.available} of ${w.minStaffing} in`)
+        .join("\n");
+      const message = `Approving leaves the team short-staffed:\n${days}\n\nApprove anyway?`;
+      if (!window.confirm(message)) return;
+      await api.approveLeave(token, id, comment, true);
+    }
     refresh();
   };
 
@@ -143,29 +140,21 @@ export const useLeaves = ({ token, isAdmin, user }: UseLeavesProps) => {
       throw new Error("Start date cannot be after end date");
     }
 
-    const days =
-      Math.ceil(
-        Math.abs(end.getTime() - start.getTime()) / (1000 * 60 * 60 * 24)
-      ) + 1;
-
-    const remaining = balances[data.type];
-
-    if (days > remaining) {
-      throw new Error(
-        `Insufficient ${data.type} leave balance. You have ${remaining} days left.`
-      );
-    }
-
+    // The server counts working days and has the final say on the balance
     await api.createLeave(token, data);
     refresh();
   };
 
   return {
     leaves,
-    rawLeaves,
+    pendingLeaves,
     balances,
-    loading,
+    loading: mine.loading || pending.loading,
     refresh,
+    hasMore: mine.hasMore,
+    loadMore: mine.loadMore,
+    hasMorePending: pending.hasMore,
+    loadMorePending: pending.loadMore,
     filters: {
       search,
       setSearch,
@@ -0,0 +1,75 @@
+import { useState, useEffect, useCallback } from "react";
+import { Page } from "../types";
+
+// Items fetched per request by list views
+export const PAGE_SIZE = 50;
+
+type PagedQuery = { cursor?: string; limit?: number };
+
+// Loads the first page of a list endpoint for query, and the next one on
+// loadMore. A null query loads nothing. Changing the query starts over.
+export const usePaged = <T, Q extends PagedQuery>(
+  fetchPage: (token: string, query: Q) => Promise<Page<T>>,
+  token: string | null,
+  query: Q | null
+) => {
+  const [items, setItems] = useState<T[]>([]);
+  const [cursor, setCursor] = useState<string | undefined>();
+  const [loading, setLoading] = useState(false);
+  const [refreshKey, setRefreshKey] = useState(0);
+
+  // Compare queries by value so callers can build them inline
+  const key = query ? JSON.stringify(query) : null;
+
+  useEffect(() => {
+    if (!token || key === null) {
+      setItems([]);
+      setCursor(undefined);
+      return;
+    }
+    let cancelled = false;
+    setLoading(true);
+    fetchPage(token, { ...(JSON.parse(key) as Q), limit: PAGE_SIZE })
+      .then((page) => {
+        if (cancelled) return;
+        setItems(page.items);
+        setCursor(page.nextCursor);
+      })
+      .catch((e) => console.error(e))
+      .finally(() => {
+        if (!cancelled) setLoading(false);
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [fetchPage, token, key, refreshKey]);
+
+  const loadMore = useCallback(async () => {
+    if (!token || key === null || !cursor || loading) return;
+    setLoading(true);
+    try {
+      const page = await fetchPage(token, {
+        ...(JSON.parse(key) as Q),
+        limit: PAGE_SIZE,
+        cursor,
+      });
+      setItems((prev) => [...prev, ...page.items]);
+      setCursor(page.nextCursor);
+    } catch (e) {
+      console.error(e);
+    } finally {
+      setLoading(false);
+    }
+  }, [fetchPage, token, key, cursor, loading]);
+
+  const refresh = useCallback(() => setRefreshKey((k) => k + 1), []);
+
+  return {
+    items,
+    setItems,
+    loading,
+    hasMore: !!cursor,
+    loadMore,
+    refresh,
+  };
+};
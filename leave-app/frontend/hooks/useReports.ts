@@ -1,4 +1,6 @@
-import { Leave, LeaveType, LeaveStatus, UserInfo, Allowances } from "../types";
+import { Leave, LeaveType, LeaveStatus, LeaveQuery, UserInfo, Allowances } from "../types";
+import { api } from "../api/client";
+import { usePaged } from "./usePaged";
 import { useMemo, useState } from "react";
 import { useBridge } from "./useBridge";
 import jsPDF from "jspdf";
@@ -98,12 +100,12 @@ export const generateCSV = (leaves: Leave[]) => {
 };
 
 export const useReports = (params: {
-  allLeaves: Leave[];
+  token: string | null;
   currentUser: UserInfo;
   isAdmin: boolean;
   users?: UserInfo[];
 }) => {
-  const { allLeaves, currentUser, isAdmin, users } =
+  const { token, currentUser, isAdmin, users } =
     params;
   const [activeTab, setActiveTab] = useState<"my" | "org">(
     isAdmin ? "org" : "my"
@@ -120,42 +122,34 @@ export const useReports = (params: {
     "pending" | "approved" | "rejected" | "all"
   >("all");
 
-  const sourceLeaves = useMemo(() => {
-    if (activeTab === "my") {
-      return allLeaves.filter((l) => l.userId === currentUser.id);
-    }
-    return allLeaves;
-  }, [activeTab, allLeaves, currentUser]);
+  // Everything but the search text is filtered by the server, a page at a time
+  const query: LeaveQuery = {
+    userId: activeTab === "my" ? currentUser.id : undefined,
+    type: typeFilter === "all" ? undefined : typeFilter,
+    status: statusFilter === "all" ? undefined : statusFilter,
+    from: startDate || undefined,
+    to: endDate || undefined,
+  };
+  const {
+    items: sourceLeaves,
+    loading,
+    hasMore,
+    loadMore,
+  } = usePaged(api.getLeaves, token, query);
 
   const filteredLeaves = useMemo(() => {
+    if (!search) return sourceLeaves;
+    const term = search.toLowerCase();
     return sourceLeaves.filter((l) => {
-      if (startDate && l.startDate < startDate) return false;
-      if (endDate && l.startDate > endDate) return false;
-      if (typeFilter !== "all" && l.type !== typeFilter) return false;
-      if (statusFilter !== "all" && l.status !== statusFilter) return false;
-
-      if (search) {
-        const term = search.toLowerCase();
-        if (activeTab === "my") {
-          return l.reason.toLowerCase().includes(term);
-        } else {
-          return (
-            l.userEmail.toLowerCase().includes(term) ||
-            l.reason.toLowerCase().includes(term)
-          );
-        }
+      if (activeTab === "my") {
+        return l.reason.toLowerCase().includes(term);
       }
-      return true;
+      return (
+        l.userEmail.toLowerCase().includes(term) ||
+        l.reason.toLowerCase().includes(term)
+      );
     });
-  }, [
-    sourceLeaves,
-    search,
-    startDate,
-    endDate,
-    typeFilter,
-    statusFilter,
-    activeTab,
-  ]);
+  }, [sourceLeaves, search, activeTab]);
 
   const stats = useMemo(() => calculateStats(filteredLeaves), [filteredLeaves]);
 
@@ -274,6 +268,9 @@ export const useReports = (params: {
     setStatusFilter,
     sourceLeaves,
     filteredLeaves,
+    loading,
+    hasMore,
+    loadMore,
     stats,
     typeData,
     requestDownloadFile,
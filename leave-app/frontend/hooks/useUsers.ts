@@ -1,6 +1,7 @@
-import { useState, useEffect, useCallback } from "react";
-import { UserInfo, Allowances } from "../types";
+import { useState, useEffect } from "react";
+import { UserInfo, UserQuery, Allowances } from "../types";
 import { api } from "../api/client";
+import { usePaged } from "./usePaged";
 
 interface UseUsersProps {
   token: string | null;
@@ -8,30 +9,33 @@ interface UseUsersProps {
   onGlobalAllowancesUpdate?: (allowances: Allowances) => void;
 }
 
+// How long to wait after typing before searching users
+const SEARCH_DELAY_MS = 300;
+
 export const useUsers = ({
   token,
   isAdmin,
   onGlobalAllowancesUpdate,
 }: UseUsersProps) => {
-  const [users, setUsers] = useState<UserInfo[]>([]);
-  const [loading, setLoading] = useState(false);
-
-  const fetchUsers = useCallback(async () => {
-    if (!token || !isAdmin) return;
-    setLoading(true);
-    try {
-      const data = await api.getUsers(token);
-      setUsers(data);
-    } catch (e) {
-      console.error("Failed to fetch users", e);
-    } finally {
-      setLoading(false);
-    }
-  }, [token, isAdmin]);
+  const [search, setSearch] = useState("");
+  const [email, setEmail] = useState("");
 
   useEffect(() => {
-    fetchUsers();
-  }, [fetchUsers]);
+    const timer = setTimeout(() => setEmail(search.trim()), SEARCH_DELAY_MS);
+    return () => clearTimeout(timer);
+  }, [search]);
+
+  const query: UserQuery | null = isAdmin
+    ? { email: email || undefined }
+    : null;
+  const {
+    items: users,
+    setItems: setUsers,
+    loading,
+    hasMore,
+    loadMore,
+    refresh,
+  } = usePaged(api.getUsers, token, query);
 
   const updateGlobalAllowances = async (
     allowances: Allowances
@@ -39,7 +43,7 @@ export const useUsers = ({
     if (!token) return;
     try {
       await api.updateGlobalAllowances(token, allowances);
-      setUsers((prev) => prev.map((u) => ({ ...u, allowances })));
+      setUsers((prev: UserInfo[]) => prev.map((u) => ({ ...u, allowances })));
       if (onGlobalAllowancesUpdate) {
         onGlobalAllowancesUpdate(allowances);
       }
@@ -56,7 +60,7 @@ export const useUsers = ({
     if (!token) return;
     try {
       // Optimistic UI update: update role locally first so UI doesn't go blank
-      setUsers((prev) =>
+      setUsers((prev: UserInfo[]) =>
         prev.map((u) => (u.id === userId ? { ...u, role } : u))
       );
       // Call backend; if backend returns updated user, we can use it to sync exact user fields
@@ -66,22 +70,14 @@ export const useUsers = ({
         typeof updatedUser === "object" &&
         "id" in updatedUser
       ) {
-        setUsers((prev) =>
+        setUsers((prev: UserInfo[]) =>
           prev.map((u) => (u.id === userId ? updatedUser : u))
         );
       }
     } catch (e) {
       console.error("Failed to update user role", e);
-      // Roll back optimistic update on error by refetching users from the server
-      try {
-        const refreshed = await api.getUsers(token);
-        setUsers(refreshed);
-      } catch (refreshErr) {
-        console.error(
-          "Failed to refresh users after role update failure",
-          refreshErr
-        );
-      }
+      // Roll back optimistic update on error by reloading users from the server
+      refresh();
       throw e;
     }
   };
@@ -89,8 +85,12 @@ export const useUsers = ({
   return {
     users,
     loading,
+    search,
+    setSearch,
+    hasMore,
+    loadMore,
     updateGlobalAllowances,
     updateUserRole,
-    refreshUsers: fetchUsers,
+    refreshUsers: refresh,
   };
 };
@@ -72,9 +72,19 @@ const server = http.createServer(async (req, res) => {
       return sendJSON(res, 200, users[0]);
     }
 
+    if (parts.length === 2 && parts[0] === 'me' && parts[1] === 'balance' && req.method === 'GET') {
+      if (!authOK(req)) return sendJSON(res, 401, { error: 'Unauthorized' });
+      const me = users[0];
+      const balance = {};
+      Object.entries(me.allowances).forEach(([type, allowance]) => {
+        balance[type] = { allowance, used: 0, pending: 0, remaining: allowance };
+      });
+      return sendJSON(res, 200, balance);
+    }
+
     if (parts.length === 1 && parts[0] === 'users' && req.method === 'GET') {
       if (!authOK(req)) return sendJSON(res, 401, { error: 'Unauthorized' });
-      return sendJSON(res, 200, users);
+      return sendJSON(res, 200, { items: users });
     }
 
     if (parts.length === 2 && parts[0] === 'admin' && parts[1] === 'allowances' && req.method === 'PUT') {
@@ -88,7 +98,7 @@ const server = http.createServer(async (req, res) => {
       // GET /api/leaves
       if (parts.length === 1 && req.method === 'GET') {
         if (!authOK(req)) return sendJSON(res, 401, { error: 'Unauthorized' });
-        return sendJSON(res, 200, leaves);
+        return sendJSON(res, 200, { items: leaves });
       }
 
       // POST /api/leaves
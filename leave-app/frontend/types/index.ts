@@ -32,6 +32,13 @@ export interface Leave {
   approverComment?: string;
 }
 
+// A day an approval would leave a team below its minimum staffing
+export interface StaffingWarning {
+  date: string;
+  available: number;
+  minStaffing: number;
+}
+
 export interface DateRange {
   start: string;
   end: string;
@@ -43,4 +50,40 @@ export interface LeaveSummary {
   approved: number;
   rejected: number;
   byType: Record<LeaveType, number>;
-}
\ No newline at end of file
+}
+// One page of a list endpoint such as GET /api/leaves
+export interface Page<T> {
+  items: T[];
+  nextCursor?: string;
+}
+
+// Query parameters of GET /api/leaves. from and to select leaves that
+// overlap the range; non-admins only ever get their own.
+export interface LeaveQuery {
+  status?: LeaveStatus;
+  type?: LeaveType;
+  userId?: string;
+  from?: string;
+  to?: string;
+  sort?: 'createdAt' | '-createdAt' | 'startDate' | '-startDate';
+  cursor?: string;
+  limit?: number;
+}
+
+// Query parameters of GET /api/users; email is a substring match
+export interface UserQuery {
+  role?: Role;
+  email?: string;
+  sort?: 'email' | '-email' | 'createdAt' | '-createdAt';
+  cursor?: string;
+  limit?: number;
+}
+
+// One leave type of GET /api/me/balance
+export interface LeaveBalance {
+  allowance: number;
+  carriedForward?: number;
+  used: number;
+  pending: number;
+  remaining: number;
+}
@@ -1,22 +1,22 @@
 
-import React, { useState, useMemo } from 'react';
+import React from 'react';
 import { UserInfo } from '../types';
-import { Button, Input } from '../components/UI';
+import { Button, Input, LoadMore } from '../components/UI';
 import { UserCircle, Settings } from 'lucide-react';
 
 interface AdminProps {
   users: UserInfo[];
+  search: string;
+  onSearchChange: (search: string) => void;
+  hasMore: boolean;
+  loading: boolean;
+  onLoadMore: () => void;
   currentUser: UserInfo;
   updateUserRole: (userId: string, role: 'admin' | 'user') => Promise<void>;
   openLimitModal: () => void;
 }
 
-export const Admin: React.FC<AdminProps> = ({ users, currentUser, updateUserRole, openLimitModal }) => {
-  const [search, setSearch] = useState('');
-
-  const filteredUsers = useMemo(() => {
-    return users.filter(user => user.email.toLowerCase().includes(search.toLowerCase()));
-  }, [users, search]);
+export const Admin: React.FC<AdminProps> = ({ users, search, onSearchChange, hasMore, loading, onLoadMore, currentUser, updateUserRole, openLimitModal }) => {
 
   return (
     <div className="space-y-4">
@@ -29,11 +29,11 @@ export const Admin: React.FC<AdminProps> = ({ users, currentUser, updateUserRole
         type="text"
         placeholder="Search users..."
         value={search}
-        onChange={(e) => setSearch(e.target.value)}
+        onChange={(e) => onSearchChange(e.target.value)}
       />
 
       <div className="space-y-4">
-        {filteredUsers.map((user) => (
+        {users.map((user) => (
           <div key={user.id} className="bg-white rounded-xl border border-slate-200 shadow-sm overflow-hidden">
             <div className="bg-slate-50/50 p-3 border-b border-slate-100 flex justify-between items-center">
               <div className="flex items-center gap-2.5">
@@ -65,6 +65,7 @@ export const Admin: React.FC<AdminProps> = ({ users, currentUser, updateUserRole
           </div>
         ))}
       </div>
+      <LoadMore hasMore={hasMore} loading={loading} onClick={onLoadMore} />
     </div>
   );
 };
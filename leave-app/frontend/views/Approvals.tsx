@@ -1,23 +1,25 @@
 
 import React, { useState } from 'react';
 import { Leave } from '../types';
-import { Card, Badge, Button, Modal } from '../components/UI';
+import { Card, Badge, Button, Modal, LoadMore } from '../components/UI';
 import { formatDate } from '../utils/formatters';
 import { CheckCircle, XCircle } from 'lucide-react';
 import { useAuth } from '../hooks/useAuth';
 
 interface ApprovalsProps {
-  leaves: Leave[];
+  leaves: Leave[]; // Pending leave of other people
+  hasMore: boolean;
+  loading: boolean;
+  onLoadMore: () => void;
   onApprove: (id: string, comment?: string) => void;
   onReject: (id: string, comment?: string) => void;
 }
 
-export const Approvals: React.FC<ApprovalsProps> = ({ leaves, onApprove, onReject }) => {
+export const Approvals: React.FC<ApprovalsProps> = ({ leaves, hasMore, loading, onLoadMore, onApprove, onReject }) => {
   const [rejectModalOpen, setRejectModalOpen] = useState(false);
   const [selectedLeaveId, setSelectedLeaveId] = useState<string | null>(null);
   const [rejectReason, setRejectReason] = useState('');
   const { user } = useAuth();
-  const pendingLeaves = leaves.filter(l => l.status === 'pending' && l.userEmail !== user?.email);
 
 
   const handleRejectClick = (id: string) => {
@@ -41,7 +43,7 @@ export const Approvals: React.FC<ApprovalsProps> = ({ leaves, onApprove, onRejec
     );
   }
 
-  if (pendingLeaves.length === 0) {
+  if (leaves.length === 0 && !hasMore) {
     return (
       <div className="flex flex-col items-center justify-center h-64 text-slate-400">
         <CheckCircle className="w-12 h-12 mb-4 opacity-20" />
@@ -53,7 +55,7 @@ export const Approvals: React.FC<ApprovalsProps> = ({ leaves, onApprove, onRejec
   return (
     <>
       <div className="space-y-4 pb-24">
-        {pendingLeaves.map((leave) => (
+        {leaves.map((leave) => (
           <Card key={leave.id}>
             <div className="flex items-center space-x-3 mb-3">
               <div className="w-8 h-8 rounded-full bg-primary-100 text-primary-600 flex items-center justify-center text-xs font-bold uppercase">
@@ -94,6 +96,7 @@ export const Approvals: React.FC<ApprovalsProps> = ({ leaves, onApprove, onRejec
             </div>
           </Card>
         ))}
+        <LoadMore hasMore={hasMore} loading={loading} onClick={onLoadMore} />
       </div>
 
       <Modal
@@ -1,7 +1,7 @@
 
 import React from 'react';
 import { Leave } from '../types';
-import { Card, Badge, Button, Modal } from '../components/UI';
+import { Card, Badge, Button, Modal, LoadMore } from '../components/UI';
 import { Filters } from '../components/Filters';
 import { formatDate, formatDuration } from '../utils/formatters';
 import { Calendar, Clock, Trash2, PlusCircle, AlertCircle } from 'lucide-react';
@@ -12,6 +12,9 @@ interface MyLeavesProps {
   onDelete: (id: string) => void;
   onRequestNew: () => void;
   filters: any;
+  hasMore: boolean;
+  loading: boolean;
+  onLoadMore: () => void;
 }
 
 const BalanceCard = ({ type, remaining, total }: { type: string, remaining: number, total: number }) => {
@@ -35,7 +38,7 @@ const BalanceCard = ({ type, remaining, total }: { type: string, remaining: numb
   );
 };
 
-export const MyLeaves: React.FC<MyLeavesProps> = ({ leaves, balances, onDelete, onRequestNew, filters }) => {
+export const MyLeaves: React.FC<MyLeavesProps> = ({ leaves, balances, onDelete, onRequestNew, filters, hasMore, loading, onLoadMore }) => {
   const [pendingDeleteId, setPendingDeleteId] = React.useState<string | null>(null);
 
   const openDeleteModal = (id: string) => setPendingDeleteId(id);
@@ -124,6 +127,7 @@ export const MyLeaves: React.FC<MyLeavesProps> = ({ leaves, balances, onDelete,
             ))}
           </div>
         )}
+        <LoadMore hasMore={hasMore} loading={loading} onClick={onLoadMore} />
       </div>
       <Modal isOpen={!!pendingDeleteId} onClose={closeDeleteModal} title="Cancel this request?">
         <p className="text-sm text-slate-600">Are you sure you want to cancel this leave request? This action cannot be undone.</p>
@@ -2,7 +2,7 @@
 import React, { useMemo } from 'react';
 import { PieChart, Pie, Cell, ResponsiveContainer, Tooltip } from 'recharts';
 import { Leave, UserInfo, Allowances, LeaveType, LeaveStatus } from '../types';
-import { Card, Button, Badge, Modal, Input } from '../components/UI';
+import { Card, Button, Badge, Modal, Input, LoadMore } from '../components/UI';
 import { Filters } from '../components/Filters';
 import { Download, Users, UserCircle, FileText, Settings, Printer, CheckCircle, XCircle, Clock, Calendar } from 'lucide-react';
 import { formatDate } from '../utils/formatters';
@@ -10,7 +10,7 @@ import { useReports } from '../hooks/useReports';
 // logic moved to useReports hook
 
 interface ReportsProps {
-  allLeaves: Leave[];
+  token: string | null;
   isAdmin: boolean;
   currentUser: UserInfo;
   users?: UserInfo[];
@@ -19,7 +19,7 @@ interface ReportsProps {
 const COLORS = ['#3b82f6', '#10b981', '#f59e0b', '#ef4444'];
 
 export const Reports: React.FC<ReportsProps> = ({ 
-  allLeaves, isAdmin, currentUser, users
+  token, isAdmin, currentUser, users
 }) => {
   const {
     activeTab,
@@ -37,11 +37,14 @@ export const Reports: React.FC<ReportsProps> = ({
     statusFilter,
     setStatusFilter,
     filteredLeaves,
+    loading,
+    hasMore,
+    loadMore,
     stats,
     typeData,
     handleDownloadCSV,
     handlePrint,
-  } = useReports({ allLeaves, currentUser, isAdmin, users });
+  } = useReports({ token, currentUser, isAdmin, users });
 
   const renderOverview = () => (
     <div className="space-y-6">
@@ -275,6 +278,12 @@ export const Reports: React.FC<ReportsProps> = ({
 
       <div className="mt-2 min-h-[300px]">
           {activeTab === 'my' ? renderOverview() : (orgSubTab === 'overview' ? renderOverview() : renderRawLog())}
+          {hasMore && (
+            <p className="text-[10px] text-slate-400 text-center mt-4">
+              Figures cover the {filteredLeaves.length} leave records loaded so far.
+            </p>
+          )}
+          <LoadMore hasMore={hasMore} loading={loading} onClick={loadMore} />
       </div>
     </div>
   );